@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fbs/pkg/graph"
+)
+
+type CacheCmd struct {
+	Stats  CacheStatsCmd  `cmd:"" help:"Show the number and total size of cache entries"`
+	GC     CacheGCCmd     `cmd:"" name:"gc" help:"Evict old or least recently used cache entries"`
+	Clean  CacheCleanCmd  `cmd:"" help:"Remove every cache entry"`
+	Verify CacheVerifyCmd `cmd:"" help:"Detect partially written or corrupted cache entries"`
+}
+
+type CacheStatsCmd struct{}
+
+type CacheGCCmd struct {
+	MaxSize   string `help:"Evict least recently used entries until the cache is at most this size (e.g. 500MB, 10GB)"`
+	OlderThan string `help:"Evict entries not used within this duration (e.g. 12h, 30d)"`
+}
+
+type CacheCleanCmd struct{}
+
+type CacheVerifyCmd struct {
+	Remove bool `help:"Remove entries that fail verification"`
+}
+
+// cacheDirectory returns the location of the local build cache
+func cacheDirectory() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".fbs", "cache"), nil
+}
+
+// openLocalCache returns the local build cache
+func openLocalCache() (*graph.LocalCache, error) {
+	cacheDir, err := cacheDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return graph.NewLocalCache(cacheDir), nil
+}
+
+func runCacheStats() error {
+	cache, err := openLocalCache()
+	if err != nil {
+		return err
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	fmt.Printf("Cache Directory: %s\n", cache.Dir())
+	fmt.Printf("Entries: %d\n", stats.Entries)
+	fmt.Printf("Total Size: %s\n", formatSize(stats.TotalSize))
+	if stats.Entries > 0 {
+		fmt.Printf("Least Recently Used: %s\n", stats.OldestAccess.Format(time.RFC3339))
+		fmt.Printf("Most Recently Used: %s\n", stats.NewestAccess.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func runCacheGC(cmd CacheGCCmd) error {
+	if cmd.MaxSize == "" && cmd.OlderThan == "" {
+		return fmt.Errorf("at least one of --max-size or --older-than is required")
+	}
+
+	var maxSize int64
+	if cmd.MaxSize != "" {
+		var err error
+		maxSize, err = parseSize(cmd.MaxSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	var olderThan time.Duration
+	if cmd.OlderThan != "" {
+		var err error
+		olderThan, err = parseAge(cmd.OlderThan)
+		if err != nil {
+			return err
+		}
+	}
+
+	cache, err := openLocalCache()
+	if err != nil {
+		return err
+	}
+
+	evicted, err := cache.GarbageCollect(maxSize, olderThan)
+	if err != nil {
+		return fmt.Errorf("failed to collect cache garbage: %w", err)
+	}
+
+	var freed int64
+	for _, entry := range evicted {
+		freed += entry.Size
+	}
+	fmt.Printf("Evicted %d entries (%s)\n", len(evicted), formatSize(freed))
+
+	return nil
+}
+
+func runCacheClean() error {
+	cache, err := openLocalCache()
+	if err != nil {
+		return err
+	}
+
+	if err := cache.Clean(); err != nil {
+		return fmt.Errorf("failed to clean cache: %w", err)
+	}
+
+	fmt.Printf("Removed all entries from %s\n", cache.Dir())
+	return nil
+}
+
+func runCacheVerify(cmd CacheVerifyCmd) error {
+	cache, err := openLocalCache()
+	if err != nil {
+		return err
+	}
+
+	problems, err := cache.Verify()
+	if err != nil {
+		return fmt.Errorf("failed to verify cache: %w", err)
+	}
+
+	if len(problems) == 0 {
+		fmt.Println("All cache entries are intact.")
+		return nil
+	}
+
+	for _, problem := range problems {
+		fmt.Printf("%s: %s\n", problem.Hash, problem.Reason)
+		if cmd.Remove {
+			if err := cache.Remove(problem.Hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cmd.Remove {
+		fmt.Printf("Removed %d broken entries\n", len(problems))
+		return nil
+	}
+
+	return fmt.Errorf("%d cache entries failed verification", len(problems))
+}
+
+// parseSize parses a human-readable byte size such as 500MB or 10G
+func parseSize(value string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"TB", 1 << 40}, {"T", 1 << 40},
+		{"GB", 1 << 30}, {"G", 1 << 30},
+		{"MB", 1 << 20}, {"M", 1 << 20},
+		{"KB", 1 << 10}, {"K", 1 << 10},
+		{"B", 1},
+	}
+
+	normalized := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(normalized, unit.suffix) {
+			normalized = strings.TrimSuffix(normalized, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(normalized), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	return int64(number * float64(multiplier)), nil
+}
+
+// parseAge parses a duration, additionally accepting a day suffix such as 30d
+func parseAge(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		number, err := strconv.ParseFloat(days, 64)
+		if err != nil || number < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(number * float64(24*time.Hour)), nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
+
+// formatSize formats a byte count for display
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"fbs/pkg/kotlin"
+)
+
+type DaemonCmd struct {
+	Stop DaemonStopCmd `cmd:"" help:"Stop the Kotlin compiler daemons kept running between builds"`
+}
+
+type DaemonStopCmd struct{}
+
+func runDaemonStop() error {
+	dir, err := kotlin.DaemonDirectory()
+	if err != nil {
+		return err
+	}
+
+	stopped, err := kotlin.StopDaemons(dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stopped %d compiler daemon(s)\n", stopped)
+	return nil
+}
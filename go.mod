@@ -2,4 +2,9 @@ module fbs
 
 go 1.24.4
 
-require github.com/alecthomas/kong v1.11.0
+require (
+	github.com/alecthomas/kong v1.11.0
+	golang.org/x/term v0.32.0
+)
+
+require golang.org/x/sys v0.33.0 // indirect
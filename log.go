@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fbs/pkg/graph"
+)
+
+type LogCmd struct {
+	Task string `arg:"" help:"Task hash (or a prefix of it), task ID or display name"`
+}
+
+// logDirectory returns the location where logs of failed tasks are kept
+func logDirectory() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".fbs", "logs"), nil
+}
+
+func runLog(cmd LogCmd) error {
+	logDir, err := logDirectory()
+	if err != nil {
+		return err
+	}
+	store := graph.NewLogStore(logDir)
+
+	infos, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	matches := matchLogs(infos, cmd.Task)
+	if len(matches) == 0 {
+		return fmt.Errorf("no log found for %q (logs are only kept for tasks that failed)", cmd.Task)
+	}
+	if len(matches) > 1 {
+		var candidates []string
+		for _, info := range matches {
+			candidates = append(candidates, fmt.Sprintf("  %.12s  %s (%s)", info.TaskHash, info.DisplayName, info.Directory))
+		}
+		return fmt.Errorf("%q matches %d tasks, use a task hash:\n%s", cmd.Task, len(matches), strings.Join(candidates, "\n"))
+	}
+
+	info := matches[0]
+	logFile, err := os.Open(store.LogPath(info.TaskHash))
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer logFile.Close()
+
+	fmt.Printf("%s (%s) failed at %s\n", info.DisplayName, info.Directory, info.Time.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Error: %s\n\n", info.Error)
+	_, err = io.Copy(os.Stdout, logFile)
+	return err
+}
+
+// matchLogs returns the logs whose task hash starts with query or whose task ID
+// or display name equals it
+func matchLogs(infos []graph.LogInfo, query string) []graph.LogInfo {
+	var matches []graph.LogInfo
+	for _, info := range infos {
+		if strings.HasPrefix(info.TaskHash, query) || info.TaskID == query || info.DisplayName == query {
+			matches = append(matches, info)
+		}
+	}
+	return matches
+}
@@ -2,42 +2,63 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 
+	"fbs/pkg/config"
 	"fbs/pkg/discoverer"
 	"fbs/pkg/gradle"
 	"fbs/pkg/graph"
+	"fbs/pkg/java"
 	"fbs/pkg/kotlin"
+	"fbs/pkg/render"
 )
 
 type CLI struct {
 	Version  bool     `short:"v" help:"Show version information"`
 	Parallel int      `short:"j" help:"Number of parallel workers for task execution" default:"8"`
+	MemoryMB int      `name:"memory-mb" help:"Memory in MB available to parallel tasks (overrides resources.memoryMb in fbs.conf.json, defaults to three quarters of the system memory)" env:"FBS_MEMORY_MB"`
+	KeepGoing bool    `short:"k" help:"Continue running independent tasks after a task fails"`
+	Sandbox  bool     `help:"Run each task with only its declared inputs and dependency outputs staged in its work directory, failing tasks that read undeclared inputs" env:"FBS_SANDBOX"`
+	Events   string   `help:"Write task execution events as JSON lines to this file" type:"path"`
+	Profile  string   `help:"Write a Chrome trace of task execution to this file and print the slowest tasks and the critical path" type:"path"`
+	Output   string   `help:"Output style: auto, tty or plain (auto uses plain when stdout is not a terminal)" enum:"auto,tty,plain" default:"auto"`
+
+	RemoteCache       string `help:"URL or directory of a shared build cache to fetch task results from" env:"FBS_REMOTE_CACHE"`
+	RemoteCacheUpload bool   `help:"Upload executed task results to the remote cache" env:"FBS_REMOTE_CACHE_UPLOAD"`
+
 	Plan     PlanCmd  `cmd:"" help:"Plan and print the build graph"`
-	Build    BuildCmd `cmd:"" help:"Execute build tasks in the specified directory"`
-	Test     TestCmd  `cmd:"" help:"Execute test tasks in the specified directory"`
-	Deps     DepsCmd  `cmd:"" help:"Execute dependency tasks in the specified directory"`
+	Build    BuildCmd `cmd:"" help:"Execute build tasks in the specified directories or targets"`
+	Test     TestCmd  `cmd:"" help:"Execute test tasks in the specified directories or targets"`
+	Deps     DepsCmd  `cmd:"" help:"Execute dependency tasks in the specified directories or targets"`
+	Cache    CacheCmd `cmd:"" help:"Inspect and maintain the local build cache"`
+	Log      LogCmd   `cmd:"" help:"Print the full log of a task that failed in its last run"`
+	Rdeps    RdepsCmd `cmd:"" help:"List every task that transitively depends on a file or directory"`
+	Affected AffectedCmd `cmd:"" help:"List or run the tasks affected by changes since a git ref"`
+	Daemon   DaemonCmd   `cmd:"" help:"Manage the Kotlin compiler daemons"`
 }
 
 type PlanCmd struct {
 	Directory string `arg:"" optional:"" help:"Directory to plan (defaults to current directory)"`
+	Format    string `help:"Output format: text, json, dot or mermaid" enum:"text,json,dot,mermaid" default:"text"`
 }
 
 type BuildCmd struct {
-	Directory string `arg:"" optional:"" help:"Directory to build (defaults to current directory)"`
+	Targets []string `arg:"" optional:"" help:"Directories or target selectors such as src/main/kotlin:kotlin-compile or //service/api:jar-compile (defaults to current directory)"`
 }
 
 type TestCmd struct {
-	Directory string `arg:"" optional:"" help:"Directory to test (defaults to current directory)"`
+	Targets []string `arg:"" optional:"" help:"Directories or target selectors such as src/test/kotlin:FooTest (defaults to current directory)"`
 }
 
 type DepsCmd struct {
-	Directory string `arg:"" optional:"" help:"Directory to download dependencies for (defaults to current directory)"`
+	Targets []string `arg:"" optional:"" help:"Directories or target selectors to download dependencies for (defaults to current directory)"`
 }
 
 func main() {
@@ -51,20 +72,68 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-	case "build <directory>", "build":
-		err := runExecute(cli.Build.Directory, graph.TaskTypeBuild, cli.Parallel)
+	case "build <targets>", "build":
+		err := runExecute(cli.Build.Targets, graph.TaskTypeBuild, &cli)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "test <targets>", "test":
+		err := runExecute(cli.Test.Targets, graph.TaskTypeTest, &cli)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "deps <targets>", "deps":
+		err := runExecute(cli.Deps.Targets, graph.TaskTypeDeps, &cli)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "cache stats":
+		err := runCacheStats()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "cache gc":
+		err := runCacheGC(cli.Cache.GC)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "cache clean":
+		err := runCacheClean()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "cache verify":
+		err := runCacheVerify(cli.Cache.Verify)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-	case "test <directory>", "test":
-		err := runExecute(cli.Test.Directory, graph.TaskTypeTest, cli.Parallel)
+	case "rdeps <path>":
+		err := runRdeps(cli.Rdeps)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-	case "deps <directory>", "deps":
-		err := runExecute(cli.Deps.Directory, graph.TaskTypeDeps, cli.Parallel)
+	case "affected":
+		err := runAffected(cli.Affected, &cli)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "daemon stop":
+		err := runDaemonStop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "log <task>":
+		err := runLog(cli.Log)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -95,6 +164,43 @@ func runPlan(cmd PlanCmd) error {
 		return fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
+	// Use a persistent digest index so unchanged files aren't re-read on every run
+	digestIndex := openDigestIndex()
+	defer digestIndex.Save()
+
+	// Plan the build graph using structure-based approach
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := planBuildGraph(ctx, absDir)
+	if err != nil {
+		return err
+	}
+
+	// Print the results
+	switch cmd.Format {
+	case "json":
+		err = discoverer.NewPlanExport(result).WriteJSON(os.Stdout)
+	case "dot":
+		err = discoverer.NewPlanExport(result).WriteDOT(os.Stdout)
+	case "mermaid":
+		err = discoverer.NewPlanExport(result).WriteMermaid(os.Stdout)
+	default:
+		printStructurePlanResult(result, absDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Cycles are part of the printed plan but still fail the command
+	return result.CycleError()
+}
+
+// planBuildGraph discovers all tasks under dir
+func planBuildGraph(ctx context.Context, dir string) (*discoverer.StructurePlanResult, error) {
+	// Task hashes contain paths relative to the workspace root, so that
+	// checkouts at different locations share cache entries
+	graph.SetWorkspaceRoot(config.FindWorkspaceRoot(dir))
+	
 	// Create structure discoverers
 	structureDiscoverers := []discoverer.StructureDiscoverer{
 		gradle.NewGradleStructureDiscoverer(),
@@ -103,208 +209,385 @@ func runPlan(cmd PlanCmd) error {
 	// Create discoverers (excluding GradleDiscoverer since that's now handled by compilation root)
 	discoverers := []discoverer.Discoverer{
 		kotlin.NewKotlinDiscoverer(),
+		java.NewJavaDiscoverer(),
+		java.NewResourcesDiscoverer(),
 		kotlin.NewJunitDiscoverer(),
 	}
 
-	// Plan the build graph using structure-based approach
-	ctx := context.Background()
-	result, err := discoverer.PlanWithStructure(ctx, absDir, discoverers, structureDiscoverers)
+	result, err := discoverer.PlanWithStructure(ctx, dir, discoverers, structureDiscoverers)
 	if err != nil {
-		return fmt.Errorf("failed to plan build graph: %w", err)
+		return nil, fmt.Errorf("failed to plan build graph: %w", err)
 	}
-
-	// Print the results
-	printStructurePlanResult(result, absDir)
-
-	return nil
+	return result, nil
 }
 
-func runExecute(directory string, taskType graph.TaskType, parallelWorkers int) error {
-	// Determine the directory to execute in
-	execDir := directory
-	if execDir == "" {
-		var err error
-		execDir, err = os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
+func runExecute(targets []string, taskType graph.TaskType, cli *CLI) error {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
-	// Convert to absolute path
-	absDir, err := filepath.Abs(execDir)
+	// Resolve target selectors, defaulting to the current directory
+	if len(targets) == 0 {
+		targets = []string{"."}
+	}
+	selectors, err := parseSelectors(targets, workDir)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
+		return err
 	}
 
-	// Change to the target directory for planning
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+	// Plan the smallest directory that contains every target
+	absDir := selectorPlanRoot(selectors)
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory %s does not exist", absDir)
 	}
-	defer os.Chdir(originalDir)
+
+	// Change to the target directory for planning
+	defer os.Chdir(workDir)
 
 	err = os.Chdir(absDir)
 	if err != nil {
 		return fmt.Errorf("failed to change to directory %s: %w", absDir, err)
 	}
 
-	// Create structure discoverers
-	structureDiscoverers := []discoverer.StructureDiscoverer{
-		gradle.NewGradleStructureDiscoverer(),
-	}
-
-	// Create discoverers (excluding GradleDiscoverer since that's now handled by compilation root)
-	discoverers := []discoverer.Discoverer{
-		kotlin.NewKotlinDiscoverer(),
-		kotlin.NewJunitDiscoverer(),
-	}
+	// Use a persistent digest index so unchanged files aren't re-read on every run
+	digestIndex := openDigestIndex()
+	defer digestIndex.Save()
 
 	// Plan the build graph using structure-based approach
-	ctx := context.Background()
-	result, err := discoverer.PlanWithStructure(ctx, absDir, discoverers, structureDiscoverers)
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := planBuildGraph(ctx, absDir)
 	if err != nil {
-		return fmt.Errorf("failed to plan build graph: %w", err)
+		return err
+	}
+	if err := result.CycleError(); err != nil {
+		return err
 	}
 
-	// Filter tasks by type and directory
-	filteredTasks := filterTasksByTypeAndDirectory(result.Graph.GetTasks(), taskType, absDir)
+	// Select the targeted tasks of the requested type
+	filteredTasks, err := selectTasks(result.Graph.GetTasks(), selectors, taskType)
+	if err != nil {
+		return err
+	}
 	
 	if len(filteredTasks) == 0 {
-		fmt.Printf("No %s tasks found in directory %s\n", taskType, absDir)
+		fmt.Printf("No %s tasks found for %s\n", taskType, strings.Join(targets, " "))
 		return nil
 	}
 
+	return executeTasks(ctx, filteredTasks, workDir, cli)
+}
+
+// executeTasks runs the given tasks and their dependencies, labelling tasks relative to workDir
+func executeTasks(ctx context.Context, filteredTasks []graph.Task, workDir string, cli *CLI) error {
 	// Create a new graph with only filtered tasks and their dependencies
 	executionGraph := createExecutionGraph(filteredTasks)
 
 	// Create a persistent cache directory for execution
-	homeDir, err := os.UserHomeDir()
+	cacheDir, err := cacheDirectory()
 	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %w", err)
+		return err
 	}
-	cacheDir := filepath.Join(homeDir, ".fbs", "cache")
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
-	// Color constants
-	const (
-		green  = "\033[32m"
-		orange = "\033[33m"
-		red    = "\033[31m"
-		reset  = "\033[0m"
-	)
-
+	// Pick the interactive or line-oriented output
+	renderer, err := render.New(cli.Output, os.Stdout, func(task graph.Task) string {
+		return taskLabel(task, workDir)
+	})
+	if err != nil {
+		return err
+	}
+	
 	// Get all tasks in execution order for display
 	orderedTasks, err := executionGraph.TopologicalSort()
 	if err != nil {
 		return fmt.Errorf("failed to sort tasks: %w", err)
 	}
 	
-	// Create task display tracking
-	taskLines := make(map[string]int) // Map task ID to line number
+	// Execute the tasks with progress
+	runner := graph.NewRunner(cacheDir)
+	if cli.RemoteCache != "" {
+		runner.SetRemoteCache(newRemoteCache(cli.RemoteCache), cli.RemoteCacheUpload)
+	}
+	runner.SetKeepGoing(cli.KeepGoing)
+	runner.SetSandbox(cli.Sandbox)
 	
-	// Initialize all tasks as pending and display them
-	for i, task := range orderedTasks {
-		taskLines[task.ID()] = i
-		
-		// Get display path for task
-		displayPath := ""
-		if _, ok := task.(*gradle.ArtifactDownload); ok {
-			// For artifact downloads, don't show the cache path
-			displayPath = ""
-		} else {
-			relPath, err := filepath.Rel(absDir, task.Directory())
-			if err != nil {
-				relPath = task.Directory()
-			}
-			if relPath == "" {
-				relPath = "."
-			}
-			displayPath = fmt.Sprintf(" (%s)", relPath)
+	// Limit the memory and network used by parallel tasks, and how long tasks may run
+	configuration, err := config.LoadConfiguration(workDir)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	runner.SetResources(resourcePools(configuration, cli))
+	timeouts, err := taskTimeouts(configuration)
+	if err != nil {
+		return err
+	}
+	runner.SetTimeouts(timeouts)
+	retries, err := retryPolicies(configuration)
+	if err != nil {
+		return err
+	}
+	runner.SetRetries(retries)
+	
+	// Start the longest chains of work first, based on the durations of previous runs
+	durations := graph.NewDurationHistory(filepath.Join(filepath.Dir(cacheDir), "durations.json"))
+	defer durations.Save()
+	runner.SetDurationHistory(durations)
+	
+	// Keep the output of failed tasks for the failure summary and `fbs log`
+	logDir, err := logDirectory()
+	if err != nil {
+		return err
+	}
+	runner.SetLogStore(graph.NewLogStore(logDir))
+	runner.AddEventHandler(renderer.HandleEvent)
+	
+	// Stream structured events for CI dashboards and IDE tooling
+	var eventWriter *graph.EventWriter
+	if cli.Events != "" {
+		eventFile, err := os.Create(cli.Events)
+		if err != nil {
+			return fmt.Errorf("failed to create events file: %w", err)
 		}
+		defer eventFile.Close()
 		
-		fmt.Printf("  %s⏳%s %s%s\n", orange, reset, task.DisplayName(), displayPath)
+		eventWriter = graph.NewEventWriter(eventFile)
+		runner.AddEventHandler(eventWriter.Handle)
 	}
 	
-	// Progress callback to update task status in place
-	progressCallback := func(task graph.Task, status string, finished bool, cached bool) {
-		if !finished {
-			return // Only update when task is finished
+	renderer.Start(orderedTasks)
+	results, err := runner.ExecuteWithProgressParallel(ctx, executionGraph, nil, cli.Parallel)
+	renderer.Finish(results, err)
+	
+	printCacheWarnings(results)
+	if eventWriter != nil && eventWriter.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write events: %v\n", eventWriter.Err())
+	}
+	
+	// Show where the build time went
+	if cli.Profile != "" {
+		if profileErr := writeProfile(cli.Profile, results, workDir); profileErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", profileErr)
+		}
+	}
+	
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return fmt.Errorf("build interrupted")
 		}
 		
-		lineNum := taskLines[task.ID()]
-		
-		// Move cursor to the specific line and update it
-		fmt.Printf("\033[%dA", len(orderedTasks)-lineNum) // Move up to the task's line
-		fmt.Printf("\r\033[K") // Clear the line
-		
-		// Determine status symbol and color
-		var statusSymbol, color string
-		if status == "failed" {
-			statusSymbol = "✗"
-			color = red
-		} else if cached {
-			statusSymbol = "↻"  // Cached symbol
-			color = "\033[36m"  // Cyan color for cached
-		} else {
-			statusSymbol = "✓"
-			color = green
+		// Failed tasks have already been reported with their logs
+		if failed := countFailures(results); failed > 0 {
+			return fmt.Errorf("%d task(s) failed", failed)
+		}
+		return fmt.Errorf("execution failed: %w", err)
+	}
+
+	return nil
+}
+
+// writeProfile writes a Chrome trace of the results to path and prints a summary of the profile
+func writeProfile(path string, results []graph.ExecutionResult, workDir string) error {
+	profile := graph.NewProfile(results)
+	
+	traceFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create profile: %w", err)
+	}
+	defer traceFile.Close()
+	
+	if err := profile.WriteTrace(traceFile); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	
+	profile.WriteSummary(os.Stdout, func(task graph.Task) string {
+		return taskLabel(task, workDir)
+	})
+	fmt.Printf("\nTrace written to %s (open in chrome://tracing or https://ui.perfetto.dev)\n", path)
+	return nil
+}
+
+// countFailures returns the number of tasks that were executed and failed
+func countFailures(results []graph.ExecutionResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Result.Error != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// taskLabel returns the display name of a task followed by its directory relative to baseDir
+func taskLabel(task graph.Task, baseDir string) string {
+	// For artifact downloads, don't show the cache path
+	if _, ok := task.(*gradle.ArtifactDownload); ok {
+		return task.DisplayName()
+	}
+	
+	relPath, err := filepath.Rel(baseDir, task.Directory())
+	if err != nil {
+		relPath = task.Directory()
+	}
+	if relPath == "" {
+		relPath = "."
+	}
+	return fmt.Sprintf("%s (%s)", task.DisplayName(), relPath)
+}
+
+// newRemoteCache creates a cache backend for an HTTP(S) URL or a directory path
+func newRemoteCache(location string) graph.CacheBackend {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return graph.NewHTTPCache(location)
+	}
+	return graph.NewDiskCache(location)
+}
+
+// printCacheWarnings reports remote cache failures without failing the build
+func printCacheWarnings(results []graph.ExecutionResult) {
+	var cacheErrors []error
+	for _, result := range results {
+		if result.CacheError != nil {
+			cacheErrors = append(cacheErrors, result.CacheError)
+		}
+	}
+	
+	if len(cacheErrors) == 0 {
+		return
+	}
+	
+	fmt.Fprintf(os.Stderr, "Warning: remote cache failed for %d task(s): %v\n", len(cacheErrors), cacheErrors[0])
+}
+
+// resourcePools returns the resource pools configured in fbs.conf.json and on the command line
+func resourcePools(configuration *config.Config, cli *CLI) graph.Resources {
+	resources := graph.Resources{
+		MemoryMB: configuration.Resources.MemoryMB,
+		Network:  configuration.Resources.Network,
+	}
+	if cli.MemoryMB > 0 {
+		resources.MemoryMB = cli.MemoryMB
+	}
+	return resources
+}
+
+// taskTimeouts parses the task timeouts configured in fbs.conf.json
+func taskTimeouts(configuration *config.Config) (graph.Timeouts, error) {
+	timeouts := make(graph.Timeouts)
+	for key, value := range configuration.Timeouts {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q for %s in fbs.conf.json: %w", value, key, err)
+		}
+		timeouts[key] = timeout
+	}
+	return timeouts, nil
+}
+
+// retryPolicies returns the default retry policies overridden by those configured in fbs.conf.json
+func retryPolicies(configuration *config.Config) (graph.RetryPolicies, error) {
+	policies := graph.DefaultRetryPolicies()
+	for key, retry := range configuration.Retries {
+		policy := graph.RetryPolicy{Attempts: retry.Attempts}
+		if policy.Attempts < 1 {
+			return nil, fmt.Errorf("invalid retry attempts %d for %s in fbs.conf.json: must be at least 1", retry.Attempts, key)
 		}
 		
-		// Get display path for task
-		displayPath := ""
-		if _, ok := task.(*gradle.ArtifactDownload); ok {
-			// For artifact downloads, don't show the cache path
-			displayPath = ""
-		} else {
-			relPath, err := filepath.Rel(absDir, task.Directory())
-			if err != nil {
-				relPath = task.Directory()
+		var err error
+		if retry.Backoff != "" {
+			if policy.Backoff, err = time.ParseDuration(retry.Backoff); err != nil {
+				return nil, fmt.Errorf("invalid retry backoff %q for %s in fbs.conf.json: %w", retry.Backoff, key, err)
 			}
-			if relPath == "" {
-				relPath = "."
+		}
+		if retry.MaxBackoff != "" {
+			if policy.MaxBackoff, err = time.ParseDuration(retry.MaxBackoff); err != nil {
+				return nil, fmt.Errorf("invalid retry max backoff %q for %s in fbs.conf.json: %w", retry.MaxBackoff, key, err)
 			}
-			displayPath = fmt.Sprintf(" (%s)", relPath)
 		}
-		
-		fmt.Printf("  %s%s%s %s%s\n", color, statusSymbol, reset, task.DisplayName(), displayPath)
-		
-		// Move cursor back to the bottom
-		fmt.Printf("\033[%dB", len(orderedTasks)-lineNum-1)
+		policies[key] = policy
 	}
+	return policies, nil
+}
 
-	// Execute the tasks with progress
-	runner := graph.NewRunner(cacheDir)
-	_, err = runner.ExecuteWithProgressParallel(ctx, executionGraph, progressCallback, parallelWorkers)
+// openDigestIndex loads the persistent file digest index and installs it as the default
+func openDigestIndex() *graph.DigestIndex {
+	indexPath := ""
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		indexPath = filepath.Join(homeDir, ".fbs", "digests.json")
+	}
 	
-	if err != nil {
-		return fmt.Errorf("execution failed: %w", err)
+	digestIndex := graph.NewDigestIndex(indexPath)
+	graph.SetDefaultDigestIndex(digestIndex)
+	return digestIndex
+}
+
+// parseSelectors parses target selectors relative to the working directory
+func parseSelectors(targets []string, workDir string) ([]*graph.Selector, error) {
+	workspaceRoot := config.FindWorkspaceRoot(workDir)
+	
+	var selectors []*graph.Selector
+	for _, target := range targets {
+		selector, err := graph.ParseSelector(target, workDir, workspaceRoot)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
 	}
+	return selectors, nil
+}
 
-	return nil
+// selectorPlanRoot returns the deepest directory containing the base directory of every selector
+func selectorPlanRoot(selectors []*graph.Selector) string {
+	root := selectors[0].BaseDir()
+	for _, selector := range selectors[1:] {
+		dir := selector.BaseDir()
+		for {
+			relPath, err := filepath.Rel(root, dir)
+			if err == nil && !strings.HasPrefix(relPath, "..") {
+				break
+			}
+			root = filepath.Dir(root)
+		}
+	}
+	return root
 }
 
-// filterTasksByTypeAndDirectory returns tasks of the specified type that are in or under the given directory
-func filterTasksByTypeAndDirectory(tasks []graph.Task, taskType graph.TaskType, baseDir string) []graph.Task {
-	var filtered []graph.Task
-	for _, task := range tasks {
-		if task.TaskType() == taskType {
-			// For deps tasks, include all from the same compilation root regardless of directory
-			// since they may be stored in cache directories outside the project
-			if taskType == graph.TaskTypeDeps {
-				filtered = append(filtered, task)
-			} else {
-				// For other tasks, check if task is in the specified directory or a subdirectory
-				taskDir := task.Directory()
-				relPath, err := filepath.Rel(baseDir, taskDir)
-				if err == nil && !strings.HasPrefix(relPath, "..") {
-					filtered = append(filtered, task)
-				}
+// selectTasks returns the tasks of the given type matched by any selector.
+// A selector that names specific tasks but matches none of them is an error.
+func selectTasks(tasks []graph.Task, selectors []*graph.Selector, taskType graph.TaskType) ([]graph.Task, error) {
+	var selected []graph.Task
+	seen := make(map[string]bool)
+	
+	for _, selector := range selectors {
+		var matches []graph.Task
+		for _, task := range tasks {
+			if task.TaskType() != taskType {
+				continue
+			}
+			
+			// For deps tasks, include all from the planned compilation roots regardless of
+			// directory since they may be stored in cache directories outside the project
+			if taskType == graph.TaskTypeDeps && !selector.HasTask() || selector.Matches(task) {
+				matches = append(matches, task)
+			}
+		}
+		
+		if len(matches) == 0 && selector.HasTask() {
+			return nil, fmt.Errorf("target %s matches no %s tasks", selector, taskType)
+		}
+		
+		for _, task := range matches {
+			if !seen[task.ID()] {
+				seen[task.ID()] = true
+				selected = append(selected, task)
 			}
 		}
 	}
-	return filtered
+	
+	return selected, nil
 }
 
 // createExecutionGraph creates a new graph containing the filtered tasks and all their dependencies
@@ -0,0 +1,449 @@
+package classfile
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// magic starts every class file
+const magic = 0xCAFEBABE
+
+// Kinds of classes in the kotlin.Metadata annotation
+const (
+	kotlinFileFacade      = 2
+	kotlinMultifileFacade = 4
+)
+
+// Access flags that decide whether a declaration is visible to other classes
+const (
+	accPrivate   = 0x0002
+	accSynthetic = 0x1000
+)
+
+// Constant pool tags
+const (
+	tagUtf8               = 1
+	tagInteger            = 3
+	tagFloat              = 4
+	tagLong               = 5
+	tagDouble             = 6
+	tagClass              = 7
+	tagString             = 8
+	tagFieldref           = 9
+	tagMethodref          = 10
+	tagInterfaceMethodref = 11
+	tagNameAndType        = 12
+	tagMethodHandle       = 15
+	tagMethodType         = 16
+	tagDynamic            = 17
+	tagInvokeDynamic      = 18
+	tagModule             = 19
+	tagPackage            = 20
+)
+
+// descriptorClass matches the class names in field and method descriptors and generic signatures
+var descriptorClass = regexp.MustCompile(`L([\w/$]+)[;<]`)
+
+// Class is what fbs needs to know about a compiled class to decide what to
+// recompile: the classes it references and a digest of its ABI
+type Class struct {
+	// Name is the internal name of the class, such as com/example/Main
+	Name string
+	// SourceFile is the name of the source file the class was compiled from, without its directory
+	SourceFile string
+	// Private is whether the class is a private or anonymous nested class,
+	// which other source files can't refer to
+	Private bool
+	// HasConstants is whether the class declares constants that compilers
+	// inline into the classes using them
+	HasConstants bool
+	// KotlinFacade is whether the class holds the top-level declarations of
+	// Kotlin files, which Kotlin modules list in META-INF/*.kotlin_module
+	KotlinFacade bool
+
+	abi        string
+	references []string
+}
+
+// constant is an entry of the constant pool
+type constant struct {
+	tag   byte
+	value string // contents of Utf8 entries and the resolved value of literals
+	index uint16 // first referenced entry
+}
+
+// ParseFile reads and parses a class file
+func ParseFile(path string) (*Class, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read class file: %w", err)
+	}
+	class, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return class, nil
+}
+
+// Parse parses a class file
+func Parse(data []byte) (*Class, error) {
+	p := &parser{data: data}
+	class := p.parse()
+	if p.err != nil {
+		return nil, p.err
+	}
+	return class, nil
+}
+
+// ABI returns a digest of everything other classes compile against: the
+// class's name, flags, supertypes and annotations, and the signatures of its
+// non-private fields and methods, including the values of constants. Method
+// bodies and private members aren't part of it, so changing them doesn't
+// change the ABI.
+func (c *Class) ABI() string {
+	return c.abi
+}
+
+// References returns the internal names of the classes this class refers to, sorted
+func (c *Class) References() []string {
+	return c.references
+}
+
+// Package returns the internal name of the package of the class, such as com/example
+func (c *Class) Package() string {
+	dir := path.Dir(c.Name)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// parser reads a class file, remembering the first error
+type parser struct {
+	data []byte
+	pos  int
+	err  error
+	pool []constant
+}
+
+func (p *parser) parse() *Class {
+	if p.u4() != magic {
+		p.fail("not a class file")
+		return nil
+	}
+	p.skip(4) // minor and major version
+	p.readConstantPool()
+
+	abi := sha256.New()
+	class := &Class{}
+	references := make(map[string]bool)
+
+	accessFlags := p.u2()
+	class.Name = p.className(p.u2())
+	superClass := p.className(p.u2())
+	fmt.Fprintf(abi, "class %s %x extends %s", class.Name, accessFlags, superClass)
+	for i, count := 0, int(p.u2()); i < count; i++ {
+		fmt.Fprintf(abi, " implements %s", p.className(p.u2()))
+	}
+
+	// Fields and methods contribute to the ABI unless other classes can't use them
+	constants := false
+	for _, kind := range []string{"field", "method"} {
+		for i, count := 0, int(p.u2()); i < count; i++ {
+			memberFlags := p.u2()
+			name := p.utf8(p.u2())
+			descriptor := p.utf8(p.u2())
+			visible := memberFlags&(accPrivate|accSynthetic) == 0
+
+			member := sha256.New()
+			fmt.Fprintf(member, "\n%s %x %s %s", kind, memberFlags, name, descriptor)
+			for j, attributes := 0, int(p.u2()); j < attributes; j++ {
+				attributeName, body := p.attribute()
+				if attributeName == "ConstantValue" && visible {
+					constants = true
+				}
+				p.memberAttribute(member, attributeName, body)
+			}
+			if visible {
+				abi.Write(member.Sum(nil))
+			}
+		}
+	}
+	class.HasConstants = constants
+
+	for i, count := 0, int(p.u2()); i < count; i++ {
+		attributeName, body := p.attribute()
+		switch attributeName {
+		case "SourceFile":
+			if len(body) == 2 {
+				class.SourceFile = p.utf8(binary.BigEndian.Uint16(body))
+			}
+		case "InnerClasses":
+			class.Private = p.isPrivateNested(class.Name, body)
+		case "Signature", "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+			fmt.Fprintf(abi, "\n%s ", attributeName)
+			p.hashAttribute(abi, attributeName, body)
+			if attributeName == "RuntimeVisibleAnnotations" {
+				kind := p.kotlinKind(body)
+				class.KotlinFacade = kind == kotlinFileFacade || kind == kotlinMultifileFacade
+			}
+		}
+	}
+	if p.err != nil {
+		return nil
+	}
+
+	// Every class named in the constant pool, including those only named in descriptors
+	for _, entry := range p.pool {
+		switch entry.tag {
+		case tagClass:
+			name := p.utf8(entry.index)
+			for strings.HasPrefix(name, "[") {
+				name = name[1:]
+			}
+			if strings.HasPrefix(name, "L") && strings.HasSuffix(name, ";") {
+				name = name[1 : len(name)-1]
+			}
+			references[name] = true
+		case tagUtf8:
+			for _, match := range descriptorClass.FindAllStringSubmatch(entry.value, -1) {
+				references[match[1]] = true
+			}
+		}
+	}
+	delete(references, class.Name)
+	for name := range references {
+		class.references = append(class.references, name)
+	}
+	sort.Strings(class.references)
+
+	class.abi = fmt.Sprintf("%x", abi.Sum(nil))
+	return class
+}
+
+// readConstantPool reads the constant pool, resolving the values of literals
+func (p *parser) readConstantPool() {
+	count := int(p.u2())
+	p.pool = make([]constant, count)
+	for i := 1; i < count && p.err == nil; i++ {
+		tag := p.u1()
+		entry := constant{tag: tag}
+		switch tag {
+		case tagUtf8:
+			entry.value = string(p.bytes(int(p.u2())))
+		case tagInteger, tagFloat:
+			entry.value = fmt.Sprintf("%x", p.bytes(4))
+		case tagLong, tagDouble:
+			entry.value = fmt.Sprintf("%x", p.bytes(8))
+		case tagClass, tagString, tagMethodType, tagModule, tagPackage:
+			entry.index = p.u2()
+		case tagFieldref, tagMethodref, tagInterfaceMethodref, tagNameAndType, tagDynamic, tagInvokeDynamic:
+			entry.index = p.u2()
+			p.skip(2)
+		case tagMethodHandle:
+			p.skip(1)
+			entry.index = p.u2()
+		default:
+			p.fail(fmt.Sprintf("unknown constant pool tag %d", tag))
+		}
+		p.pool[i] = entry
+
+		// Longs and doubles take up two entries
+		if tag == tagLong || tag == tagDouble {
+			i++
+		}
+	}
+}
+
+// attribute reads an attribute and returns its name and body
+func (p *parser) attribute() (string, []byte) {
+	name := p.utf8(p.u2())
+	return name, p.bytes(int(p.u4()))
+}
+
+// memberAttribute adds the attributes of a field or method that callers depend on to h
+func (p *parser) memberAttribute(h hash.Hash, name string, body []byte) {
+	switch name {
+	case "ConstantValue", "Signature", "Exceptions", "AnnotationDefault",
+		"RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+		fmt.Fprintf(h, " %s ", name)
+		p.hashAttribute(h, name, body)
+	}
+}
+
+// hashAttribute writes the contents of an attribute to h with constant pool
+// indices replaced by the constants they refer to, since the indices change
+// whenever anything else in the class changes
+func (p *parser) hashAttribute(h hash.Hash, name string, body []byte) {
+	attr := &parser{data: body, pool: p.pool}
+	switch name {
+	case "ConstantValue", "Signature":
+		attr.hashConstant(h, attr.u2())
+	case "Exceptions":
+		for i, count := 0, int(attr.u2()); i < count; i++ {
+			attr.hashConstant(h, attr.u2())
+		}
+	case "AnnotationDefault":
+		attr.hashElementValue(h)
+	case "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+		for i, count := 0, int(attr.u2()); i < count; i++ {
+			attr.hashAnnotation(h)
+		}
+	}
+	if attr.err != nil && p.err == nil {
+		p.err = fmt.Errorf("invalid %s attribute: %w", name, attr.err)
+	}
+}
+
+// hashAnnotation writes an annotation and its element values to h
+func (p *parser) hashAnnotation(h hash.Hash) {
+	fmt.Fprintf(h, "@%s(", p.utf8(p.u2()))
+	for i, count := 0, int(p.u2()); i < count && p.err == nil; i++ {
+		fmt.Fprintf(h, "%s=", p.utf8(p.u2()))
+		p.hashElementValue(h)
+	}
+	fmt.Fprint(h, ")")
+}
+
+// hashElementValue writes an annotation element value to h
+func (p *parser) hashElementValue(h hash.Hash) {
+	tag := p.u1()
+	fmt.Fprintf(h, "%c", tag)
+	switch tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's', 'c':
+		p.hashConstant(h, p.u2())
+	case 'e':
+		p.hashConstant(h, p.u2())
+		p.hashConstant(h, p.u2())
+	case '@':
+		p.hashAnnotation(h)
+	case '[':
+		for i, count := 0, int(p.u2()); i < count && p.err == nil; i++ {
+			p.hashElementValue(h)
+		}
+	default:
+		p.fail(fmt.Sprintf("unknown element value tag %c", tag))
+	}
+	fmt.Fprint(h, ";")
+}
+
+// hashConstant writes the value of a constant pool entry to h
+func (p *parser) hashConstant(h hash.Hash, index uint16) {
+	entry := p.constant(index)
+	switch entry.tag {
+	case tagClass, tagString:
+		fmt.Fprintf(h, "%d:%s,", entry.tag, p.utf8(entry.index))
+	default:
+		fmt.Fprintf(h, "%d:%s,", entry.tag, entry.value)
+	}
+}
+
+// kotlinKind returns the kind of class recorded in the kotlin.Metadata
+// annotation among the annotations in body, or 0 if there is none
+func (p *parser) kotlinKind(body []byte) int {
+	attr := &parser{data: body, pool: p.pool}
+	discard := sha256.New()
+	for i, count := 0, int(attr.u2()); i < count && attr.err == nil; i++ {
+		annotationType := attr.utf8(attr.u2())
+		for j, pairs := 0, int(attr.u2()); j < pairs && attr.err == nil; j++ {
+			name := attr.utf8(attr.u2())
+			if annotationType == "Lkotlin/Metadata;" && name == "k" && attr.pos+3 <= len(attr.data) && attr.data[attr.pos] == 'I' {
+				kind, _ := strconv.ParseInt(attr.constant(binary.BigEndian.Uint16(attr.data[attr.pos+1:])).value, 16, 32)
+				return int(kind)
+			}
+			attr.hashElementValue(discard)
+		}
+	}
+	return 0
+}
+
+// isPrivateNested reports whether the InnerClasses attribute declares the
+// class itself as private or anonymous
+func (p *parser) isPrivateNested(className string, body []byte) bool {
+	attr := &parser{data: body, pool: p.pool}
+	for i, count := 0, int(attr.u2()); i < count && attr.err == nil; i++ {
+		inner := attr.u2()
+		attr.skip(2) // outer class
+		innerName := attr.u2()
+		flags := attr.u2()
+		if attr.err == nil && p.className(inner) == className {
+			return innerName == 0 || flags&accPrivate != 0
+		}
+	}
+	return false
+}
+
+// constant returns a constant pool entry
+func (p *parser) constant(index uint16) constant {
+	if int(index) >= len(p.pool) {
+		p.fail(fmt.Sprintf("constant pool index %d out of range", index))
+		return constant{}
+	}
+	return p.pool[index]
+}
+
+// utf8 returns the string of a Utf8 constant pool entry
+func (p *parser) utf8(index uint16) string {
+	return p.constant(index).value
+}
+
+// className returns the name of a Class constant pool entry, or an empty string for index 0
+func (p *parser) className(index uint16) string {
+	if index == 0 {
+		return ""
+	}
+	return p.utf8(p.constant(index).index)
+}
+
+func (p *parser) fail(message string) {
+	if p.err == nil {
+		p.err = errors.New(message)
+	}
+}
+
+func (p *parser) bytes(n int) []byte {
+	if p.err != nil {
+		return nil
+	}
+	if n < 0 || p.pos+n > len(p.data) {
+		p.fail("truncated class file")
+		return nil
+	}
+	b := p.data[p.pos : p.pos+n]
+	p.pos += n
+	return b
+}
+
+func (p *parser) skip(n int) {
+	p.bytes(n)
+}
+
+func (p *parser) u1() byte {
+	if b := p.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (p *parser) u2() uint16 {
+	if b := p.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (p *parser) u4() uint32 {
+	if b := p.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
@@ -0,0 +1,198 @@
+package classfile
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// classBuilder assembles minimal class files for tests
+type classBuilder struct {
+	pool    [][]byte
+	strings map[string]uint16
+	members [2][][]byte // fields and methods
+	attrs   [][]byte
+}
+
+func newClassBuilder() *classBuilder {
+	return &classBuilder{strings: make(map[string]uint16)}
+}
+
+func u2(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+func (b *classBuilder) add(entry []byte) uint16 {
+	b.pool = append(b.pool, entry)
+	return uint16(len(b.pool))
+}
+
+func (b *classBuilder) utf8(s string) uint16 {
+	if index, exists := b.strings[s]; exists {
+		return index
+	}
+	entry := append([]byte{tagUtf8}, u2(uint16(len(s)))...)
+	index := b.add(append(entry, s...))
+	b.strings[s] = index
+	return index
+}
+
+func (b *classBuilder) class(name string) uint16 {
+	return b.add(append([]byte{tagClass}, u2(b.utf8(name))...))
+}
+
+func (b *classBuilder) attribute(name string, body []byte) []byte {
+	attr := u2(b.utf8(name))
+	attr = binary.BigEndian.AppendUint32(attr, uint32(len(body)))
+	return append(attr, body...)
+}
+
+// member adds a field (kind 0) or method (kind 1) with the given attributes
+func (b *classBuilder) member(kind int, flags uint16, name, descriptor string, attrs ...[]byte) {
+	member := append(u2(flags), u2(b.utf8(name))...)
+	member = append(member, u2(b.utf8(descriptor))...)
+	member = append(member, u2(uint16(len(attrs)))...)
+	for _, attr := range attrs {
+		member = append(member, attr...)
+	}
+	b.members[kind] = append(b.members[kind], member)
+}
+
+func (b *classBuilder) build(name, super string) []byte {
+	this := b.class(name)
+	superIndex := b.class(super)
+	var data []byte
+	data = binary.BigEndian.AppendUint32(data, magic)
+	data = append(data, 0, 0, 0, 61)
+	data = append(data, u2(uint16(len(b.pool)+1))...)
+	for _, entry := range b.pool {
+		data = append(data, entry...)
+	}
+	data = append(data, u2(0x0021)...)
+	data = append(data, u2(this)...)
+	data = append(data, u2(superIndex)...)
+	data = append(data, u2(0)...)
+	for _, members := range b.members {
+		data = append(data, u2(uint16(len(members)))...)
+		for _, member := range members {
+			data = append(data, member...)
+		}
+	}
+	data = append(data, u2(uint16(len(b.attrs)))...)
+	for _, attr := range b.attrs {
+		data = append(data, attr...)
+	}
+	return data
+}
+
+// greeter builds com/example/Greeter with a public method whose body is
+// padded with the given bytes and a private method with the given descriptor
+func greeter(body []byte, privateDescriptor string) []byte {
+	b := newClassBuilder()
+	b.member(1, 0x0001, "greet", "(Lcom/example/Name;)Ljava/lang/String;", b.attribute("Code", body))
+	b.member(1, 0x0002, "helper", privateDescriptor, b.attribute("Code", body))
+	b.class("com/example/Util")
+	b.attrs = append(b.attrs, b.attribute("SourceFile", u2(b.utf8("Greeter.kt"))))
+	return b.build("com/example/Greeter", "java/lang/Object")
+}
+
+func TestParse(t *testing.T) {
+	class, err := Parse(greeter([]byte{1, 2, 3}, "()V"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if class.Name != "com/example/Greeter" || class.Package() != "com/example" {
+		t.Errorf("Unexpected name %s in package %s", class.Name, class.Package())
+	}
+	if class.SourceFile != "Greeter.kt" {
+		t.Errorf("Expected source file Greeter.kt, got %s", class.SourceFile)
+	}
+	if class.Private || class.HasConstants {
+		t.Errorf("Expected a public class without constants")
+	}
+
+	expected := []string{"com/example/Name", "com/example/Util", "java/lang/Object", "java/lang/String"}
+	if !reflect.DeepEqual(class.References(), expected) {
+		t.Errorf("Expected references %v, got %v", expected, class.References())
+	}
+}
+
+func TestABI_IgnoresBodiesAndPrivateMembers(t *testing.T) {
+	original, err := Parse(greeter([]byte{1, 2, 3}, "()V"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	changedBody, err := Parse(greeter([]byte{4, 5, 6, 7}, "(I)V"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if changedBody.ABI() != original.ABI() {
+		t.Error("Expected method bodies and private members not to change the ABI")
+	}
+
+	b := newClassBuilder()
+	b.member(1, 0x0001, "greet", "(Lcom/example/Name;I)Ljava/lang/String;")
+	changedSignature, err := Parse(b.build("com/example/Greeter", "java/lang/Object"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if changedSignature.ABI() == original.ABI() {
+		t.Error("Expected a changed public signature to change the ABI")
+	}
+}
+
+func TestABI_IncludesConstantValues(t *testing.T) {
+	constant := func(value uint32) *Class {
+		b := newClassBuilder()
+		index := b.add(binary.BigEndian.AppendUint32([]byte{tagInteger}, value))
+		b.member(0, 0x0019, "LIMIT", "I", b.attribute("ConstantValue", u2(index)))
+		class, err := Parse(b.build("com/example/Limits", "java/lang/Object"))
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		return class
+	}
+
+	if !constant(1).HasConstants {
+		t.Error("Expected the class to declare constants")
+	}
+	if constant(1).ABI() == constant(2).ABI() {
+		t.Error("Expected a changed constant value to change the ABI")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	if _, err := Parse([]byte("not a class")); err == nil {
+		t.Error("Expected an error for data that isn't a class file")
+	}
+	if _, err := Parse(greeter([]byte{1}, "()V")[:40]); err == nil {
+		t.Error("Expected an error for a truncated class file")
+	}
+}
+
+func TestParse_KotlinFacade(t *testing.T) {
+	kotlinClass := func(kind uint32) *Class {
+		b := newClassBuilder()
+		kindIndex := b.add(binary.BigEndian.AppendUint32([]byte{tagInteger}, kind))
+		annotation := append(u2(1), u2(b.utf8("Lkotlin/Metadata;"))...)
+		annotation = append(annotation, u2(1)...)
+		annotation = append(annotation, u2(b.utf8("k"))...)
+		annotation = append(annotation, 'I')
+		annotation = append(annotation, u2(kindIndex)...)
+		b.attrs = append(b.attrs, b.attribute("RuntimeVisibleAnnotations", annotation))
+		class, err := Parse(b.build("com/example/MainKt", "java/lang/Object"))
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		return class
+	}
+
+	if !kotlinClass(kotlinFileFacade).KotlinFacade {
+		t.Error("Expected a file facade")
+	}
+	if kotlinClass(1).KotlinFacade {
+		t.Error("Expected a regular class not to be a facade")
+	}
+}
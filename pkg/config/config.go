@@ -10,6 +10,52 @@ import (
 // Config represents the merged configuration from all fbs.conf.json files
 type Config struct {
 	Discoverers map[string]json.RawMessage `json:"discoverers"`
+	Resources   ResourcesConfig            `json:"resources"`
+	// Timeouts maps task names (such as junit-test), task types (build, test
+	// or deps) and "default" to durations such as "10m"
+	Timeouts map[string]string `json:"timeouts"`
+	// Retries maps task names, task types and "default" to the retry policy of
+	// failed tasks
+	Retries map[string]RetryConfig `json:"retries"`
+	// Toolchain pins the compilers and JVM that tasks run
+	Toolchain ToolchainConfig `json:"toolchain"`
+}
+
+// ToolchainConfig configures where the kotlinc, java, javac and jar tools are found.
+// Relative paths are relative to the fbs.conf.json file they appear in. Tools
+// that aren't configured are looked up on the PATH.
+type ToolchainConfig struct {
+	// JavaHome is the JDK that provides java, javac and jar
+	JavaHome string `json:"javaHome"`
+	// KotlinHome is the Kotlin compiler distribution that provides kotlinc
+	KotlinHome string `json:"kotlinHome"`
+	// Kotlinc, Java, Javac and Jar override the path of a single tool
+	Kotlinc string `json:"kotlinc"`
+	Java    string `json:"java"`
+	Javac   string `json:"javac"`
+	Jar     string `json:"jar"`
+	// KotlinDaemon enables compiling in a persistent compiler JVM, which is the default
+	KotlinDaemon *bool `json:"kotlinDaemon"`
+}
+
+// RetryConfig configures how often a failed task is executed again
+type RetryConfig struct {
+	// Attempts is the maximum number of executions, including the first one
+	Attempts int `json:"attempts"`
+	// Backoff is the delay before the first retry, such as "1s", doubled for every further retry
+	Backoff string `json:"backoff"`
+	// MaxBackoff limits the delay between retries
+	MaxBackoff string `json:"maxBackoff"`
+}
+
+// ResourcesConfig configures the resource pools parallel tasks are scheduled
+// against. Zero values leave the defaults in place.
+type ResourcesConfig struct {
+	// MemoryMB is the memory in megabytes available to running tasks,
+	// three quarters of the system memory by default
+	MemoryMB int `json:"memoryMb"`
+	// Network is the number of concurrent network transfers, 8 by default
+	Network int `json:"network"`
 }
 
 // DiscovererConfig represents configuration for a specific discoverer
@@ -32,6 +78,8 @@ func (c *ArtifactDownloadConfig) GetDiscovererID() string {
 func LoadConfiguration(startDir string) (*Config, error) {
 	config := &Config{
 		Discoverers: make(map[string]json.RawMessage),
+		Timeouts:    make(map[string]string),
+		Retries:     make(map[string]RetryConfig),
 	}
 	
 	// Walk up the directory hierarchy looking for fbs.conf.json files
@@ -64,6 +112,42 @@ func LoadConfiguration(startDir string) (*Config, error) {
 	return config, nil
 }
 
+// FindWorkspaceRoot returns the outermost directory above startDir (inclusive) that
+// contains an fbs.conf.json file. Without one it falls back to the root of the
+// git repository containing startDir, the directory holding .git, so that the
+// root doesn't depend on where fbs is started. Outside of a repository it
+// returns startDir itself.
+func FindWorkspaceRoot(startDir string) string {
+	configRoot := ""
+	gitRoot := ""
+	currentDir := startDir
+	for {
+		if _, err := os.Stat(filepath.Join(currentDir, "fbs.conf.json")); err == nil {
+			configRoot = currentDir
+		}
+		if gitRoot == "" {
+			if _, err := os.Stat(filepath.Join(currentDir, ".git")); err == nil {
+				gitRoot = currentDir
+			}
+		}
+		
+		parentDir := filepath.Dir(currentDir)
+		if parentDir == currentDir {
+			break
+		}
+		currentDir = parentDir
+	}
+	
+	switch {
+	case configRoot != "":
+		return configRoot
+	case gitRoot != "":
+		return gitRoot
+	default:
+		return startDir
+	}
+}
+
 // mergeConfigFile merges a single config file into the current configuration
 func (c *Config) mergeConfigFile(configPath string) error {
 	data, err := os.ReadFile(configPath)
@@ -82,9 +166,50 @@ func (c *Config) mergeConfigFile(configPath string) error {
 		c.Discoverers[discovererID] = discovererConfig
 	}
 	
+	// Merge resource pools, keeping parent values that aren't overridden
+	if fileConfig.Resources.MemoryMB != 0 {
+		c.Resources.MemoryMB = fileConfig.Resources.MemoryMB
+	}
+	if fileConfig.Resources.Network != 0 {
+		c.Resources.Network = fileConfig.Resources.Network
+	}
+	
+	// Merge timeouts per task name or type
+	for key, timeout := range fileConfig.Timeouts {
+		c.Timeouts[key] = timeout
+	}
+	
+	// Merge retry policies per task name or type
+	for key, retry := range fileConfig.Retries {
+		c.Retries[key] = retry
+	}
+	
+	// Merge toolchain paths, resolving them against the directory of the config file
+	configDir := filepath.Dir(configPath)
+	mergePath(&c.Toolchain.JavaHome, fileConfig.Toolchain.JavaHome, configDir)
+	mergePath(&c.Toolchain.KotlinHome, fileConfig.Toolchain.KotlinHome, configDir)
+	mergePath(&c.Toolchain.Kotlinc, fileConfig.Toolchain.Kotlinc, configDir)
+	mergePath(&c.Toolchain.Java, fileConfig.Toolchain.Java, configDir)
+	mergePath(&c.Toolchain.Javac, fileConfig.Toolchain.Javac, configDir)
+	mergePath(&c.Toolchain.Jar, fileConfig.Toolchain.Jar, configDir)
+	if fileConfig.Toolchain.KotlinDaemon != nil {
+		c.Toolchain.KotlinDaemon = fileConfig.Toolchain.KotlinDaemon
+	}
+	
 	return nil
 }
 
+// mergePath overrides target with path if it is set, making a relative path absolute
+func mergePath(target *string, path, baseDir string) {
+	if path == "" {
+		return
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	*target = path
+}
+
 // GetDiscovererConfig retrieves configuration for a specific discoverer
 func (c *Config) GetDiscovererConfig(discovererID string, result interface{}) error {
 	rawConfig, exists := c.Discoverers[discovererID]
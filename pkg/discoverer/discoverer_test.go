@@ -70,6 +70,10 @@ func (m *MockTask) Execute(ctx context.Context, workDir string, dependencyInputs
 	return graph.TaskResult{Files: []string{m.id + ".txt"}}
 }
 
+func (m *MockTask) DisplayName() string {
+	return m.name
+}
+
 func TestMultiDiscoverer_Discover(t *testing.T) {
 	ctx := context.Background()
 	
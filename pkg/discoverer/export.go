@@ -0,0 +1,268 @@
+package discoverer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fbs/pkg/graph"
+)
+
+// PlanExportVersion is the version of the exported plan schema. It is bumped
+// whenever a field is removed or changes meaning; new fields may be added
+// without a version change.
+const PlanExportVersion = 1
+
+// PlanExport is a serializable description of a planned build graph
+type PlanExport struct {
+	SchemaVersion    int                     `json:"schemaVersion"`
+	RootDir          string                  `json:"rootDir"`
+	CompilationRoots []ExportCompilationRoot `json:"compilationRoots"`
+	Tasks            []ExportTask            `json:"tasks"`
+	Edges            []ExportEdge            `json:"edges"`
+	Cycles           [][]string              `json:"cycles"`
+	Errors           []string                `json:"errors"`
+}
+
+// ExportCompilationRoot describes a compilation root
+type ExportCompilationRoot struct {
+	Dir  string `json:"dir"`
+	Type string `json:"type"`
+}
+
+// ExportTask describes a single task. CacheKey is the hash including all
+// dependencies and is omitted when the graph has cycles.
+type ExportTask struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	DisplayName     string         `json:"displayName"`
+	Type            graph.TaskType `json:"type"`
+	Directory       string         `json:"directory"`
+	Hash            string         `json:"hash"`
+	CacheKey        string         `json:"cacheKey,omitempty"`
+	CompilationRoot string         `json:"compilationRoot,omitempty"`
+	Dependencies    []string       `json:"dependencies"`
+}
+
+// ExportEdge is a dependency edge: the task From depends on the task To
+type ExportEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NewPlanExport creates an export of a planning result with tasks and edges in a stable order
+func NewPlanExport(result *StructurePlanResult) *PlanExport {
+	export := &PlanExport{
+		SchemaVersion:    PlanExportVersion,
+		RootDir:          result.RootDir,
+		CompilationRoots: []ExportCompilationRoot{},
+		Tasks:            []ExportTask{},
+		Edges:            []ExportEdge{},
+		Cycles:           [][]string{},
+		Errors:           []string{},
+	}
+
+	for _, root := range result.CompilationRoots {
+		export.CompilationRoots = append(export.CompilationRoots, ExportCompilationRoot{
+			Dir:  root.GetRootDir(),
+			Type: root.GetType(),
+		})
+	}
+	sort.Slice(export.CompilationRoots, func(i, j int) bool {
+		return export.CompilationRoots[i].Dir < export.CompilationRoots[j].Dir
+	})
+
+	cycles := result.Graph.FindCycles()
+	for _, cycle := range cycles {
+		var ids []string
+		for _, task := range cycle {
+			ids = append(ids, task.ID())
+		}
+		export.Cycles = append(export.Cycles, ids)
+	}
+
+	tasks := append([]graph.Task(nil), result.Graph.GetTasks()...)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Directory() != tasks[j].Directory() {
+			return tasks[i].Directory() < tasks[j].Directory()
+		}
+		if tasks[i].DisplayName() != tasks[j].DisplayName() {
+			return tasks[i].DisplayName() < tasks[j].DisplayName()
+		}
+		return tasks[i].ID() < tasks[j].ID()
+	})
+
+	// Computing cache keys recurses through dependencies and never ends on a cycle
+	var cacheKeys map[string]string
+	if len(cycles) == 0 {
+		cacheKeys = graph.ComputeTaskHashes(tasks)
+	}
+
+	for _, task := range tasks {
+		exportTask := ExportTask{
+			ID:           task.ID(),
+			Name:         task.Name(),
+			DisplayName:  task.DisplayName(),
+			Type:         task.TaskType(),
+			Directory:    task.Directory(),
+			Hash:         task.Hash(),
+			Dependencies: append([]string{}, result.Graph.Edges(task.ID())...),
+		}
+		sort.Strings(exportTask.Dependencies)
+
+		exportTask.CacheKey = cacheKeys[task.ID()]
+		if root, exists := result.TaskCompilationRoots[task.ID()]; exists {
+			exportTask.CompilationRoot = root.GetRootDir()
+		}
+
+		export.Tasks = append(export.Tasks, exportTask)
+		for _, depID := range exportTask.Dependencies {
+			export.Edges = append(export.Edges, ExportEdge{From: task.ID(), To: depID})
+		}
+	}
+
+	for _, err := range result.Errors {
+		export.Errors = append(export.Errors, err.Error())
+	}
+
+	return export
+}
+
+// WriteJSON writes the export as indented JSON
+func (e *PlanExport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(e)
+}
+
+// WriteDOT writes the export as a Graphviz digraph with one cluster per compilation root
+func (e *PlanExport) WriteDOT(w io.Writer) error {
+	nodeIDs := e.nodeIDs()
+
+	var b strings.Builder
+	b.WriteString("digraph fbs {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for i, group := range e.groupByRoot() {
+		indent := "  "
+		if group.root != nil {
+			fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+			fmt.Fprintf(&b, "    label=%s;\n", dotQuote(fmt.Sprintf("%s: %s", group.root.Type, e.relPath(group.root.Dir))))
+			indent = "    "
+		}
+		for _, task := range group.tasks {
+			fmt.Fprintf(&b, "%s%s [label=%s];\n", indent, nodeIDs[task.ID], dotQuote(task.DisplayName+"\n"+e.relPath(task.Directory)))
+		}
+		if group.root != nil {
+			b.WriteString("  }\n")
+		}
+	}
+
+	for _, edge := range e.Edges {
+		if to, exists := nodeIDs[edge.To]; exists {
+			fmt.Fprintf(&b, "  %s -> %s;\n", nodeIDs[edge.From], to)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the export as a Mermaid flowchart with one subgraph per compilation root
+func (e *PlanExport) WriteMermaid(w io.Writer) error {
+	nodeIDs := e.nodeIDs()
+
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+
+	for i, group := range e.groupByRoot() {
+		indent := "  "
+		if group.root != nil {
+			fmt.Fprintf(&b, "  subgraph root%d[%s]\n", i, mermaidQuote(fmt.Sprintf("%s: %s", group.root.Type, e.relPath(group.root.Dir))))
+			indent = "    "
+		}
+		for _, task := range group.tasks {
+			fmt.Fprintf(&b, "%s%s[%s]\n", indent, nodeIDs[task.ID], mermaidQuote(task.DisplayName+"<br/>"+e.relPath(task.Directory)))
+		}
+		if group.root != nil {
+			b.WriteString("  end\n")
+		}
+	}
+
+	for _, edge := range e.Edges {
+		if to, exists := nodeIDs[edge.To]; exists {
+			fmt.Fprintf(&b, "  %s --> %s\n", nodeIDs[edge.From], to)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exportGroup is the set of tasks belonging to one compilation root
+type exportGroup struct {
+	root  *ExportCompilationRoot
+	tasks []ExportTask
+}
+
+// groupByRoot groups tasks by compilation root, with tasks without a root last
+func (e *PlanExport) groupByRoot() []exportGroup {
+	var groups []exportGroup
+	grouped := make(map[string]bool)
+	for i := range e.CompilationRoots {
+		root := &e.CompilationRoots[i]
+		group := exportGroup{root: root}
+		for _, task := range e.Tasks {
+			if task.CompilationRoot == root.Dir {
+				group.tasks = append(group.tasks, task)
+				grouped[task.ID] = true
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	ungrouped := exportGroup{}
+	for _, task := range e.Tasks {
+		if !grouped[task.ID] {
+			ungrouped.tasks = append(ungrouped.tasks, task)
+		}
+	}
+	return append(groups, ungrouped)
+}
+
+// nodeIDs assigns short identifiers to tasks, since task IDs may contain
+// characters that are not valid in Mermaid node names
+func (e *PlanExport) nodeIDs() map[string]string {
+	nodeIDs := make(map[string]string)
+	for i, task := range e.Tasks {
+		nodeIDs[task.ID] = fmt.Sprintf("t%d", i)
+	}
+	return nodeIDs
+}
+
+// relPath returns a path relative to the planning directory for use in labels
+func (e *PlanExport) relPath(path string) string {
+	relPath, err := filepath.Rel(e.RootDir, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return path
+	}
+	return relPath
+}
+
+// dotQuote quotes a string for use as a DOT identifier or label
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// mermaidQuote quotes a string for use as a Mermaid node label
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,133 @@
+package discoverer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"fbs/pkg/graph"
+)
+
+// MockDependentTask is a mock task with dependencies that can be changed after creation
+type MockDependentTask struct {
+	MockTask
+	deps []graph.Task
+}
+
+func (m *MockDependentTask) Dependencies() []graph.Task {
+	return m.deps
+}
+
+func newExportResult(t *testing.T, tasks ...graph.Task) *StructurePlanResult {
+	buildGraph := graph.NewGraph()
+	for _, task := range tasks {
+		if err := buildGraph.AddTask(task); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	buildGraph.RefreshEdges()
+
+	root := &MockCompilationRoot{rootDir: "/ws/app", rootType: "gradle"}
+	taskRoots := make(map[string]CompilationRoot)
+	for _, task := range tasks {
+		taskRoots[task.ID()] = root
+	}
+
+	return &StructurePlanResult{
+		Graph:                buildGraph,
+		RootDir:              "/ws",
+		TaskCompilationRoots: taskRoots,
+		CompilationRoots:     []CompilationRoot{root},
+	}
+}
+
+func TestPlanExport_JSON(t *testing.T) {
+	compile := &MockDependentTask{MockTask: MockTask{id: "compile", name: "kotlin-compile", directory: "/ws/app/src/main/kotlin", hash: "h1"}}
+	jar := &MockDependentTask{MockTask: MockTask{id: "jar", name: "jar-compile", directory: "/ws/app", hash: "h2"}, deps: []graph.Task{compile}}
+	result := newExportResult(t, jar, compile)
+	result.Errors = []error{errors.New("failed to read build.gradle.kts")}
+
+	var buf bytes.Buffer
+	if err := NewPlanExport(result).WriteJSON(&buf); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+
+	var export PlanExport
+	if err := json.Unmarshal(buf.Bytes(), &export); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	if export.SchemaVersion != PlanExportVersion {
+		t.Errorf("Expected schema version %d, got %d", PlanExportVersion, export.SchemaVersion)
+	}
+	if len(export.Tasks) != 2 || export.Tasks[0].ID != "jar" || export.Tasks[1].ID != "compile" {
+		t.Fatalf("Expected tasks sorted by directory, got %+v", export.Tasks)
+	}
+	if export.Tasks[0].CacheKey == "" || export.Tasks[0].CompilationRoot != "/ws/app" {
+		t.Errorf("Expected cache key and compilation root, got %+v", export.Tasks[0])
+	}
+	if len(export.Edges) != 1 || export.Edges[0] != (ExportEdge{From: "jar", To: "compile"}) {
+		t.Errorf("Expected a single jar -> compile edge, got %+v", export.Edges)
+	}
+	if len(export.Cycles) != 0 {
+		t.Errorf("Expected no cycles, got %v", export.Cycles)
+	}
+	if len(export.Errors) != 1 {
+		t.Errorf("Expected planning error to be exported, got %v", export.Errors)
+	}
+}
+
+func TestPlanExport_Cycles(t *testing.T) {
+	a := &MockDependentTask{MockTask: MockTask{id: "a", name: "a", directory: "/ws/app/a", hash: "ha"}}
+	b := &MockDependentTask{MockTask: MockTask{id: "b", name: "b", directory: "/ws/app/b", hash: "hb"}, deps: []graph.Task{a}}
+	a.deps = []graph.Task{b}
+
+	export := NewPlanExport(newExportResult(t, a, b))
+
+	if len(export.Cycles) != 1 || len(export.Cycles[0]) != 2 {
+		t.Fatalf("Expected one cycle of two tasks, got %v", export.Cycles)
+	}
+	for _, task := range export.Tasks {
+		if task.CacheKey != "" {
+			t.Errorf("Expected no cache key for %s in a cyclic graph", task.ID)
+		}
+	}
+}
+
+func TestPlanExport_DOTAndMermaid(t *testing.T) {
+	compile := &MockDependentTask{MockTask: MockTask{id: "compile", name: "kotlin-compile", directory: "/ws/app/src/main/kotlin", hash: "h1"}}
+	jar := &MockDependentTask{MockTask: MockTask{id: "jar", name: `jar "main"`, directory: "/ws/app", hash: "h2"}, deps: []graph.Task{compile}}
+	export := NewPlanExport(newExportResult(t, jar, compile))
+
+	var dot bytes.Buffer
+	if err := export.WriteDOT(&dot); err != nil {
+		t.Fatalf("Failed to write DOT: %v", err)
+	}
+	for _, expected := range []string{
+		"digraph fbs {",
+		`label="gradle: app";`,
+		`t0 [label="jar \"main\"\napp"];`,
+		"t0 -> t1;",
+	} {
+		if !strings.Contains(dot.String(), expected) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", expected, dot.String())
+		}
+	}
+
+	var mermaid bytes.Buffer
+	if err := export.WriteMermaid(&mermaid); err != nil {
+		t.Fatalf("Failed to write Mermaid: %v", err)
+	}
+	for _, expected := range []string{
+		"graph LR",
+		`subgraph root0["gradle: app"]`,
+		`t0["jar #quot;main#quot;<br/>app"]`,
+		"t0 --> t1",
+	} {
+		if !strings.Contains(mermaid.String(), expected) {
+			t.Errorf("Expected Mermaid output to contain %q, got:\n%s", expected, mermaid.String())
+		}
+	}
+}
@@ -74,6 +74,10 @@ func (m *MockPlanTask) Execute(ctx context.Context, workDir string, dependencyIn
 	return graph.TaskResult{Files: []string{m.id + ".txt"}}
 }
 
+func (m *MockPlanTask) DisplayName() string {
+	return m.name
+}
+
 func TestFindGitRoot(t *testing.T) {
 	// This test assumes we're running in a git repository
 	rootDir, err := findGitRoot()
@@ -2,6 +2,7 @@ package discoverer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,6 +26,17 @@ type StructurePlanResult struct {
 	CompilationRoots []CompilationRoot
 }
 
+// CycleError returns the error describing the dependency cycles of the graph, or nil if it has none
+func (r *StructurePlanResult) CycleError() error {
+	for _, err := range r.Errors {
+		var cycleErr *graph.CycleError
+		if errors.As(err, &cycleErr) {
+			return err
+		}
+	}
+	return nil
+}
+
 // PlanWithStructure discovers build tasks using structure-based discovery
 // Given a directory, it discovers all tasks from subdirectories, finding their
 // compilation roots and organizing them accordingly
@@ -175,6 +187,14 @@ func PlanWithStructure(ctx context.Context, dir string, discoverers []Discoverer
 		}
 	}
 	
+	// Project dependencies were added to tasks that are already in the graph
+	buildGraph.RefreshEdges()
+	
+	// Report cycles with the plan, they would otherwise only surface once the runner sorts the graph
+	if err := buildGraph.CheckCycles(); err != nil {
+		allErrors = append(allErrors, err)
+	}
+	
 	return &StructurePlanResult{
 		Graph:                buildGraph,
 		Errors:               allErrors,
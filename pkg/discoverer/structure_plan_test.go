@@ -2,8 +2,10 @@ package discoverer
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"fbs/pkg/graph"
@@ -45,10 +47,14 @@ func (m *MockCompilationRoot) GetBuildContext(dir string) *BuildContext {
 	return NewBuildContext()
 }
 
-func (m *MockCompilationRoot) GetTaskDependencies(dir string, tasks []graph.Task) []graph.Task {
+func (m *MockCompilationRoot) GetTaskDependencies(dir string, tasks []graph.Task, buildContext *BuildContext) []graph.Task {
 	return tasks // Return tasks unchanged for simple testing
 }
 
+func (m *MockCompilationRoot) ResolveProjectDependencies(buildGraph *graph.Graph, allRoots []CompilationRoot) error {
+	return nil
+}
+
 func TestPlanWithStructure_FindsCompilationRoot(t *testing.T) {
 	// Create temporary directory structure
 	tempDir, err := os.MkdirTemp("", "structure_plan_test")
@@ -223,4 +229,78 @@ func TestPlanWithStructure_TraversesUpwards(t *testing.T) {
 	if resolvedRootDir != resolvedTempDir {
 		t.Errorf("Expected compilation root dir %s, got %s", resolvedTempDir, resolvedRootDir)
 	}
-}
\ No newline at end of file
+}
+
+// CyclicCompilationRoot links every pair of its tasks in both directions when resolving project dependencies
+type CyclicCompilationRoot struct {
+	MockCompilationRoot
+}
+
+func (m *CyclicCompilationRoot) ResolveProjectDependencies(buildGraph *graph.Graph, allRoots []CompilationRoot) error {
+	tasks := buildGraph.GetTasks()
+	for _, task := range tasks {
+		for _, other := range tasks {
+			if other != task {
+				task.(*MockDependentTask).deps = append(task.(*MockDependentTask).deps, other)
+			}
+		}
+	}
+	return nil
+}
+
+func TestPlanWithStructure_DetectsCycles(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"api", "web"} {
+		if err := os.Mkdir(filepath.Join(tempDir, name), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+
+	structureDisc := &MockStructureDiscoverer{
+		name: "MockStructure",
+		checkFunc: func(dir string) CompilationRoot {
+			if dir == tempDir {
+				return &CyclicCompilationRoot{MockCompilationRoot{rootDir: dir, rootType: "mock"}}
+			}
+			return nil
+		},
+	}
+
+	discoverer := NewMockPlanDiscoverer("MockDiscoverer",
+		func(ctx context.Context, path string, potentialDependencies []graph.Task, buildContext *BuildContext) (*DiscoveryResult, error) {
+			if path == tempDir {
+				return &DiscoveryResult{Path: path}, nil
+			}
+			name := filepath.Base(path)
+			return &DiscoveryResult{
+				Tasks: []graph.Task{
+					&MockDependentTask{MockTask: MockTask{id: name, name: name + "-jar", directory: path, hash: name}},
+				},
+				Path: path,
+			}, nil
+		})
+
+	result, err := PlanWithStructure(context.Background(), tempDir, []Discoverer{discoverer}, []StructureDiscoverer{structureDisc})
+	if err != nil {
+		t.Fatalf("PlanWithStructure failed: %v", err)
+	}
+
+	// The planned graph is kept so that the cycle can be exported
+	if len(result.Graph.GetTasks()) != 2 {
+		t.Errorf("Expected 2 planned tasks, got %d", len(result.Graph.GetTasks()))
+	}
+	if cycles := NewPlanExport(result).Cycles; len(cycles) != 1 {
+		t.Errorf("Expected the export to contain 1 cycle, got %v", cycles)
+	}
+
+	err = result.CycleError()
+	var cycleErr *graph.CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("Expected the plan to report a cycle error, got %v", err)
+	}
+	for _, expected := range []string{"api-jar (" + filepath.Join(tempDir, "api") + ")", "-> web-jar (" + filepath.Join(tempDir, "web") + ")"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to contain %q, got:\n%s", expected, err.Error())
+		}
+	}
+}
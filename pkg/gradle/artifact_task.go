@@ -98,12 +98,18 @@ func (a *ArtifactDownload) TaskType() graph.TaskType {
 	return graph.TaskTypeDeps
 }
 
+// Resources returns the resources of a download, which is network-bound and
+// doesn't occupy a CPU slot
+func (a *ArtifactDownload) Resources() graph.Resources {
+	return graph.Resources{Network: 1}
+}
+
 // Execute runs the artifact download task
 func (a *ArtifactDownload) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
 	var allJars []string
 	
 	// Download main artifact
-	mainJar, err := a.downloadArtifact(a.group, a.name, a.version)
+	mainJar, err := a.downloadArtifact(ctx, a.group, a.name, a.version)
 	if err != nil {
 		return graph.TaskResult{
 			Error: fmt.Errorf("failed to download main artifact %s: %w", a.artifact, err),
@@ -113,10 +119,10 @@ func (a *ArtifactDownload) Execute(ctx context.Context, workDir string, dependen
 	
 	// Download transitive dependencies
 	for _, dep := range a.transitive {
-		depJar, err := a.downloadArtifact(dep.GroupID, dep.ArtifactID, dep.Version)
+		depJar, err := a.downloadArtifact(ctx, dep.GroupID, dep.ArtifactID, dep.Version)
 		if err != nil {
 			// Log warning but continue with other dependencies
-			fmt.Printf("Warning: failed to download transitive dependency %s: %v\n", dep.String(), err)
+			fmt.Fprintf(graph.TaskLog(ctx), "Warning: failed to download transitive dependency %s: %v\n", dep.String(), err)
 			continue
 		}
 		allJars = append(allJars, depJar)
@@ -129,7 +135,7 @@ func (a *ArtifactDownload) Execute(ctx context.Context, workDir string, dependen
 }
 
 // downloadArtifact downloads a single artifact JAR
-func (a *ArtifactDownload) downloadArtifact(group, name, version string) (string, error) {
+func (a *ArtifactDownload) downloadArtifact(ctx context.Context, group, name, version string) (string, error) {
 	// Generate local cache path
 	homeDir, _ := os.UserHomeDir()
 	localPath := filepath.Join(homeDir, ".gradle", "caches", "modules-2", "files-2.1", 
@@ -147,14 +153,19 @@ func (a *ArtifactDownload) downloadArtifact(group, name, version string) (string
 	
 	// Try each repository until one works
 	var lastErr error
+	notFound := 0
 	for _, repoURL := range a.repositories {
 		// Construct download URL for this repository
 		downloadURL := fmt.Sprintf("%s/%s/%s/%s/%s-%s.jar",
 			strings.TrimSuffix(repoURL, "/"),
 			strings.ReplaceAll(group, ".", "/"), name, version, name, version)
 		
-		// Try to download from this repository
-		resp, err := http.Get(downloadURL)
+		// Try to download from this repository, giving up when the build is cancelled
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			lastErr = fmt.Errorf("failed to download from %s: %w", repoURL, err)
 			continue
@@ -163,6 +174,9 @@ func (a *ArtifactDownload) downloadArtifact(group, name, version string) (string
 		
 		if resp.StatusCode != http.StatusOK {
 			resp.Body.Close()
+			if resp.StatusCode == http.StatusNotFound {
+				notFound++
+			}
 			lastErr = fmt.Errorf("failed to download from %s: HTTP %d", repoURL, resp.StatusCode)
 			continue
 		}
@@ -180,6 +194,8 @@ func (a *ArtifactDownload) downloadArtifact(group, name, version string) (string
 		resp.Body.Close()
 		
 		if err != nil {
+			// Don't leave a truncated JAR behind, it would be mistaken for a complete download
+			os.Remove(localPath)
 			return "", fmt.Errorf("failed to save artifact: %w", err)
 		}
 		
@@ -187,7 +203,12 @@ func (a *ArtifactDownload) downloadArtifact(group, name, version string) (string
 	}
 	
 	// If we get here, all repositories failed
-	return "", fmt.Errorf("failed to download %s:%s:%s from any repository: %w", group, name, version, lastErr)
+	err := fmt.Errorf("failed to download %s:%s:%s from any repository: %w", group, name, version, lastErr)
+	if notFound == len(a.repositories) {
+		// The artifact doesn't exist, so retrying the download won't help
+		return "", graph.Permanent(err)
+	}
+	return "", err
 }
 
 // GetArtifact returns the artifact coordinate
@@ -223,11 +244,11 @@ func (a *ArtifactDownload) generateID() string {
 	return fmt.Sprintf("%x", hasher.Sum(nil))
 }
 
-// generateHash creates a hash for this task's configuration
+// generateHash creates a hash for this task's configuration. It leaves out the
+// local cache path, which depends on the user's home directory.
 func (a *ArtifactDownload) generateHash() string {
 	hasher := sha256.New()
 	hasher.Write([]byte(a.artifact))
-	hasher.Write([]byte(a.localPath))
 	for _, repo := range a.repositories {
 		hasher.Write([]byte(repo))
 	}
@@ -239,6 +260,11 @@ func (a *ArtifactDownload) DisplayName() string {
 	return fmt.Sprintf("artifact-download (%s)", a.artifact)
 }
 
+// TargetNames returns the artifact coordinates this task can be selected by
+func (a *ArtifactDownload) TargetNames() []string {
+	return []string{a.artifact}
+}
+
 // GetDisplayPath returns a clean display path without the full cache path
 func (a *ArtifactDownload) GetDisplayPath() string {
 	return a.artifact
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -23,6 +24,7 @@ type GradleBuildInfo struct {
 	ProjectDir   string
 	Dependencies []GradleDependency
 	Plugins      []string
+	JvmToolchain int // JDK major version from jvmToolchain(21), or 0 if not set
 }
 
 // ParseGradleBuildFile parses a build.gradle.kts file and extracts dependency information
@@ -49,6 +51,7 @@ func ParseGradleBuildFile(buildFilePath string) (*GradleBuildInfo, error) {
 	stringDependencyRegex := regexp.MustCompile(`["']([^"']+)["']`)
 	libsDependencyRegex := regexp.MustCompile(`libs\.([^)]+)`)
 	pluginRegex := regexp.MustCompile(`^\s*(id|kotlin)\s*\(\s*["']([^"']+)["']\s*\)`)
+	jvmToolchainRegex := regexp.MustCompile(`jvmToolchain\s*\(\s*(\d+)\s*\)|JavaLanguageVersion\.of\s*\(\s*(\d+)\s*\)`)
 	
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -58,6 +61,16 @@ func ParseGradleBuildFile(buildFilePath string) (*GradleBuildInfo, error) {
 			continue
 		}
 		
+		// The JDK version can be set with kotlin { jvmToolchain(21) } or
+		// java { toolchain { languageVersion.set(JavaLanguageVersion.of(21)) } }
+		if matches := jvmToolchainRegex.FindStringSubmatch(line); matches != nil {
+			version := matches[1]
+			if version == "" {
+				version = matches[2]
+			}
+			buildInfo.JvmToolchain, _ = strconv.Atoi(version)
+		}
+		
 		// Track if we're in dependencies or plugins block
 		if strings.Contains(line, "dependencies {") {
 			inDependenciesBlock = true
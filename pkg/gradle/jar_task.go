@@ -5,19 +5,20 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
 )
 
-// JarCompile represents a task that compiles Kotlin sources into a JAR file
+// JarCompile represents a task that packages compiled Kotlin and Java classes and resources into a JAR file
 type JarCompile struct {
 	projectDir   string
 	outputPath   string
 	mainSources  []string
 	dependencies []graph.Task
+	toolchain    *toolchain.Toolchain
 	id           string
 	hash         string
 }
@@ -76,8 +77,18 @@ func (j *JarCompile) TaskType() graph.TaskType {
 	return graph.TaskTypeBuild
 }
 
+// Resources returns the resources of the jar tool's JVM
+func (j *JarCompile) Resources() graph.Resources {
+	return graph.Resources{CPU: 1, MemoryMB: 256}
+}
+
 // Execute runs the JAR compilation task
 func (j *JarCompile) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
+	tools := j.tools()
+	if tools.Err != nil {
+		return graph.TaskResult{Error: tools.Err}
+	}
+	
 	// Create JAR file in the work directory (cache), not in the project directory
 	jarFileName := filepath.Base(j.outputPath)
 	jarPath := filepath.Join(workDir, jarFileName)
@@ -89,68 +100,78 @@ func (j *JarCompile) Execute(ctx context.Context, workDir string, dependencyInpu
 		}
 	}
 	
-	// Collect all .class files from dependency inputs
-	var classFiles []string
+	// Collect all .class files and resources from dependency inputs, and the
+	// ABI fingerprints of the tasks they came from. Every file is added
+	// relative to the classes or resources directory it was written to, since
+	// Kotlin classes, Java classes and resources end up in different directories.
+	var entries []string
+	var abis []string
 	for _, depInput := range dependencyInputs {
+		abis = append(abis, depInput.ABI)
 		for _, file := range depInput.Files {
-			if strings.HasSuffix(file, ".class") {
-				fullPath := filepath.Join(depInput.OutputDir, file)
-				classFiles = append(classFiles, fullPath)
+			var baseDir string
+			switch {
+			case strings.HasSuffix(file, ".class"):
+				baseDir = "classes"
+			case strings.HasPrefix(filepath.ToSlash(file), "resources/"):
+				baseDir = "resources"
+			default:
+				continue
+			}
+			
+			entryDir := depInput.OutputDir
+			if relPath, found := strings.CutPrefix(filepath.ToSlash(file), baseDir+"/"); found {
+				entryDir = filepath.Join(entryDir, baseDir)
+				file = relPath
 			}
+			if err := graph.CheckStaged(ctx, entryDir); err != nil {
+				return graph.TaskResult{Error: err}
+			}
+			entries = append(entries, "-C", entryDir, file)
 		}
 	}
 	
-	if len(classFiles) == 0 {
+	if len(entries) == 0 {
 		return graph.TaskResult{
-			Error: fmt.Errorf("no compiled classes found to package"),
+			Error: fmt.Errorf("no compiled classes or resources found to package"),
 		}
 	}
 	
-	// Create JAR file using jar command  
-	cmd := exec.CommandContext(ctx, "jar", "cf", jarPath)
-	
-	// Find the common classes directory to work from
-	var classesDir string
-	if len(classFiles) > 0 {
-		// Look for the classes directory in the path
-		firstClassFile := classFiles[0]
-		if strings.Contains(firstClassFile, "/classes/") {
-			classesDir = firstClassFile[:strings.Index(firstClassFile, "/classes/")+9] // Include "/classes/"
-		} else {
-			classesDir = filepath.Dir(firstClassFile)
-		}
-	}
+	// Create JAR file using jar command
+	cmd := graph.Command(ctx, tools.Jar.Path, append([]string{"cf", jarPath}, entries...)...)
 	
-	// Set working directory to the classes directory
-	if classesDir != "" {
-		cmd.Dir = classesDir
-		
-		// Add all class files relative to the classes directory
-		for _, classFile := range classFiles {
-			relPath, err := filepath.Rel(classesDir, classFile)
-			if err != nil {
-				relPath = filepath.Base(classFile) // Just use filename as fallback
-			}
-			cmd.Args = append(cmd.Args, relPath)
-		}
-	} else {
-		// Fallback: add files directly
-		cmd.Args = append(cmd.Args, classFiles...)
-	}
+	cmd.Stdout = graph.TaskLog(ctx)
+	cmd.Stderr = cmd.Stdout
 	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
+	if err := cmd.Run(); err != nil {
 		return graph.TaskResult{
-			Error: fmt.Errorf("jar compilation failed: %w\nOutput: %s", err, string(output)),
+			Error: fmt.Errorf("jar compilation failed: %w", err),
 		}
 	}
 	
-	// Return the JAR file as output (relative path for caching)
+	// Return the JAR file as output (relative path for caching). Its ABI is
+	// that of the classes it packages, so compilations against the JAR aren't
+	// repeated when only their implementation changed.
 	return graph.TaskResult{
 		Files: []string{jarFileName},
+		ABI:   graph.CombineABI(abis...),
 	}
 }
 
+// SetToolchain sets the JDK whose jar tool packages the classes
+func (j *JarCompile) SetToolchain(tools *toolchain.Toolchain) {
+	j.toolchain = tools
+	j.hash = j.generateHash()
+}
+
+// tools returns the toolchain of this task, defaulting to the tools on the PATH
+func (j *JarCompile) tools() *toolchain.Toolchain {
+	if j.toolchain == nil {
+		return toolchain.Default()
+	}
+	return j.toolchain
+}
+
 // GetOutputPath returns the path where the JAR file will be created
 func (j *JarCompile) GetOutputPath() string {
 	return j.outputPath
@@ -175,11 +196,12 @@ func (j *JarCompile) generateID() string {
 // generateHash creates a hash for this task's configuration
 func (j *JarCompile) generateHash() string {
 	hasher := sha256.New()
-	hasher.Write([]byte(j.projectDir))
-	hasher.Write([]byte(j.outputPath))
+	hasher.Write([]byte(graph.HashPath(j.projectDir)))
+	hasher.Write([]byte(graph.HashPath(j.outputPath)))
 	for _, source := range j.mainSources {
 		hasher.Write([]byte(source))
 	}
+	hasher.Write([]byte(j.tools().Jar.Fingerprint()))
 	return fmt.Sprintf("%x", hasher.Sum(nil))
 }
 
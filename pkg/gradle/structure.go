@@ -9,7 +9,9 @@ import (
 	"fbs/pkg/config"
 	"fbs/pkg/discoverer"
 	"fbs/pkg/graph"
+	"fbs/pkg/java"
 	"fbs/pkg/kotlin"
+	"fbs/pkg/toolchain"
 )
 
 // GradleStructureDiscoverer discovers Gradle compilation roots
@@ -57,12 +59,23 @@ type GradleCompilationRoot struct {
 	artifactTasks    []*ArtifactDownload // Cached artifact tasks
 	jarTaskReturned  bool                // Track if JAR task has been returned
 	artifactsReturned bool               // Track if artifact tasks have been returned
+	sourceSets       map[string]*sourceSetTasks // Tasks of each source set, which span several directories
+}
+
+// sourceSetTasks are the tasks discovered in the source roots of a source set,
+// such as src/main/kotlin, src/main/java and src/main/resources
+type sourceSetTasks struct {
+	kotlinTasks    []*kotlin.KotlinCompile
+	javaTasks      []*java.JavaCompile
+	resourcesTasks []*java.ProcessResources
+	junitTasks     []*kotlin.JunitTest
 }
 
 // NewGradleCompilationRoot creates a new Gradle compilation root
 func NewGradleCompilationRoot(rootDir string) *GradleCompilationRoot {
 	root := &GradleCompilationRoot{
-		rootDir: rootDir,
+		rootDir:    rootDir,
+		sourceSets: make(map[string]*sourceSetTasks),
 	}
 	
 	// Try to load version catalog from the project root
@@ -92,6 +105,11 @@ func (g *GradleCompilationRoot) GetBuildContext(dir string) *discoverer.BuildCon
 		context.Set(g.versions)
 	}
 	
+	// Request the JDK version the project builds with
+	if g.buildInfo != nil && g.buildInfo.JvmToolchain > 0 {
+		context.Set(&toolchain.Request{JavaVersion: g.buildInfo.JvmToolchain})
+	}
+	
 	return context
 }
 
@@ -113,44 +131,56 @@ func (g *GradleCompilationRoot) GetTaskDependencies(dir string, tasks []graph.Ta
 	
 	// Separate different types of tasks
 	var kotlinCompileTasks []*kotlin.KotlinCompile
+	var javaCompileTasks []*java.JavaCompile
+	var resourcesTasks []*java.ProcessResources
 	var junitTestTasks []*kotlin.JunitTest
-	var mainKotlinTasks []*kotlin.KotlinCompile
-	var testKotlinTasks []*kotlin.KotlinCompile
+	var mainTasks []graph.Task // Tasks whose output is packaged into the JAR
+	var testCompileTasks []graph.Task
 	
 	for _, task := range tasks {
+		var sourceDir string
 		switch t := task.(type) {
 		case *kotlin.KotlinCompile:
 			kotlinCompileTasks = append(kotlinCompileTasks, t)
-			// Check if this is a main source compile task
-			if strings.Contains(t.GetSourceDir(), "src/main") {
-				mainKotlinTasks = append(mainKotlinTasks, t)
-			}
-			// Check if this is a test source compile task
-			if strings.Contains(t.GetSourceDir(), "src/test") {
-				testKotlinTasks = append(testKotlinTasks, t)
+			sourceDir = t.GetSourceDir()
+		case *java.JavaCompile:
+			javaCompileTasks = append(javaCompileTasks, t)
+			sourceDir = t.GetSourceDir()
+		case *java.ProcessResources:
+			resourcesTasks = append(resourcesTasks, t)
+			if strings.Contains(t.GetResourceDir(), "src/main") {
+				mainTasks = append(mainTasks, task)
 			}
 		case *kotlin.JunitTest:
 			junitTestTasks = append(junitTestTasks, t)
 		}
+		// Check if this is a main or test source compile task
+		if strings.Contains(sourceDir, "src/main") {
+			mainTasks = append(mainTasks, task)
+		}
+		if strings.Contains(sourceDir, "src/test") {
+			testCompileTasks = append(testCompileTasks, task)
+		}
 		allTasks = append(allTasks, task)
 	}
 	
 	// 1. Create or reuse JAR compilation task for main sources
-	if len(mainKotlinTasks) > 0 && g.jarTask == nil {
+	if len(mainTasks) > 0 && g.jarTask == nil {
 		// Create JAR task only once per compilation root
 		g.jarTask = NewJarCompile(g.rootDir, []string{}) // Start with empty sources
+		g.jarTask.SetToolchain(toolchain.FromBuildContext(buildContext))
 	}
 	
-	// Add main kotlin tasks as dependencies to JAR task if it exists
+	// Add main kotlin, java and resources tasks as dependencies to JAR task if it exists
 	if g.jarTask != nil {
-		for _, kotlinTask := range mainKotlinTasks {
-			g.jarTask.AddDependency(kotlinTask)
+		for _, mainTask := range mainTasks {
+			g.jarTask.AddDependency(mainTask)
 		}
 		// Always include JAR task when there are main tasks (first time) or test tasks that need it
-		if len(mainKotlinTasks) > 0 && !g.jarTaskReturned {
+		if len(mainTasks) > 0 && !g.jarTaskReturned {
 			allTasks = append(allTasks, g.jarTask)
 			g.jarTaskReturned = true
-		} else if len(testKotlinTasks) > 0 {
+		} else if len(testCompileTasks) > 0 {
 			// Also include the JAR task when we have test tasks that depend on it
 			allTasks = append(allTasks, g.jarTask)
 		}
@@ -210,6 +240,11 @@ func (g *GradleCompilationRoot) GetTaskDependencies(dir string, tasks []graph.Ta
 			kotlinTask.AddDependency(artifactTask)
 		}
 	}
+	for _, javaTask := range javaCompileTasks {
+		for _, artifactTask := range g.artifactTasks {
+			javaTask.AddDependency(artifactTask)
+		}
+	}
 	
 	// 3.5. Add JAR compilation as dependency to test compilation tasks
 	// This must happen after the JAR task is created and added to allTasks
@@ -219,6 +254,11 @@ func (g *GradleCompilationRoot) GetTaskDependencies(dir string, tasks []graph.Ta
 				kotlinTask.AddDependency(g.jarTask)
 			}
 		}
+		for _, javaTask := range javaCompileTasks {
+			if strings.Contains(javaTask.GetSourceDir(), "src/test") {
+				javaTask.AddDependency(g.jarTask)
+			}
+		}
 	}
 	
 	// 4. Add JAR task as dependency for test tasks (if it exists)
@@ -269,9 +309,77 @@ func (g *GradleCompilationRoot) GetTaskDependencies(dir string, tasks []graph.Ta
 		}
 	}
 	
+	// 7. Connect the Kotlin, Java and resources tasks of each source set,
+	// whose source roots are discovered separately
+	g.linkSourceSet(dir, kotlinCompileTasks, javaCompileTasks, resourcesTasks, junitTestTasks)
+	
 	return allTasks
 }
 
+// linkSourceSet records the tasks discovered in dir with the other tasks of
+// its source set, and makes Java compilations depend on the Kotlin classes of
+// the source set and JUnit tests on its Java classes and resources
+func (g *GradleCompilationRoot) linkSourceSet(dir string, kotlinTasks []*kotlin.KotlinCompile, javaTasks []*java.JavaCompile, resourcesTasks []*java.ProcessResources, junitTasks []*kotlin.JunitTest) {
+	sourceSetDir := g.sourceSetDir(dir)
+	if sourceSetDir == "" {
+		return
+	}
+	sourceSet, exists := g.sourceSets[sourceSetDir]
+	if !exists {
+		sourceSet = &sourceSetTasks{}
+		g.sourceSets[sourceSetDir] = sourceSet
+	}
+	sourceSet.kotlinTasks = append(sourceSet.kotlinTasks, kotlinTasks...)
+	sourceSet.javaTasks = append(sourceSet.javaTasks, javaTasks...)
+	sourceSet.resourcesTasks = append(sourceSet.resourcesTasks, resourcesTasks...)
+	sourceSet.junitTasks = append(sourceSet.junitTasks, junitTasks...)
+	
+	for _, javaTask := range sourceSet.javaTasks {
+		for _, kotlinTask := range sourceSet.kotlinTasks {
+			if !dependsOn(javaTask, kotlinTask) {
+				javaTask.AddDependency(kotlinTask)
+			}
+		}
+		for _, junitTask := range sourceSet.junitTasks {
+			if !dependsOn(junitTask, javaTask) {
+				junitTask.AddDependency(javaTask)
+			}
+		}
+	}
+	for _, resourcesTask := range sourceSet.resourcesTasks {
+		for _, junitTask := range sourceSet.junitTasks {
+			if !dependsOn(junitTask, resourcesTask) {
+				junitTask.AddDependency(resourcesTask)
+			}
+		}
+	}
+}
+
+// sourceSetDir returns the directory of the source set that dir belongs to,
+// such as src/main for src/main/kotlin/com/example, or an empty string if dir
+// isn't part of a source set
+func (g *GradleCompilationRoot) sourceSetDir(dir string) string {
+	rel, err := filepath.Rel(g.rootDir, dir)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || parts[0] != "src" {
+		return ""
+	}
+	return filepath.Join(g.rootDir, parts[0], parts[1])
+}
+
+// dependsOn checks if a task already has a specific task as a dependency
+func dependsOn(task graph.Task, dependency graph.Task) bool {
+	for _, dep := range task.Dependencies() {
+		if dep.ID() == dependency.ID() {
+			return true
+		}
+	}
+	return false
+}
+
 // loadVersionCatalog loads the Gradle version catalog if it exists
 func (g *GradleCompilationRoot) loadVersionCatalog() {
 	// Search upward from the compilation root to find version catalog
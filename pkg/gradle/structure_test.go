@@ -4,7 +4,14 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"fbs/pkg/discoverer"
+	"fbs/pkg/graph"
+	"fbs/pkg/java"
+	"fbs/pkg/kotlin"
+	"fbs/pkg/toolchain"
 )
 
 func TestGradleStructureDiscoverer_IsCompilationRoot(t *testing.T) {
@@ -106,9 +113,169 @@ kotlin-jvm = { id = "org.jetbrains.kotlin.jvm", version.ref = "kotlin" }
 	}
 }
 
+func TestGradleCompilationRoot_JvmToolchain(t *testing.T) {
+	tempDir := t.TempDir()
+	buildFile := `plugins {
+    kotlin("jvm")
+}
+
+kotlin {
+    jvmToolchain(21)
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "build.gradle.kts"), []byte(buildFile), 0644); err != nil {
+		t.Fatalf("Failed to create build file: %v", err)
+	}
+
+	root := NewGradleCompilationRoot(tempDir)
+	if root.GetBuildInfo().JvmToolchain != 21 {
+		t.Errorf("Expected jvmToolchain 21, got %d", root.GetBuildInfo().JvmToolchain)
+	}
+
+	request := root.GetBuildContext(tempDir).GetByExample((*toolchain.Request)(nil))
+	if request == nil || request.(*toolchain.Request).JavaVersion != 21 {
+		t.Errorf("Expected a JDK 21 request in the build context, got %v", request)
+	}
+}
+
+func TestGradleCompilationRoot_MixedSourceSets(t *testing.T) {
+	tempDir := t.TempDir()
+	for path, content := range map[string]string{
+		"build.gradle.kts":                "plugins {\n    kotlin(\"jvm\")\n}\n",
+		"src/main/kotlin/App.kt":          "package com.example\n",
+		"src/main/java/Legacy.java":       "package com.example;\n",
+		"src/test/kotlin/AppTest.kt":      "package com.example\n",
+		"src/test/java/TestFixtures.java": "package com.example;\n",
+		"src/main/resources/app.conf":     "port = 8080\n",
+		"src/test/resources/fixture.json": "{}\n",
+	} {
+		os.MkdirAll(filepath.Dir(filepath.Join(tempDir, path)), 0755)
+		if err := os.WriteFile(filepath.Join(tempDir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+
+	discoverers := []discoverer.Discoverer{kotlin.NewKotlinDiscoverer(), java.NewJavaDiscoverer(), java.NewResourcesDiscoverer(), kotlin.NewJunitDiscoverer()}
+	result, err := discoverer.PlanWithStructure(context.Background(), tempDir, discoverers, []discoverer.StructureDiscoverer{NewGradleStructureDiscoverer()})
+	if err != nil {
+		t.Fatalf("Planning failed: %v", err)
+	}
+
+	// findTask returns the task of the given type discovered in a directory of tempDir
+	findTask := func(name, dir string) graph.Task {
+		t.Helper()
+		for _, task := range result.Graph.GetTasks() {
+			if task.Name() == name && task.Directory() == filepath.Join(tempDir, dir) {
+				return task
+			}
+		}
+		t.Fatalf("No %s task found in %s", name, dir)
+		return nil
+	}
+	expectDependency := func(task, dependency graph.Task) {
+		t.Helper()
+		for _, dep := range task.Dependencies() {
+			if dep.ID() == dependency.ID() {
+				return
+			}
+		}
+		t.Errorf("Expected %s in %s to depend on %s in %s", task.Name(), task.Directory(), dependency.Name(), dependency.Directory())
+	}
+
+	mainKotlin := findTask("kotlin-compile", "src/main/kotlin")
+	mainJava := findTask("java-compile", "src/main/java")
+	testJava := findTask("java-compile", "src/test/java")
+	jar := findTask("jar-compile", "")
+
+	// javac compiles against the Kotlin classes, and the JAR contains both and the resources
+	expectDependency(mainJava, mainKotlin)
+	expectDependency(jar, mainKotlin)
+	expectDependency(jar, mainJava)
+	expectDependency(jar, findTask("process-resources", "src/main/resources"))
+
+	// Test sources compile against the JAR, and tests run with all test classes
+	expectDependency(testJava, findTask("kotlin-compile", "src/test/kotlin"))
+	expectDependency(testJava, jar)
+	junit := findTask("junit-test", "src/test/kotlin")
+	expectDependency(junit, testJava)
+	expectDependency(junit, findTask("process-resources", "src/test/resources"))
+}
+
+func TestJarCompile_PackagesClassesAndResources(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	fakeJar := filepath.Join(t.TempDir(), "jar")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n"
+	if err := os.WriteFile(fakeJar, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to create fake jar: %v", err)
+	}
+
+	task := NewJarCompile(t.TempDir(), []string{})
+	task.SetToolchain(&toolchain.Toolchain{Jar: toolchain.Tool{Name: "jar", Path: fakeJar}})
+
+	kotlinOutput, javaOutput, resourcesOutput := t.TempDir(), t.TempDir(), t.TempDir()
+	result := task.Execute(context.Background(), t.TempDir(), []graph.DependencyInput{
+		{OutputDir: kotlinOutput, Files: []string{"classes/com/example/App.class"}},
+		{OutputDir: javaOutput, Files: []string{"classes/com/example/Legacy.class"}},
+		{OutputDir: resourcesOutput, Files: []string{"resources/db/migration/V1__init.sql"}},
+	})
+	if result.Error != nil {
+		t.Fatalf("Packaging failed: %v", result.Error)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	for _, expected := range []string{
+		"-C " + filepath.Join(kotlinOutput, "classes") + " com/example/App.class",
+		"-C " + filepath.Join(javaOutput, "classes") + " com/example/Legacy.class",
+		"-C " + filepath.Join(resourcesOutput, "resources") + " db/migration/V1__init.sql",
+	} {
+		if !strings.Contains(string(args), expected) {
+			t.Errorf("Expected jar arguments to contain %q, got %q", expected, args)
+		}
+	}
+}
+
+func TestHash_IndependentOfCheckoutLocation(t *testing.T) {
+	defer graph.SetWorkspaceRoot("")
+	tools := &toolchain.Toolchain{Jar: toolchain.Tool{Name: "jar", Version: "21"}}
+
+	// hashes plans a checkout of the same project in a new directory
+	hashes := func() []string {
+		root := t.TempDir()
+		projectDir := filepath.Join(root, "service")
+		os.MkdirAll(projectDir, 0755)
+		if err := os.WriteFile(filepath.Join(projectDir, "build.gradle.kts"), []byte("plugins {}\n"), 0644); err != nil {
+			t.Fatalf("Failed to create build file: %v", err)
+		}
+		graph.SetWorkspaceRoot(root)
+
+		jar := NewJarCompile(projectDir, []string{})
+		jar.SetToolchain(tools)
+		return []string{jar.Hash(), NewGradleProject(projectDir, "build.gradle.kts").Hash()}
+	}
+
+	first, second := hashes(), hashes()
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Errorf("Expected identical checkouts to have identical hashes, got %v and %v", first, second)
+	}
+}
+
+func TestArtifactDownload_HashIndependentOfHome(t *testing.T) {
+	repositories := []string{"https://repo1.maven.org/maven2"}
+	first := &ArtifactDownload{artifact: "com.example:lib:1.0", repositories: repositories, localPath: "/home/alice/.gradle/caches/lib-1.0.jar"}
+	second := &ArtifactDownload{artifact: "com.example:lib:1.0", repositories: repositories, localPath: "/home/ci/.gradle/caches/lib-1.0.jar"}
+	if first.generateHash() != second.generateHash() {
+		t.Error("Expected the artifact hash not to depend on the home directory")
+	}
+
+	other := &ArtifactDownload{artifact: "com.example:lib:1.1", repositories: repositories}
+	if other.generateHash() == first.generateHash() {
+		t.Error("Expected the artifact hash to change with the coordinate")
+	}
+}
+
 func TestGradleStructureDiscoverer_Name(t *testing.T) {
 	discoverer := NewGradleStructureDiscoverer()
 	if discoverer.Name() != "GradleStructureDiscoverer" {
 		t.Errorf("Expected name 'GradleStructureDiscoverer', got '%s'", discoverer.Name())
 	}
-}
\ No newline at end of file
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"fbs/pkg/graph"
@@ -51,14 +50,14 @@ func (g *GradleProject) TaskType() graph.TaskType {
 func (g *GradleProject) Hash() string {
 	h := sha256.New()
 	
-	// Include task type and project directory
+	// Include task type and project directory, relative to the workspace root
 	h.Write([]byte("GradleProject"))
-	h.Write([]byte(g.projectDir))
+	h.Write([]byte(graph.HashPath(g.projectDir)))
 	h.Write([]byte(g.buildFile))
 	
-	// Include build file modification time if file exists
-	if info, err := os.Stat(filepath.Join(g.projectDir, g.buildFile)); err == nil {
-		h.Write([]byte(fmt.Sprintf("%d", info.ModTime().Unix())))
+	// Include build file contents digest if file exists
+	if digest, err := graph.FileDigest(filepath.Join(g.projectDir, g.buildFile)); err == nil {
+		h.Write([]byte(digest))
 	}
 	
 	return fmt.Sprintf("%x", h.Sum(nil))
@@ -71,6 +70,11 @@ func (g *GradleProject) Dependencies() []graph.Task {
 
 // Execute runs the Gradle project build
 func (g *GradleProject) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
+	// Gradle reads the whole project in place and can't run on staged inputs
+	if graph.Sandboxed(ctx) {
+		return graph.TaskResult{Error: fmt.Errorf("gradle project %s can't run in the sandbox", g.projectDir)}
+	}
+	
 	// Create build output directory
 	buildDir := filepath.Join(workDir, "gradle-build")
 	if err := os.MkdirAll(buildDir, 0755); err != nil {
@@ -88,13 +92,15 @@ func (g *GradleProject) Execute(ctx context.Context, workDir string, dependencyI
 	args := []string{"build", "--build-cache"}
 	
 	// Execute gradle command
-	cmd := exec.CommandContext(ctx, gradleCmd, args...)
+	cmd := graph.Command(ctx, gradleCmd, args...)
 	cmd.Dir = g.projectDir
 	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
+	cmd.Stdout = graph.TaskLog(ctx)
+	cmd.Stderr = cmd.Stdout
+	
+	if err := cmd.Run(); err != nil {
 		return graph.TaskResult{
-			Error: fmt.Errorf("gradle build failed: %w\nOutput: %s", err, string(output)),
+			Error: fmt.Errorf("gradle build failed: %w", err),
 		}
 	}
 	
@@ -112,7 +118,7 @@ func (g *GradleProject) Execute(ctx context.Context, workDir string, dependencyI
 	
 	// List generated build files
 	var buildFiles []string
-	err = filepath.Walk(buildDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(buildDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+const (
+	benchmarkTasks      = 50000
+	benchmarkLayerWidth = 500
+)
+
+// newSyntheticTasks creates tasks in layers, where every task depends on up to
+// three tasks of the previous layer, similar to modules in a large monorepo
+func newSyntheticTasks(n, width int) []Task {
+	tasks := make([]Task, 0, n)
+	for i := 0; i < n; i++ {
+		var deps []Task
+		if layerStart := i - i%width; layerStart > 0 {
+			for j := 0; j < 3; j++ {
+				deps = append(deps, tasks[layerStart-width+(i*7+j*13)%width])
+			}
+		}
+		task := NewMockTask(fmt.Sprintf("task-%d", i), "mock-task", fmt.Sprintf("/bench/%d", i/width), fmt.Sprintf("hash-%d", i), deps)
+		task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+			return TaskResult{}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+func newSyntheticGraph(b *testing.B, tasks []Task) *Graph {
+	graph := NewGraph()
+	for _, task := range tasks {
+		if err := graph.AddTask(task); err != nil {
+			b.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	return graph
+}
+
+func BenchmarkGraph_AddTask(b *testing.B) {
+	tasks := newSyntheticTasks(benchmarkTasks, benchmarkLayerWidth)
+	b.ResetTimer()
+	
+	for i := 0; i < b.N; i++ {
+		newSyntheticGraph(b, tasks)
+	}
+}
+
+func BenchmarkGraph_TopologicalSort(b *testing.B) {
+	graph := newSyntheticGraph(b, newSyntheticTasks(benchmarkTasks, benchmarkLayerWidth))
+	b.ResetTimer()
+	
+	for i := 0; i < b.N; i++ {
+		if _, err := graph.TopologicalSort(); err != nil {
+			b.Fatalf("Failed to sort tasks: %v", err)
+		}
+	}
+}
+
+func BenchmarkComputeTaskHashes(b *testing.B) {
+	tasks := newSyntheticTasks(benchmarkTasks, benchmarkLayerWidth)
+	b.ResetTimer()
+	
+	for i := 0; i < b.N; i++ {
+		ComputeTaskHashes(tasks)
+	}
+}
+
+func BenchmarkRunner_ExecuteParallel(b *testing.B) {
+	graph := newSyntheticGraph(b, newSyntheticTasks(benchmarkTasks, benchmarkLayerWidth))
+	runner := NewRunner(b.TempDir())
+	b.ResetTimer()
+	
+	for i := 0; i < b.N; i++ {
+		results, err := runner.ExecuteWithProgressParallel(context.Background(), graph, nil, 8)
+		if err != nil {
+			b.Fatalf("Failed to execute tasks: %v", err)
+		}
+		if len(results) != benchmarkTasks {
+			b.Fatalf("Expected %d results, got %d", benchmarkTasks, len(results))
+		}
+	}
+}
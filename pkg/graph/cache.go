@@ -0,0 +1,336 @@
+package graph
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CacheBackend stores task outputs keyed by task hash
+type CacheBackend interface {
+	// Name returns a human-readable name for this cache backend
+	Name() string
+
+	// Fetch restores the outputs stored under taskHash into destDir
+	// It returns false if the cache has no entry for the hash
+	Fetch(ctx context.Context, taskHash string, destDir string) (bool, error)
+
+	// Store saves the outputs found in srcDir under taskHash
+	Store(ctx context.Context, taskHash string, srcDir string) error
+}
+
+// DiskCache is a cache backend that keeps one directory per task hash,
+// for example on a shared network mount
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a cache backend rooted at the given directory
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{
+		dir: dir,
+	}
+}
+
+// Name returns the name of this cache backend
+func (d *DiskCache) Name() string {
+	return "disk"
+}
+
+// Fetch copies the entry for taskHash into destDir
+func (d *DiskCache) Fetch(ctx context.Context, taskHash string, destDir string) (bool, error) {
+	entryDir := filepath.Join(d.dir, taskHash)
+	if !dirHasEntries(entryDir) {
+		return false, nil
+	}
+
+	if err := copyTree(entryDir, destDir); err != nil {
+		return false, fmt.Errorf("failed to copy cache entry %s: %w", taskHash, err)
+	}
+
+	return true, nil
+}
+
+// Store copies srcDir into the entry for taskHash
+func (d *DiskCache) Store(ctx context.Context, taskHash string, srcDir string) error {
+	entryDir := filepath.Join(d.dir, taskHash)
+	if dirHasEntries(entryDir) {
+		return nil // Already stored
+	}
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Copy into a staging directory first so readers never see a partial entry
+	stagingDir, err := os.MkdirTemp(d.dir, ".store-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	if err := copyTree(srcDir, stagingDir); err != nil {
+		return fmt.Errorf("failed to copy outputs to cache: %w", err)
+	}
+
+	if err := os.Rename(stagingDir, entryDir); err != nil {
+		// Another writer may have stored the same entry concurrently
+		if dirHasEntries(entryDir) {
+			return nil
+		}
+		return fmt.Errorf("failed to commit cache entry %s: %w", taskHash, err)
+	}
+
+	return nil
+}
+
+// HTTPCache is a cache backend that GETs and PUTs one gzipped tarball per task hash.
+// Any static file server that accepts PUT requests can serve as the remote.
+type HTTPCache struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPCache creates a cache backend for the given base URL
+func NewHTTPCache(baseURL string) *HTTPCache {
+	return &HTTPCache{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  http.DefaultClient,
+	}
+}
+
+// Name returns the name of this cache backend
+func (h *HTTPCache) Name() string {
+	return "http"
+}
+
+// Fetch downloads and extracts the tarball for taskHash into destDir
+func (h *HTTPCache) Fetch(ctx context.Context, taskHash string, destDir string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.entryURL(taskHash), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch %s: %w", taskHash, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to fetch %s: HTTP %d", taskHash, resp.StatusCode)
+	}
+
+	if err := extractTarGz(resp.Body, destDir); err != nil {
+		return false, fmt.Errorf("failed to extract %s: %w", taskHash, err)
+	}
+
+	return true, nil
+}
+
+// Store packs srcDir into a tarball and uploads it under taskHash
+func (h *HTTPCache) Store(ctx context.Context, taskHash string, srcDir string) error {
+	// Pack into a temporary file so the request has a known content length
+	archive, err := os.CreateTemp("", "fbs-cache-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	if err := writeTarGz(archive, srcDir); err != nil {
+		return fmt.Errorf("failed to pack %s: %w", taskHash, err)
+	}
+
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s: %w", taskHash, err)
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to pack %s: %w", taskHash, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, h.entryURL(taskHash), archive)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", taskHash, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to upload %s: HTTP %d", taskHash, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// entryURL returns the URL of the tarball for the given task hash
+func (h *HTTPCache) entryURL(taskHash string) string {
+	return fmt.Sprintf("%s/%s.tar.gz", h.baseURL, taskHash)
+}
+
+// writeTarGz writes the regular files and directories under srcDir as a gzipped tarball
+func writeTarGz(w io.Writer, srcDir string) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// extractTarGz extracts a gzipped tarball into destDir, rejecting entries that escape it
+func extractTarGz(r io.Reader, destDir string) error {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(header.Name)
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+		destPath := filepath.Join(destDir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(destPath, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tarReader)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			// Task outputs only consist of regular files and directories
+			continue
+		}
+	}
+}
+
+// copyTree copies the regular files and directories under srcDir into destDir
+func copyTree(srcDir, destDir string) error {
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(destDir, relPath)
+
+		if info.IsDir() {
+			return os.MkdirAll(destPath, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return copyFile(path, destPath, info.Mode())
+	})
+}
+
+// copyFile copies a single regular file
+func copyFile(src, dst string, mode os.FileMode) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+		return err
+	}
+	return dstFile.Close()
+}
+
+// dirHasEntries reports whether dir exists and is a non-empty directory
+func dirHasEntries(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err == nil && len(entries) > 0
+}
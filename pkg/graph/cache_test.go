@@ -0,0 +1,281 @@
+package graph
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestCacheServer starts an in-process static file server that accepts PUT requests
+func newTestCacheServer(t *testing.T) (*httptest.Server, map[string][]byte) {
+	var mu sync.Mutex
+	blobs := make(map[string][]byte)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			data, exists := blobs[r.URL.Path]
+			if !exists {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		case http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			blobs[r.URL.Path] = data
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, blobs
+}
+
+// newCountingTask creates a task that writes a nested output file and counts its executions
+func newCountingTask(executionCount *int) *MockTask {
+	task := NewMockTask("remote", "mock-task", "/test", "hashRemote", nil)
+	task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+		*executionCount++
+		outputPath := filepath.Join(workDir, "classes", "Remote.class")
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return TaskResult{Error: err}
+		}
+		if err := os.WriteFile(outputPath, []byte("remote output"), 0644); err != nil {
+			return TaskResult{Error: err}
+		}
+		return TaskResult{Files: []string{filepath.Join("classes", "Remote.class")}}
+	}
+	return task
+}
+
+func TestRunner_HTTPRemoteCache(t *testing.T) {
+	server, blobs := newTestCacheServer(t)
+	ctx := context.Background()
+
+	executionCount := 0
+	task := newCountingTask(&executionCount)
+	graph := NewGraph()
+	graph.AddTask(task)
+
+	// The first runner executes the task and populates the remote cache
+	producer := NewRunner(t.TempDir())
+	producer.SetRemoteCache(NewHTTPCache(server.URL), true)
+	results, err := producer.Execute(ctx, graph)
+	if err != nil {
+		t.Fatalf("First execution failed: %v", err)
+	}
+	if results[0].CacheHit {
+		t.Error("First execution should not be a cache hit")
+	}
+	if results[0].CacheError != nil {
+		t.Fatalf("Unexpected cache error: %v", results[0].CacheError)
+	}
+	if len(blobs) != 1 {
+		t.Fatalf("Expected 1 uploaded entry, got %d", len(blobs))
+	}
+
+	// A second runner with an empty local cache should fetch from the remote
+	consumer := NewRunner(t.TempDir())
+	consumer.SetRemoteCache(NewHTTPCache(server.URL), false)
+	results, err = consumer.Execute(ctx, graph)
+	if err != nil {
+		t.Fatalf("Second execution failed: %v", err)
+	}
+	if !results[0].CacheHit || !results[0].RemoteHit {
+		t.Error("Second execution should be a remote cache hit")
+	}
+	if executionCount != 1 {
+		t.Errorf("Expected 1 execution, got %d", executionCount)
+	}
+
+	content, err := os.ReadFile(filepath.Join(results[0].OutputDir, "classes", "Remote.class"))
+	if err != nil {
+		t.Fatalf("Failed to read fetched output: %v", err)
+	}
+	if string(content) != "remote output" {
+		t.Errorf("Expected fetched output 'remote output', got '%s'", string(content))
+	}
+
+	// A third run should be served from the local result directory
+	results, err = consumer.Execute(ctx, graph)
+	if err != nil {
+		t.Fatalf("Third execution failed: %v", err)
+	}
+	if !results[0].CacheHit || results[0].RemoteHit {
+		t.Error("Third execution should be a local cache hit")
+	}
+}
+
+func TestRunner_RemoteCacheUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	executionCount := 0
+	graph := NewGraph()
+	graph.AddTask(newCountingTask(&executionCount))
+
+	runner := NewRunner(t.TempDir())
+	runner.SetRemoteCache(NewHTTPCache(server.URL), true)
+	results, err := runner.Execute(context.Background(), graph)
+	if err != nil {
+		t.Fatalf("Remote cache failures should not fail the build: %v", err)
+	}
+	if executionCount != 1 {
+		t.Errorf("Expected the task to be executed, got %d executions", executionCount)
+	}
+	if results[0].CacheError == nil || !strings.Contains(results[0].CacheError.Error(), "HTTP 500") {
+		t.Errorf("Expected an HTTP 500 cache error, got %v", results[0].CacheError)
+	}
+}
+
+func TestDiskCache_StoreAndFetch(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDiskCache(t.TempDir())
+
+	srcDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(srcDir, "classes"), 0755); err != nil {
+		t.Fatalf("Failed to create source dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "classes", "A.class"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	found, err := cache.Fetch(ctx, "missing", t.TempDir())
+	if err != nil || found {
+		t.Fatalf("Expected a miss for unknown hash, got found=%v err=%v", found, err)
+	}
+
+	if err := cache.Store(ctx, "hash1", srcDir); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	destDir := t.TempDir()
+	found, err = cache.Fetch(ctx, "hash1", destDir)
+	if err != nil || !found {
+		t.Fatalf("Expected a hit, got found=%v err=%v", found, err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "classes", "A.class")); err != nil {
+		t.Errorf("Expected fetched file to exist: %v", err)
+	}
+}
+
+// runCachedTasks executes a task per hash so that each gets a local cache entry
+func runCachedTasks(t *testing.T, runner *Runner, ids ...string) []ExecutionResult {
+	graph := NewGraph()
+	for _, id := range ids {
+		graph.AddTask(NewMockTask(id, "mock-task", "/test", "hash-"+id, nil))
+	}
+	results, err := runner.Execute(context.Background(), graph)
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	return results
+}
+
+func TestLocalCache_GarbageCollect(t *testing.T) {
+	resultDir := t.TempDir()
+	runner := NewRunner(resultDir)
+	results := runCachedTasks(t, runner, "old", "new")
+
+	cache := NewLocalCache(resultDir)
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Entries != 2 {
+		t.Fatalf("Expected 2 entries, got %d", stats.Entries)
+	}
+
+	// Pretend the first entry was last used a week ago
+	var oldHash, newHash string
+	for _, result := range results {
+		if result.Task.ID() == "old" {
+			oldHash = result.TaskHash
+		} else {
+			newHash = result.TaskHash
+		}
+	}
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+	if err := os.Chtimes(cache.manifestPath(oldHash), weekAgo, weekAgo); err != nil {
+		t.Fatalf("Failed to age entry: %v", err)
+	}
+
+	evicted, err := cache.GarbageCollect(0, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("GarbageCollect failed: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0].Hash != oldHash {
+		t.Fatalf("Expected only the old entry to be evicted, got %v", evicted)
+	}
+
+	// A cache hit refreshes the access time of the remaining entry
+	results = runCachedTasks(t, runner, "new")
+	if !results[0].CacheHit {
+		t.Error("Expected the remaining entry to be a cache hit")
+	}
+
+	// A size limit smaller than the entry evicts everything
+	evicted, err = cache.GarbageCollect(1, 0)
+	if err != nil {
+		t.Fatalf("GarbageCollect failed: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0].Hash != newHash {
+		t.Fatalf("Expected the remaining entry to be evicted, got %v", evicted)
+	}
+}
+
+func TestLocalCache_Verify(t *testing.T) {
+	resultDir := t.TempDir()
+	results := runCachedTasks(t, NewRunner(resultDir), "intact", "corrupt")
+
+	cache := NewLocalCache(resultDir)
+	problems, err := cache.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v", problems)
+	}
+
+	// Truncate an output file and simulate an entry without metadata
+	for _, result := range results {
+		if result.Task.ID() == "corrupt" {
+			if err := os.WriteFile(filepath.Join(result.OutputDir, "corrupt.txt"), nil, 0644); err != nil {
+				t.Fatalf("Failed to corrupt entry: %v", err)
+			}
+		}
+	}
+	partialDir := filepath.Join(resultDir, "partial")
+	if err := os.MkdirAll(partialDir, 0755); err != nil {
+		t.Fatalf("Failed to create partial entry: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(partialDir, "half.txt"), []byte("half"), 0644); err != nil {
+		t.Fatalf("Failed to create partial entry: %v", err)
+	}
+
+	problems, err = cache.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %v", problems)
+	}
+}
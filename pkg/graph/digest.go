@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// racyWindow is how close to "now" a file modification time may be before
+// its digest is considered unsafe to remember. A file written within the same
+// timestamp granularity as the digest could change again without its stat
+// information changing.
+const racyWindow = 2 * time.Second
+
+// digestEntry records the content digest of a file together with the stat
+// information that was observed when the digest was computed
+type digestEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Digest  string `json:"digest"`
+}
+
+// DigestIndex maps file stat information to content digests so that unchanged
+// files don't have to be re-read on every run
+type DigestIndex struct {
+	path    string
+	entries map[string]digestEntry
+	dirty   bool
+	mu      sync.Mutex
+}
+
+// NewDigestIndex creates a digest index persisted at the given path.
+// An empty path creates an in-memory index. A missing or unreadable index
+// file results in an empty index.
+func NewDigestIndex(path string) *DigestIndex {
+	index := &DigestIndex{
+		path:    path,
+		entries: make(map[string]digestEntry),
+	}
+
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			// A corrupt index is not fatal, it only costs re-reading the files
+			if err := json.Unmarshal(data, &index.entries); err != nil {
+				index.entries = make(map[string]digestEntry)
+			}
+		}
+	}
+
+	return index
+}
+
+// FileDigest returns the hex encoded SHA-256 digest of the file contents,
+// reusing the recorded digest if the file's size and modification time are unchanged
+func (d *DigestIndex) FileDigest(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for %s: %w", path, err)
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", absPath)
+	}
+
+	d.mu.Lock()
+	entry, exists := d.entries[absPath]
+	d.mu.Unlock()
+
+	if exists && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+		return entry.Digest, nil
+	}
+
+	digest, err := digestFile(absPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Only remember digests of files that were not modified very recently,
+	// otherwise a quick subsequent write could go unnoticed
+	if time.Since(info.ModTime()) > racyWindow {
+		d.mu.Lock()
+		d.entries[absPath] = digestEntry{
+			Size:    info.Size(),
+			ModTime: info.ModTime().UnixNano(),
+			Digest:  digest,
+		}
+		d.dirty = true
+		d.mu.Unlock()
+	}
+
+	return digest, nil
+}
+
+// Save writes the index to disk if it has changed since it was loaded
+func (d *DigestIndex) Save() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.path == "" || !d.dirty {
+		return nil
+	}
+
+	// Drop entries for files that no longer exist
+	for path := range d.entries {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(d.entries, path)
+		}
+	}
+
+	data, err := json.Marshal(d.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode digest index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return fmt.Errorf("failed to create digest index directory: %w", err)
+	}
+
+	// Write to a temporary file first so a concurrent reader never sees a partial index
+	tempFile, err := os.CreateTemp(filepath.Dir(d.path), ".digests-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary digest index: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write digest index: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write digest index: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), d.path); err != nil {
+		return fmt.Errorf("failed to replace digest index: %w", err)
+	}
+
+	d.dirty = false
+	return nil
+}
+
+// digestFile computes the hex encoded SHA-256 digest of a file's contents
+func digestFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+var (
+	defaultDigestIndex   = NewDigestIndex("")
+	defaultDigestIndexMu sync.RWMutex
+)
+
+// SetDefaultDigestIndex replaces the digest index used by FileDigest
+func SetDefaultDigestIndex(index *DigestIndex) {
+	defaultDigestIndexMu.Lock()
+	defer defaultDigestIndexMu.Unlock()
+	defaultDigestIndex = index
+}
+
+// FileDigest returns the content digest of a file using the default digest index.
+// Task implementations use this to hash their input files.
+func FileDigest(path string) (string, error) {
+	defaultDigestIndexMu.RLock()
+	index := defaultDigestIndex
+	defaultDigestIndexMu.RUnlock()
+
+	return index.FileDigest(path)
+}
@@ -0,0 +1,158 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultTaskDuration is the estimate for tasks that have never been executed
+// and whose type has no recorded durations either
+const defaultTaskDuration = time.Second
+
+// DurationHistory records how long tasks took to execute in previous runs, so
+// the scheduler can estimate the remaining work behind each task. Tasks are
+// identified by their directory and display name, since their IDs change with
+// every change to their inputs.
+type DurationHistory struct {
+	path    string
+	entries map[string]durationEntry
+	types   map[TaskType]time.Duration // average duration per task type
+	dirty   bool
+	mu      sync.Mutex
+}
+
+// durationEntry records the last execution time of a task
+type durationEntry struct {
+	TaskType   TaskType `json:"type"`
+	DurationMs int64    `json:"durationMs"`
+}
+
+// NewDurationHistory creates a duration history persisted at the given path.
+// An empty path creates an in-memory history. A missing or unreadable history
+// file results in an empty history.
+func NewDurationHistory(path string) *DurationHistory {
+	history := &DurationHistory{
+		path:    path,
+		entries: make(map[string]durationEntry),
+		types:   make(map[TaskType]time.Duration),
+	}
+
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			// A corrupt history is not fatal, it only makes the schedule less informed
+			if err := json.Unmarshal(data, &history.entries); err != nil {
+				history.entries = make(map[string]durationEntry)
+			}
+		}
+	}
+
+	// Average the durations per task type as the estimate for new tasks
+	totals := make(map[TaskType]int64)
+	counts := make(map[TaskType]int64)
+	for _, entry := range history.entries {
+		totals[entry.TaskType] += entry.DurationMs
+		counts[entry.TaskType]++
+	}
+	for taskType, total := range totals {
+		history.types[taskType] = time.Duration(total/counts[taskType]) * time.Millisecond
+	}
+
+	return history
+}
+
+// Estimate returns the expected duration of a task: its last recorded duration,
+// or the average duration of its task type if it has never been executed
+func (h *DurationHistory) Estimate(task Task) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if entry, exists := h.entries[durationKey(task)]; exists {
+		return time.Duration(entry.DurationMs) * time.Millisecond
+	}
+	if duration, exists := h.types[task.TaskType()]; exists {
+		return duration
+	}
+	return defaultTaskDuration
+}
+
+// Record stores the duration of an execution of a task
+func (h *DurationHistory) Record(task Task, duration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries[durationKey(task)] = durationEntry{
+		TaskType:   task.TaskType(),
+		DurationMs: duration.Milliseconds(),
+	}
+	h.dirty = true
+}
+
+// Save writes the history to disk if it has changed since it was loaded
+func (h *DurationHistory) Save() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.path == "" || !h.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(h.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode duration history: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create duration history directory: %w", err)
+	}
+
+	// Write to a temporary file first so a concurrent reader never sees a partial history
+	tempFile, err := os.CreateTemp(filepath.Dir(h.path), ".durations-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary duration history: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write duration history: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write duration history: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), h.path); err != nil {
+		return fmt.Errorf("failed to replace duration history: %w", err)
+	}
+
+	h.dirty = false
+	return nil
+}
+
+// durationKey identifies a task across runs
+func durationKey(task Task) string {
+	return task.Directory() + ":" + task.DisplayName()
+}
+
+// criticalPathPriorities returns for each task the estimated duration of the
+// longest chain of work from the start of the task to the end of the build,
+// following the tasks that depend on it. Scheduling the tasks with the longest
+// remaining path first keeps workers busy until the end of the build.
+func criticalPathPriorities(graph *Graph, orderedTasks []Task, history *DurationHistory) map[string]time.Duration {
+	priorities := make(map[string]time.Duration, len(orderedTasks))
+	for i := len(orderedTasks) - 1; i >= 0; i-- {
+		task := orderedTasks[i]
+
+		var longestDependent time.Duration
+		for _, dependent := range graph.Dependents(task.ID()) {
+			if priorities[dependent.ID()] > longestDependent {
+				longestDependent = priorities[dependent.ID()]
+			}
+		}
+		priorities[task.ID()] = history.Estimate(task) + longestDependent
+	}
+	return priorities
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDurationHistory_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "durations.json")
+	compile := NewMockTask("compile", "kotlin-compile", "/test/a", "hash1", nil)
+	otherCompile := NewMockTask("other", "kotlin-compile", "/test/b", "hash2", nil)
+	
+	history := NewDurationHistory(path)
+	if history.Estimate(compile) != defaultTaskDuration {
+		t.Errorf("Expected default estimate for an unknown task, got %v", history.Estimate(compile))
+	}
+	
+	history.Record(compile, 4*time.Second)
+	if err := history.Save(); err != nil {
+		t.Fatalf("Failed to save history: %v", err)
+	}
+	
+	// A task whose ID changed is still recognized by its directory and name
+	reloaded := NewDurationHistory(path)
+	changed := NewMockTask("compile-changed", "kotlin-compile", "/test/a", "hash3", nil)
+	if reloaded.Estimate(changed) != 4*time.Second {
+		t.Errorf("Expected recorded duration of 4s, got %v", reloaded.Estimate(changed))
+	}
+	
+	// A new task of a known type is estimated by the type average
+	if reloaded.Estimate(otherCompile) != 4*time.Second {
+		t.Errorf("Expected type average of 4s, got %v", reloaded.Estimate(otherCompile))
+	}
+}
+
+func TestRunner_CriticalPathScheduling(t *testing.T) {
+	var mu sync.Mutex
+	var started []string
+	record := func(task *MockTask) *MockTask {
+		task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+			mu.Lock()
+			started = append(started, task.id)
+			mu.Unlock()
+			return TaskResult{}
+		}
+		return task
+	}
+	
+	// Short independent tasks are discovered before a long chain
+	graph := NewGraph()
+	for _, id := range []string{"short1", "short2", "short3"} {
+		graph.AddTask(record(NewMockTask(id, id, "/test", id, nil)))
+	}
+	long1 := record(NewMockTask("long1", "long1", "/test", "long1", nil))
+	long2 := record(NewMockTask("long2", "long2", "/test", "long2", []Task{long1}))
+	long3 := record(NewMockTask("long3", "long3", "/test", "long3", []Task{long2}))
+	graph.AddTask(long1)
+	graph.AddTask(long2)
+	graph.AddTask(long3)
+	
+	history := NewDurationHistory("")
+	for _, task := range graph.GetTasks() {
+		history.Record(task, time.Second)
+	}
+	history.Record(long1, 10*time.Second)
+	
+	runner := NewRunner(t.TempDir())
+	runner.SetDurationHistory(history)
+	if _, err := runner.ExecuteWithProgressParallel(context.Background(), graph, nil, 2); err != nil {
+		t.Fatalf("Failed to execute tasks: %v", err)
+	}
+	
+	// The head of the long chain is handed to a worker before any short task
+	if started[0] != "long1" && started[1] != "long1" {
+		t.Errorf("Expected long1 to start first, got %v", started)
+	}
+}
+
+func TestCriticalPathPriorities(t *testing.T) {
+	graph := NewGraph()
+	a := NewMockTask("A", "task-a", "/test", "hashA", nil)
+	b := NewMockTask("B", "task-b", "/test", "hashB", []Task{a})
+	c := NewMockTask("C", "task-c", "/test", "hashC", []Task{a})
+	d := NewMockTask("D", "task-d", "/test", "hashD", []Task{b})
+	for _, task := range []Task{a, b, c, d} {
+		graph.AddTask(task)
+	}
+	
+	history := NewDurationHistory("")
+	history.Record(a, 1*time.Second)
+	history.Record(b, 2*time.Second)
+	history.Record(c, 5*time.Second)
+	history.Record(d, 4*time.Second)
+	
+	ordered, err := graph.TopologicalSort()
+	if err != nil {
+		t.Fatalf("Failed to sort tasks: %v", err)
+	}
+	priorities := criticalPathPriorities(graph, ordered, history)
+	
+	expected := map[string]time.Duration{"A": 7 * time.Second, "B": 6 * time.Second, "C": 5 * time.Second, "D": 4 * time.Second}
+	for id, priority := range expected {
+		if priorities[id] != priority {
+			t.Errorf("Expected priority %v for %s, got %v", priority, id, priorities[id])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened to a task
+type EventType string
+
+const (
+	// EventTaskQueued is emitted when all dependencies of a task are done and it is waiting for a worker
+	EventTaskQueued EventType = "task-queued"
+	// EventTaskStarted is emitted when a worker picks up a task
+	EventTaskStarted EventType = "task-started"
+	// EventTaskCacheHit is emitted when a task's result was restored from the local or remote cache
+	EventTaskCacheHit EventType = "task-cache-hit"
+	// EventTaskSucceeded is emitted when a task was executed successfully
+	EventTaskSucceeded EventType = "task-succeeded"
+	// EventTaskFailed is emitted when a task was executed and failed
+	EventTaskFailed EventType = "task-failed"
+	// EventTaskRetrying is emitted when an execution of a task failed and the task is executed again
+	EventTaskRetrying EventType = "task-retrying"
+	// EventTaskSkipped is emitted when a task is not run because a dependency failed
+	EventTaskSkipped EventType = "task-skipped"
+)
+
+// Event describes a change in the state of a task during execution
+type Event struct {
+	Type        EventType `json:"type"`
+	Time        time.Time `json:"time"`
+	TaskID      string    `json:"taskId"`
+	TaskName    string    `json:"taskName"`
+	DisplayName string    `json:"displayName"`
+	TaskType    TaskType  `json:"taskType"`
+	Directory   string    `json:"directory"`
+	TaskHash    string    `json:"taskHash,omitempty"`
+	DurationMs  int64     `json:"durationMs,omitempty"`
+	OutputSize  int64     `json:"outputSize,omitempty"`
+	Remote      bool      `json:"remote,omitempty"`
+	Attempt     int       `json:"attempt,omitempty"`
+	Flaky       bool      `json:"flaky,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// EventHandler receives execution events. Handlers are never called concurrently.
+type EventHandler func(event Event)
+
+// newTaskEvent creates an event of the given type for a task
+func newTaskEvent(eventType EventType, task Task) Event {
+	return Event{
+		Type:        eventType,
+		Time:        time.Now(),
+		TaskID:      task.ID(),
+		TaskName:    task.Name(),
+		DisplayName: task.DisplayName(),
+		TaskType:    task.TaskType(),
+		Directory:   task.Directory(),
+	}
+}
+
+// newResultEvent creates the event that reports how a task finished
+func newResultEvent(result ExecutionResult) Event {
+	var event Event
+	switch {
+	case result.Skipped:
+		event = newTaskEvent(EventTaskSkipped, result.Task)
+	case result.Result.Error != nil:
+		event = newTaskEvent(EventTaskFailed, result.Task)
+		event.Error = result.Result.Error.Error()
+	case result.CacheHit:
+		event = newTaskEvent(EventTaskCacheHit, result.Task)
+		event.Remote = result.RemoteHit
+	default:
+		event = newTaskEvent(EventTaskSucceeded, result.Task)
+	}
+
+	event.TaskHash = result.TaskHash
+	event.Flaky = result.Flaky
+	if result.Attempts > 1 {
+		event.Attempt = result.Attempts
+	}
+	event.DurationMs = result.Duration.Milliseconds()
+	if event.Type == EventTaskCacheHit || event.Type == EventTaskSucceeded {
+		event.OutputSize = outputSize(result)
+	}
+	return event
+}
+
+// outputSize returns the total size of the files a task produced
+func outputSize(result ExecutionResult) int64 {
+	var size int64
+	for _, file := range result.Result.Files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(result.OutputDir, file)
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// EventWriter writes events as JSON lines, one event per line
+type EventWriter struct {
+	encoder *json.Encoder
+	err     error
+	mu      sync.Mutex
+}
+
+// NewEventWriter creates an event writer for the given writer
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// Handle writes a single event. It can be passed to Runner.AddEventHandler.
+func (e *EventWriter) Handle(event Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.err != nil {
+		return
+	}
+	e.err = e.encoder.Encode(event)
+}
+
+// Err returns the first error encountered while writing events
+func (e *EventWriter) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// decodeEvents parses JSON-lines output and groups the event types by task ID
+func decodeEvents(t *testing.T, data []byte) (map[string][]EventType, map[string]Event) {
+	types := make(map[string][]EventType)
+	last := make(map[string]Event)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Failed to decode event %q: %v", scanner.Text(), err)
+		}
+		if event.Time.IsZero() {
+			t.Errorf("Event %s for %s has no timestamp", event.Type, event.TaskID)
+		}
+		types[event.TaskID] = append(types[event.TaskID], event.Type)
+		last[event.TaskID] = event
+	}
+	return types, last
+}
+
+func assertEventTypes(t *testing.T, types map[string][]EventType, taskID string, expected ...EventType) {
+	t.Helper()
+	if fmt.Sprint(types[taskID]) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v for task %s, got %v", expected, taskID, types[taskID])
+	}
+}
+
+func TestRunner_EventStream(t *testing.T) {
+	ctx := context.Background()
+	resultDir := t.TempDir()
+
+	depTask := NewMockTask("dep", "dep-task", "/test", "hashEvDep", nil)
+	mainTask := NewMockTask("main", "main-task", "/test", "hashEvMain", []Task{depTask})
+	graph := NewGraph()
+	graph.AddTask(depTask)
+	graph.AddTask(mainTask)
+
+	// First run executes both tasks
+	var buffer bytes.Buffer
+	runner := NewRunner(resultDir)
+	writer := NewEventWriter(&buffer)
+	runner.AddEventHandler(writer.Handle)
+	if _, err := runner.ExecuteWithProgressParallel(ctx, graph, nil, 2); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if writer.Err() != nil {
+		t.Fatalf("Failed to write events: %v", writer.Err())
+	}
+
+	types, last := decodeEvents(t, buffer.Bytes())
+	assertEventTypes(t, types, "dep", EventTaskQueued, EventTaskStarted, EventTaskSucceeded)
+	assertEventTypes(t, types, "main", EventTaskQueued, EventTaskStarted, EventTaskSucceeded)
+	if last["main"].OutputSize == 0 {
+		t.Error("Expected the succeeded event to report the output size")
+	}
+	if last["main"].TaskHash == "" || last["main"].DisplayName != "main-task" {
+		t.Errorf("Expected task details in event, got %+v", last["main"])
+	}
+
+	// Second run is served from the cache
+	buffer.Reset()
+	runner = NewRunner(resultDir)
+	runner.AddEventHandler(NewEventWriter(&buffer).Handle)
+	if _, err := runner.Execute(ctx, graph); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	types, _ = decodeEvents(t, buffer.Bytes())
+	assertEventTypes(t, types, "main", EventTaskQueued, EventTaskStarted, EventTaskCacheHit)
+}
+
+func TestRunner_EventStreamFailure(t *testing.T) {
+	var buffer bytes.Buffer
+	runner := NewRunner(t.TempDir())
+	runner.SetKeepGoing(true)
+	runner.AddEventHandler(NewEventWriter(&buffer).Handle)
+
+	if _, err := runner.Execute(context.Background(), newKeepGoingGraph()); err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+
+	types, last := decodeEvents(t, buffer.Bytes())
+	assertEventTypes(t, types, "failing", EventTaskQueued, EventTaskStarted, EventTaskFailed)
+	assertEventTypes(t, types, "dependent", EventTaskSkipped)
+	assertEventTypes(t, types, "independent", EventTaskQueued, EventTaskStarted, EventTaskSucceeded)
+	if last["failing"].Error != "compilation error" {
+		t.Errorf("Expected the failed event to carry the error text, got %q", last["failing"].Error)
+	}
+}
@@ -2,49 +2,64 @@ package graph
 
 import (
 	"fmt"
+	"strings"
 )
 
-// Graph represents a directed acyclic graph of tasks
+// Graph represents a directed acyclic graph of tasks. Tasks are indexed by ID
+// and both edge directions are precomputed, so lookups and traversals do not
+// scan the whole graph.
 type Graph struct {
-	tasks []Task
-	edges map[string][]string // task ID -> list of dependency task IDs
+	tasks      []Task              // tasks in insertion order
+	index      map[string]Task     // task ID -> task
+	edges      map[string][]string // task ID -> list of dependency task IDs
+	dependents map[string][]string // task ID -> list of dependent task IDs
 }
 
 // NewGraph creates a new empty graph
 func NewGraph() *Graph {
 	return &Graph{
-		tasks: make([]Task, 0),
-		edges: make(map[string][]string),
+		tasks:      make([]Task, 0),
+		index:      make(map[string]Task),
+		edges:      make(map[string][]string),
+		dependents: make(map[string][]string),
 	}
 }
 
 // AddTask adds a task to the graph
 func (g *Graph) AddTask(task Task) error {
 	// Check if task already exists
-	for _, existing := range g.tasks {
-		if existing.ID() == task.ID() {
-			return fmt.Errorf("task with ID %s already exists", task.ID())
-		}
+	if _, exists := g.index[task.ID()]; exists {
+		return fmt.Errorf("task with ID %s already exists", task.ID())
 	}
 	
 	g.tasks = append(g.tasks, task)
+	g.index[task.ID()] = task
+	g.addEdges(task)
 	
-	// Add edges for dependencies
-	var depIDs []string
-	for _, dep := range task.Dependencies() {
+	return nil
+}
+
+// addEdges records the dependencies of a task in both edge directions,
+// ignoring a dependency that is listed more than once
+func (g *Graph) addEdges(task Task) {
+	deps := task.Dependencies()
+	depIDs := make([]string, 0, len(deps))
+	seen := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		if seen[dep.ID()] {
+			continue
+		}
+		seen[dep.ID()] = true
 		depIDs = append(depIDs, dep.ID())
+		g.dependents[dep.ID()] = append(g.dependents[dep.ID()], task.ID())
 	}
 	g.edges[task.ID()] = depIDs
-	
-	return nil
 }
 
 // GetTask returns a task by its ID
 func (g *Graph) GetTask(id string) (Task, error) {
-	for _, task := range g.tasks {
-		if task.ID() == id {
-			return task, nil
-		}
+	if task, exists := g.index[id]; exists {
+		return task, nil
 	}
 	return nil, fmt.Errorf("task with ID %s not found", id)
 }
@@ -54,56 +69,198 @@ func (g *Graph) GetTasks() []Task {
 	return g.tasks
 }
 
-// TopologicalSort returns tasks in topological order (dependencies first)
+// Len returns the number of tasks in the graph
+func (g *Graph) Len() int {
+	return len(g.tasks)
+}
+
+// RefreshEdges recomputes the edges of the graph from the current dependencies
+// of its tasks. It must be called after dependencies are added to tasks that
+// are already in the graph.
+func (g *Graph) RefreshEdges() {
+	g.edges = make(map[string][]string, len(g.tasks))
+	g.dependents = make(map[string][]string, len(g.tasks))
+	for _, task := range g.tasks {
+		g.addEdges(task)
+	}
+}
+
+// Edges returns the IDs of the direct dependencies of the given task
+func (g *Graph) Edges(id string) []string {
+	return g.edges[id]
+}
+
+// FindCycles returns the dependency cycles in the graph. Each cycle lists its
+// tasks in dependency order, starting from the task encountered first.
+func (g *Graph) FindCycles() [][]Task {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	
+	state := make(map[string]int)
+	
+	var cycles [][]Task
+	var stack []string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = inProgress
+		stack = append(stack, id)
+		
+		for _, depID := range g.edges[id] {
+			if _, exists := g.index[depID]; !exists {
+				continue
+			}
+			switch state[depID] {
+			case unvisited:
+				visit(depID)
+			case inProgress:
+				// Back edge: the stack from depID onwards forms a cycle
+				var cycle []Task
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == depID {
+						for _, cycleID := range stack[i:] {
+							cycle = append(cycle, g.index[cycleID])
+						}
+						break
+					}
+				}
+				cycles = append(cycles, cycle)
+			}
+		}
+		
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+	
+	for _, task := range g.tasks {
+		if state[task.ID()] == unvisited {
+			visit(task.ID())
+		}
+	}
+	
+	return cycles
+}
+
+// CycleError reports dependency cycles in a graph
+type CycleError struct {
+	Cycles [][]Task
+}
+
+// Error returns every cycle as a chain of tasks in which each task depends on the next
+func (e *CycleError) Error() string {
+	var b strings.Builder
+	if len(e.Cycles) == 1 {
+		b.WriteString("dependency cycle detected:")
+	} else {
+		fmt.Fprintf(&b, "%d dependency cycles detected:", len(e.Cycles))
+	}
+	
+	for i, cycle := range e.Cycles {
+		indent := "  "
+		if len(e.Cycles) > 1 {
+			fmt.Fprintf(&b, "\n  cycle %d:", i+1)
+			indent = "    "
+		}
+		for j, task := range cycle {
+			if j == 0 {
+				fmt.Fprintf(&b, "\n%s%s (%s)", indent, task.DisplayName(), task.Directory())
+			} else {
+				fmt.Fprintf(&b, "\n%s-> %s (%s)", indent, task.DisplayName(), task.Directory())
+			}
+		}
+		// Close the cycle with the task it started from
+		fmt.Fprintf(&b, "\n%s-> %s (%s)", indent, cycle[0].DisplayName(), cycle[0].Directory())
+	}
+	return b.String()
+}
+
+// CheckCycles returns a *CycleError describing every dependency cycle in the graph, or nil
+func (g *Graph) CheckCycles() error {
+	if cycles := g.FindCycles(); len(cycles) > 0 {
+		return &CycleError{Cycles: cycles}
+	}
+	return nil
+}
+
+// Dependents returns the tasks in the graph that directly depend on the given task
+func (g *Graph) Dependents(id string) []Task {
+	var dependents []Task
+	for _, dependentID := range g.dependents[id] {
+		dependents = append(dependents, g.index[dependentID])
+	}
+	return dependents
+}
+
+// TransitiveDependents returns the given tasks together with every task that
+// transitively depends on them, in the order the tasks were added to the graph
+func (g *Graph) TransitiveDependents(ids []string) []Task {
+	reached := make(map[string]bool)
+	queue := append([]string(nil), ids...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if reached[current] {
+			continue
+		}
+		reached[current] = true
+		queue = append(queue, g.dependents[current]...)
+	}
+	
+	var result []Task
+	for _, task := range g.tasks {
+		if reached[task.ID()] {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+// TopologicalSort returns tasks in topological order (dependencies first).
+// Tasks that become ready at the same time keep their insertion order.
 func (g *Graph) TopologicalSort() ([]Task, error) {
 	// Kahn's algorithm for topological sorting
-	inDegree := make(map[string]int)
+	inDegree := make(map[string]int, len(g.tasks))
 	
 	// Initialize in-degree count (number of dependencies for each task)
+	var queue []string
 	for _, task := range g.tasks {
 		inDegree[task.ID()] = len(g.edges[task.ID()])
-	}
-	
-	// Find tasks with no dependencies (in-degree = 0)
-	var queue []string
-	for taskID, degree := range inDegree {
-		if degree == 0 {
-			queue = append(queue, taskID)
+		
+		// Start with the tasks that have no dependencies
+		if inDegree[task.ID()] == 0 {
+			queue = append(queue, task.ID())
 		}
 	}
 	
-	var result []Task
+	result := make([]Task, 0, len(g.tasks))
 	
 	for len(queue) > 0 {
 		// Pop from queue
 		current := queue[0]
 		queue = queue[1:]
-		
-		// Add to result
-		task, err := g.GetTask(current)
-		if err != nil {
-			return nil, err
-		}
-		result = append(result, task)
+		result = append(result, g.index[current])
 		
 		// For each task that depends on the current task, reduce its in-degree
-		for _, otherTask := range g.tasks {
-			otherTaskDeps := g.edges[otherTask.ID()]
-			for _, dep := range otherTaskDeps {
-				if dep == current {
-					inDegree[otherTask.ID()]--
-					if inDegree[otherTask.ID()] == 0 {
-						queue = append(queue, otherTask.ID())
-					}
-				}
+		for _, dependentID := range g.dependents[current] {
+			if _, exists := g.index[dependentID]; !exists {
+				continue
+			}
+			inDegree[dependentID]--
+			if inDegree[dependentID] == 0 {
+				queue = append(queue, dependentID)
 			}
 		}
 	}
 	
 	// Check for cycles
 	if len(result) != len(g.tasks) {
-		return nil, fmt.Errorf("cycle detected in task graph")
+		if err := g.CheckCycles(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("task graph contains dependencies that are not part of the graph")
 	}
 	
 	return result, nil
-}
\ No newline at end of file
+}
@@ -2,9 +2,12 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -76,6 +79,10 @@ func (m *MockTask) Execute(ctx context.Context, workDir string, dependencyInputs
 	return TaskResult{Files: []string{fmt.Sprintf("%s.txt", m.id)}}
 }
 
+func (m *MockTask) DisplayName() string {
+	return m.name
+}
+
 func TestGraph_AddTask(t *testing.T) {
 	graph := NewGraph()
 	task := NewMockTask("task1", "mock-task", "/test", "hash1", nil)
@@ -129,8 +136,8 @@ func TestGraph_CycleDetection(t *testing.T) {
 	graph := NewGraph()
 	
 	// Create a cycle: A -> B -> A
-	taskA := NewMockTask("A", "mock-task", "/test", "hashA", nil)
-	taskB := NewMockTask("B", "mock-task", "/test", "hashB", []Task{taskA})
+	taskA := NewMockTask("A", "task-a", "/test/a", "hashA", nil)
+	taskB := NewMockTask("B", "task-b", "/test/b", "hashB", []Task{taskA})
 	taskA.dependencies = []Task{taskB} // Create the cycle
 	
 	graph.AddTask(taskA)
@@ -140,6 +147,94 @@ func TestGraph_CycleDetection(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for cyclic graph")
 	}
+	
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("Expected a CycleError, got %v", err)
+	}
+	expected := "dependency cycle detected:\n  task-a (/test/a)\n  -> task-b (/test/b)\n  -> task-a (/test/a)"
+	if err.Error() != expected {
+		t.Errorf("Expected error:\n%s\ngot:\n%s", expected, err.Error())
+	}
+}
+
+func TestRunner_ParallelRejectsCycles(t *testing.T) {
+	graph := NewGraph()
+	taskA := NewMockTask("A", "task-a", "/test", "hashA", nil)
+	taskB := NewMockTask("B", "task-b", "/test", "hashB", []Task{taskA})
+	taskA.dependencies = []Task{taskB}
+	graph.AddTask(taskA)
+	graph.AddTask(taskB)
+	
+	// Without validation the parallel scheduler would wait for these tasks forever
+	runner := NewRunner(t.TempDir())
+	_, err := runner.ExecuteWithProgressParallel(context.Background(), graph, nil, 4)
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("Expected a CycleError, got %v", err)
+	}
+}
+
+func TestGraph_DuplicateDependencies(t *testing.T) {
+	graph := NewGraph()
+	taskA := NewMockTask("A", "mock-task", "/test", "hashA", nil)
+	taskB := NewMockTask("B", "mock-task", "/test", "hashB", []Task{taskA, taskA})
+	graph.AddTask(taskA)
+	graph.AddTask(taskB)
+	
+	if edges := graph.Edges("B"); len(edges) != 1 {
+		t.Errorf("Expected a single edge from B, got %v", edges)
+	}
+	if dependents := graph.Dependents("A"); len(dependents) != 1 {
+		t.Errorf("Expected a single dependent of A, got %d", len(dependents))
+	}
+	
+	sorted, err := graph.TopologicalSort()
+	if err != nil || len(sorted) != 2 {
+		t.Fatalf("Expected both tasks to be sorted, got %v (%v)", sorted, err)
+	}
+	
+	runner := NewRunner(t.TempDir())
+	results, err := runner.ExecuteWithProgressParallel(context.Background(), graph, nil, 2)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Expected both tasks to run, got %d results (%v)", len(results), err)
+	}
+}
+
+func TestGraph_CheckCycles(t *testing.T) {
+	graph := NewGraph()
+	
+	// Two independent cycles next to an acyclic chain
+	taskA := NewMockTask("A", "task-a", "/test", "hashA", nil)
+	taskB := NewMockTask("B", "task-b", "/test", "hashB", []Task{taskA})
+	taskA.dependencies = []Task{taskB}
+	taskC := NewMockTask("C", "task-c", "/test", "hashC", nil)
+	taskC.dependencies = []Task{taskC}
+	taskD := NewMockTask("D", "task-d", "/test", "hashD", []Task{taskA})
+	
+	for _, task := range []Task{taskA, taskB, taskC, taskD} {
+		graph.AddTask(task)
+	}
+	
+	err := graph.CheckCycles()
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("Expected a CycleError, got %v", err)
+	}
+	if len(cycleErr.Cycles) != 2 {
+		t.Fatalf("Expected 2 cycles, got %d", len(cycleErr.Cycles))
+	}
+	if !strings.HasPrefix(err.Error(), "2 dependency cycles detected:") || !strings.Contains(err.Error(), "task-c (/test)\n    -> task-c (/test)") {
+		t.Errorf("Unexpected error message:\n%s", err.Error())
+	}
+	
+	acyclic := NewGraph()
+	taskE := NewMockTask("E", "task-e", "/test", "hashE", nil)
+	acyclic.AddTask(taskE)
+	acyclic.AddTask(NewMockTask("F", "task-f", "/test", "hashF", []Task{taskE}))
+	if err := acyclic.CheckCycles(); err != nil {
+		t.Errorf("Expected no cycles, got %v", err)
+	}
 }
 
 func TestComputeTaskHash(t *testing.T) {
@@ -247,6 +342,75 @@ func TestRunner_ExecuteWithFailure(t *testing.T) {
 	}
 }
 
+// newKeepGoingGraph creates a graph where "failing" fails, "dependent" depends on it,
+// "transitive" depends on "dependent", and "independent" has no dependencies
+func newKeepGoingGraph() *Graph {
+	failingTask := NewMockTask("failing", "failing-task", "/test/a", "hashKGFail", nil)
+	failingTask.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+		return TaskResult{Error: fmt.Errorf("compilation error")}
+	}
+	dependentTask := NewMockTask("dependent", "dependent-task", "/test/a", "hashKGDep", []Task{failingTask})
+	transitiveTask := NewMockTask("transitive", "transitive-task", "/test/a", "hashKGTrans", []Task{dependentTask})
+	independentTask := NewMockTask("independent", "independent-task", "/test/b", "hashKGInd", nil)
+
+	graph := NewGraph()
+	graph.AddTask(failingTask)
+	graph.AddTask(dependentTask)
+	graph.AddTask(transitiveTask)
+	graph.AddTask(independentTask)
+	return graph
+}
+
+func TestRunner_KeepGoing(t *testing.T) {
+	for _, parallelism := range []int{1, 4} {
+		t.Run(fmt.Sprintf("parallelism-%d", parallelism), func(t *testing.T) {
+			runner := NewRunner(t.TempDir())
+			runner.SetKeepGoing(true)
+
+			statuses := make(map[string]string)
+			var mu sync.Mutex
+			progressCallback := func(task Task, status string, finished bool, cached bool) {
+				if finished {
+					mu.Lock()
+					statuses[task.ID()] = status
+					mu.Unlock()
+				}
+			}
+
+			results, err := runner.ExecuteWithProgressParallel(context.Background(), newKeepGoingGraph(), progressCallback, parallelism)
+
+			var buildErr *BuildError
+			if !errors.As(err, &buildErr) {
+				t.Fatalf("Expected a BuildError, got %v", err)
+			}
+			if len(buildErr.Failures) != 1 || buildErr.Failures[0].Task.ID() != "failing" {
+				t.Errorf("Expected only the failing task to be reported, got %v", buildErr.Failures)
+			}
+			if buildErr.Skipped != 2 {
+				t.Errorf("Expected 2 skipped tasks, got %d", buildErr.Skipped)
+			}
+			if !strings.Contains(err.Error(), "failing-task (/test/a): compilation error") {
+				t.Errorf("Expected error to list the failed task, got %q", err.Error())
+			}
+			if len(results) != 4 {
+				t.Fatalf("Expected a result for every task, got %d", len(results))
+			}
+
+			expected := map[string]string{
+				"failing":     "failed",
+				"dependent":   "skipped",
+				"transitive":  "skipped",
+				"independent": "completed",
+			}
+			for id, status := range expected {
+				if statuses[id] != status {
+					t.Errorf("Expected task %s to be %s, got %s", id, status, statuses[id])
+				}
+			}
+		})
+	}
+}
+
 func TestRunner_ExecuteWithCancellation(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "graph_test")
 	if err != nil {
@@ -414,4 +578,325 @@ func TestRunner_DependencyInputs(t *testing.T) {
 	if depInput.OutputDir == "" {
 		t.Error("Dependency output directory should not be empty")
 	}
-}
\ No newline at end of file
+}
+
+func TestDigestIndex_FileDigest(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "graph_digest_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	
+	filePath := filepath.Join(tempDir, "input.txt")
+	if err := os.WriteFile(filePath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	
+	// Backdate the file so its digest is remembered by the index
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filePath, past, past); err != nil {
+		t.Fatalf("Failed to set file times: %v", err)
+	}
+	
+	indexPath := filepath.Join(tempDir, "digests.json")
+	index := NewDigestIndex(indexPath)
+	
+	digest1, err := index.FileDigest(filePath)
+	if err != nil {
+		t.Fatalf("Failed to compute digest: %v", err)
+	}
+	
+	// The same contents with a different modification time should give the same digest
+	otherPath := filepath.Join(tempDir, "other.txt")
+	if err := os.WriteFile(otherPath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	digest2, err := index.FileDigest(otherPath)
+	if err != nil {
+		t.Fatalf("Failed to compute digest: %v", err)
+	}
+	if digest1 != digest2 {
+		t.Error("Expected identical contents to produce identical digests")
+	}
+	
+	if err := index.Save(); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	
+	// A reloaded index should serve the recorded digest without re-reading the file
+	reloaded := NewDigestIndex(indexPath)
+	entry, exists := reloaded.entries[filePath]
+	if !exists {
+		t.Fatal("Expected backdated file to be recorded in the persisted index")
+	}
+	if entry.Digest != digest1 {
+		t.Errorf("Expected persisted digest %s, got %s", digest1, entry.Digest)
+	}
+	
+	// Changing the contents must produce a new digest
+	if err := os.WriteFile(filePath, []byte("changed"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	digest3, err := reloaded.FileDigest(filePath)
+	if err != nil {
+		t.Fatalf("Failed to compute digest: %v", err)
+	}
+	if digest3 == digest1 {
+		t.Error("Expected changed contents to produce a different digest")
+	}
+}
+
+func TestRunner_IncompleteEntryIsCacheMiss(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "graph_manifest_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	
+	graph := NewGraph()
+	runner := NewRunner(tempDir)
+	task := NewMockTask("partial", "mock-task", "/test", "hashPartial", nil)
+	graph.AddTask(task)
+	
+	// Simulate a run that was interrupted while populating the entry
+	entryDir := filepath.Join(tempDir, ComputeTaskHash(task))
+	if err := os.MkdirAll(entryDir, 0755); err != nil {
+		t.Fatalf("Failed to create entry dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(entryDir, "stale.txt"), []byte("stale"), 0644); err != nil {
+		t.Fatalf("Failed to write stale file: %v", err)
+	}
+	
+	ctx := context.Background()
+	results, err := runner.Execute(ctx, graph)
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if results[0].CacheHit {
+		t.Fatal("An entry without a manifest should not be a cache hit")
+	}
+	
+	// The incomplete entry is replaced by the committed one
+	if _, err := os.Stat(filepath.Join(entryDir, "stale.txt")); !os.IsNotExist(err) {
+		t.Error("Expected the incomplete entry to be replaced")
+	}
+	
+	manifest, err := ReadManifest(entryDir)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if manifest.TaskName != "mock-task" || manifest.TaskHash != results[0].TaskHash {
+		t.Errorf("Unexpected manifest metadata: %+v", manifest)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0].Path != "partial.txt" || manifest.Files[0].Digest == "" {
+		t.Errorf("Unexpected manifest files: %+v", manifest.Files)
+	}
+	
+	// The next run is served from the manifest
+	results, err = runner.Execute(ctx, graph)
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if !results[0].CacheHit {
+		t.Error("Expected a cache hit once the entry has a manifest")
+	}
+	if len(results[0].Result.Files) != 1 || results[0].Result.Files[0] != "partial.txt" {
+		t.Errorf("Expected cached files from the manifest, got %v", results[0].Result.Files)
+	}
+	
+	// No staging directories are left behind
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read result dir: %v", err)
+	}
+	for _, entry := range entries {
+		if isStagingDir(entry.Name()) {
+			t.Errorf("Unexpected staging directory %s", entry.Name())
+		}
+	}
+}
+
+func TestRunner_TaskLogs(t *testing.T) {
+	store := NewLogStore(t.TempDir())
+	runner := NewRunner(t.TempDir())
+	runner.SetKeepGoing(true)
+	runner.SetLogStore(store)
+
+	// Both tasks write to their log, only the failing one keeps it
+	okTask := NewMockTask("ok", "ok-task", "/test", "hashLogOK", nil)
+	okTask.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+		fmt.Fprintln(TaskLog(ctx), "all good")
+		os.WriteFile(filepath.Join(workDir, "ok.txt"), []byte("ok"), 0644)
+		return TaskResult{Files: []string{"ok.txt"}}
+	}
+	failingTask := NewMockTask("failing", "failing-task", "/test", "hashLogFail", nil)
+	failingTask.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+		fmt.Fprintln(TaskLog(ctx), "error: unresolved reference")
+		return TaskResult{Error: fmt.Errorf("compilation failed")}
+	}
+
+	graph := NewGraph()
+	graph.AddTask(okTask)
+	graph.AddTask(failingTask)
+
+	results, err := runner.Execute(context.Background(), graph)
+	if err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+
+	for _, result := range results {
+		switch result.Task.ID() {
+		case "ok":
+			if result.LogPath != "" {
+				t.Errorf("Expected no log for a successful task, got %s", result.LogPath)
+			}
+			if _, err := os.Stat(store.LogPath(result.TaskHash)); !os.IsNotExist(err) {
+				t.Error("Expected the log of a successful task to be deleted")
+			}
+		case "failing":
+			content, err := os.ReadFile(result.LogPath)
+			if err != nil {
+				t.Fatalf("Failed to read kept log: %v", err)
+			}
+			if string(content) != "error: unresolved reference\n" {
+				t.Errorf("Unexpected log content %q", string(content))
+			}
+		}
+	}
+
+	infos, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(infos) != 1 || infos[0].DisplayName != "failing-task" || infos[0].Error != "compilation failed" {
+		t.Errorf("Expected one kept log for the failing task, got %+v", infos)
+	}
+}
+
+func TestRunner_TaskLogs_KeepsLastRun(t *testing.T) {
+	store := NewLogStore(t.TempDir())
+	runner := NewRunner(t.TempDir())
+	runner.SetLogStore(store)
+
+	// The same task fails under two different input hashes
+	for _, hash := range []string{"hashLogRun1", "hashLogRun2"} {
+		task := NewMockTask("failing", "failing-task", "/test", hash, nil)
+		task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+			fmt.Fprintln(TaskLog(ctx), "run with "+hash)
+			return TaskResult{Error: fmt.Errorf("compilation failed")}
+		}
+		graph := NewGraph()
+		graph.AddTask(task)
+		if _, err := runner.Execute(context.Background(), graph); err == nil {
+			t.Fatal("Expected execution to fail")
+		}
+	}
+
+	infos, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("Expected only the log of the last run, got %+v", infos)
+	}
+	content, err := os.ReadFile(store.LogPath(infos[0].TaskHash))
+	if err != nil || string(content) != "run with hashLogRun2\n" {
+		t.Errorf("Expected the log of the last run, got %q: %v", content, err)
+	}
+
+	// A fixed task succeeding under a new hash drops the stale failure log
+	graph := NewGraph()
+	graph.AddTask(NewMockTask("failing", "failing-task", "/test", "hashLogRun3", nil))
+	if _, err := runner.Execute(context.Background(), graph); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	infos, err = store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(infos) != 0 {
+		t.Errorf("Expected no logs after a successful run, got %+v", infos)
+	}
+	if _, err := os.Stat(store.LogPath("hashLogRun2")); !os.IsNotExist(err) {
+		t.Errorf("Expected the failure log to be removed, got %v", err)
+	}
+}
+
+// MockSourceTask is a mock task that declares its input files
+type MockSourceTask struct {
+	*MockTask
+	inputs []string
+}
+
+func (m *MockSourceTask) Inputs() []string {
+	return m.inputs
+}
+
+func TestGraph_TransitiveDependents(t *testing.T) {
+	taskA := NewMockTask("A", "task-a", "/test", "hashA", nil)
+	taskB := NewMockTask("B", "task-b", "/test", "hashB", []Task{taskA})
+	taskC := NewMockTask("C", "task-c", "/test", "hashC", []Task{taskB})
+	taskD := NewMockTask("D", "task-d", "/test", "hashD", nil)
+
+	graph := NewGraph()
+	graph.AddTask(taskA)
+	graph.AddTask(taskB)
+	graph.AddTask(taskC)
+	graph.AddTask(taskD)
+
+	dependents := graph.Dependents("A")
+	if len(dependents) != 1 || dependents[0].ID() != "B" {
+		t.Errorf("Expected B to be the only direct dependent of A, got %v", dependents)
+	}
+
+	var ids []string
+	for _, task := range graph.TransitiveDependents([]string{"A"}) {
+		ids = append(ids, task.ID())
+	}
+	if fmt.Sprint(ids) != "[A B C]" {
+		t.Errorf("Expected [A B C], got %v", ids)
+	}
+}
+
+func TestGraph_OwningTasks(t *testing.T) {
+	moduleDir := t.TempDir()
+	mainDir := filepath.Join(moduleDir, "src", "main", "kotlin")
+	testDir := filepath.Join(moduleDir, "src", "test", "kotlin")
+	for _, dir := range []string{mainDir, testDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+
+	compile := &MockSourceTask{NewMockTask("compile", "kotlin-compile", mainDir, "h1", nil), []string{filepath.Join(mainDir, "Main.kt")}}
+	fooTest := &MockSourceTask{NewMockTask("foo", "junit-test", testDir, "h2", []Task{compile}), []string{filepath.Join(testDir, "FooTest.kt")}}
+	barTest := &MockSourceTask{NewMockTask("bar", "junit-test", testDir, "h3", []Task{compile}), []string{filepath.Join(testDir, "BarTest.kt")}}
+	jar := NewMockTask("jar", "jar-compile", moduleDir, "h4", []Task{compile})
+
+	graph := NewGraph()
+	graph.AddTask(compile)
+	graph.AddTask(fooTest)
+	graph.AddTask(barTest)
+	graph.AddTask(jar)
+
+	owners := func(path string) string {
+		var ids []string
+		for _, task := range graph.OwningTasks(path) {
+			ids = append(ids, task.ID())
+		}
+		return fmt.Sprint(ids)
+	}
+
+	tests := map[string]string{
+		filepath.Join(testDir, "FooTest.kt"):         "[foo]",
+		filepath.Join(testDir, "Deleted.kt"):         "[foo bar]",
+		filepath.Join(moduleDir, "build.gradle.kts"): "[compile foo bar jar]",
+		filepath.Join(moduleDir, "src", "test"):      "[foo bar]",
+		filepath.Join(filepath.Dir(moduleDir), "README.md"): "[]",
+	}
+	for path, expected := range tests {
+		if actual := owners(path); actual != expected {
+			t.Errorf("Expected owners of %s to be %s, got %s", path, expected, actual)
+		}
+	}
+}
@@ -3,11 +3,80 @@ package graph
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 )
 
-// ComputeTaskHash computes a hash for a task including its dependencies
+var (
+	workspaceRoot   string
+	workspaceRootMu sync.RWMutex
+)
+
+// SetWorkspaceRoot sets the directory that HashPath makes paths relative to
+func SetWorkspaceRoot(dir string) {
+	workspaceRootMu.Lock()
+	defer workspaceRootMu.Unlock()
+	workspaceRoot = dir
+}
+
+// HashPath returns the form of a path that task hashes include. Paths in the
+// workspace are made relative to its root, so that checkouts at different
+// locations share cache keys. Other paths are returned unchanged.
+func HashPath(path string) string {
+	workspaceRootMu.RLock()
+	root := workspaceRoot
+	workspaceRootMu.RUnlock()
+	
+	if root == "" {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// HashClasspathEntry returns the form of a classpath entry that task hashes
+// include. JARs and other files are identified by their content digest, so
+// that JARs in per-user caches such as ~/.gradle hash the same on every
+// machine. Directories and missing files fall back to HashPath.
+func HashClasspathEntry(path string) string {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		if digest, err := FileDigest(path); err == nil {
+			return digest
+		}
+	}
+	return HashPath(path)
+}
+
+// ComputeTaskHash computes a hash for a task including its dependencies.
+// ABI consumers are executed under the hash computed by executionHash once
+// their dependencies published their ABI fingerprints.
 func ComputeTaskHash(task Task) string {
+	return computeTaskHash(task, make(map[string]string))
+}
+
+// ComputeTaskHashes computes the hashes of all given tasks, including their
+// dependencies, computing the hash of each shared dependency only once
+func ComputeTaskHashes(tasks []Task) map[string]string {
+	hashes := make(map[string]string, len(tasks))
+	for _, task := range tasks {
+		computeTaskHash(task, hashes)
+	}
+	return hashes
+}
+
+// computeTaskHash computes a hash for a task, reusing and recording the hashes
+// of tasks that were already computed
+func computeTaskHash(task Task, hashes map[string]string) string {
+	if hash, exists := hashes[task.ID()]; exists {
+		return hash
+	}
+	
 	h := sha256.New()
 	
 	// Add the task's own hash
@@ -16,7 +85,7 @@ func ComputeTaskHash(task Task) string {
 	// Add dependency hashes (sorted for consistency)
 	var depHashes []string
 	for _, dep := range task.Dependencies() {
-		depHashes = append(depHashes, ComputeTaskHash(dep))
+		depHashes = append(depHashes, computeTaskHash(dep, hashes))
 	}
 	sort.Strings(depHashes)
 	
@@ -24,5 +93,62 @@ func ComputeTaskHash(task Task) string {
 		h.Write([]byte(depHash))
 	}
 	
+	hash := fmt.Sprintf("%x", h.Sum(nil))
+	hashes[task.ID()] = hash
+	return hash
+}
+
+// executionHash computes the hash a task is executed and cached under from the
+// results of its dependencies. ABI consumers use the ABI fingerprints their
+// dependencies published instead of the dependencies' hashes, and every other
+// task uses the execution hashes of its dependencies, so without ABI
+// fingerprints this is the hash computed by ComputeTaskHash. If a dependency
+// has no result, precomputedHash is returned.
+func executionHash(task Task, precomputedHash string, dependencyResults map[string]ExecutionResult) string {
+	_, consumesABI := task.(ABIConsumer)
+	
+	h := sha256.New()
+	h.Write([]byte(task.Hash()))
+	
+	var depHashes []string
+	for _, dep := range task.Dependencies() {
+		result, exists := dependencyResults[dep.ID()]
+		if !exists {
+			return precomputedHash
+		}
+		if consumesABI && result.Result.ABI != "" {
+			depHashes = append(depHashes, "abi:"+result.Result.ABI)
+		} else {
+			depHashes = append(depHashes, result.TaskHash)
+		}
+	}
+	sort.Strings(depHashes)
+	
+	for _, depHash := range depHashes {
+		h.Write([]byte(depHash))
+	}
+	
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// CombineABI returns the ABI fingerprint of the classes described by the given
+// fingerprints together, such as the classes of the sources of a module. It
+// returns an empty string if there are none or one of them is unknown.
+func CombineABI(abis ...string) string {
+	if len(abis) == 0 {
+		return ""
+	}
+	
+	sorted := make([]string, len(abis))
+	copy(sorted, abis)
+	sort.Strings(sorted)
+	
+	h := sha256.New()
+	for _, abi := range sorted {
+		if abi == "" {
+			return ""
+		}
+		fmt.Fprintf(h, "%s\n", abi)
+	}
 	return fmt.Sprintf("%x", h.Sum(nil))
-}
\ No newline at end of file
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// MockABIConsumer is a MockTask that only depends on the ABI of its dependencies
+type MockABIConsumer struct {
+	*MockTask
+}
+
+func (m *MockABIConsumer) ConsumesABI() {}
+
+func TestRunner_ABIKeys(t *testing.T) {
+	runner := NewRunner(t.TempDir())
+	executions := make(map[string]int)
+
+	// build runs a library publishing abi, a compilation against it and a test of it
+	build := func(libraryHash, abi string) []ExecutionResult {
+		t.Helper()
+		library := NewMockTask("library-"+libraryHash, "library", "/lib", libraryHash, nil)
+		library.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+			os.WriteFile(filepath.Join(workDir, "library.txt"), []byte(libraryHash), 0644)
+			return TaskResult{Files: []string{"library.txt"}, ABI: abi}
+		}
+
+		count := func(name string, task *MockTask) {
+			task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+				executions[name]++
+				if dependencyInputs[0].ABI != abi {
+					t.Errorf("Expected dependency ABI %s, got %s", abi, dependencyInputs[0].ABI)
+				}
+				os.WriteFile(filepath.Join(workDir, name+".txt"), []byte(name), 0644)
+				return TaskResult{Files: []string{name + ".txt"}}
+			}
+		}
+		compile := &MockABIConsumer{MockTask: NewMockTask("compile", "compile", "/app", "hashCompile", []Task{library})}
+		count("compile", compile.MockTask)
+		test := NewMockTask("test", "test", "/app", "hashTest", []Task{library})
+		count("test", test)
+
+		g := NewGraph()
+		for _, task := range []Task{library, compile, test} {
+			g.AddTask(task)
+		}
+		results, err := runner.Execute(context.Background(), g)
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		return results
+	}
+
+	build("lib1", "abi1")
+
+	// A change to the implementation of the library only reruns the test
+	build("lib2", "abi1")
+	if executions["compile"] != 1 || executions["test"] != 2 {
+		t.Errorf("Expected the compilation to be cached and the test to rerun, got %v", executions)
+	}
+
+	// An ABI change recompiles the dependents
+	results := build("lib3", "abi2")
+	if executions["compile"] != 2 || executions["test"] != 3 {
+		t.Errorf("Expected an ABI change to recompile, got %v", executions)
+	}
+
+	// The ABI of cached results is read from their manifest
+	for _, result := range results {
+		if result.Task.Name() == "library" && result.Result.ABI != "abi2" {
+			t.Errorf("Expected the library to publish abi2, got %q", result.Result.ABI)
+		}
+	}
+	build("lib3", "abi2")
+	if executions["compile"] != 2 || executions["test"] != 3 {
+		t.Errorf("Expected a fully cached build, got %v", executions)
+	}
+}
+
+func TestCombineABI(t *testing.T) {
+	if CombineABI("a", "b") != CombineABI("b", "a") {
+		t.Error("Expected the combined ABI not to depend on the order")
+	}
+	if CombineABI("a", "b") == CombineABI("a", "c") {
+		t.Error("Expected different ABIs to combine differently")
+	}
+	if CombineABI("a", "") != "" || CombineABI() != "" {
+		t.Error("Expected an unknown ABI to make the combined ABI unknown")
+	}
+}
+
+func TestHashPath(t *testing.T) {
+	SetWorkspaceRoot("/checkout/repo")
+	defer SetWorkspaceRoot("")
+
+	if HashPath("/checkout/repo/service/src/main/kotlin") != "service/src/main/kotlin" {
+		t.Errorf("Expected a path relative to the workspace root, got %s", HashPath("/checkout/repo/service/src/main/kotlin"))
+	}
+	if HashPath("/checkout/repository/lib") != "/checkout/repository/lib" {
+		t.Errorf("Expected a path outside the workspace to be unchanged, got %s", HashPath("/checkout/repository/lib"))
+	}
+}
+
+func TestHashClasspathEntry(t *testing.T) {
+	// The same JAR in the caches of two users hashes the same
+	var digests []string
+	for _, home := range []string{t.TempDir(), t.TempDir()} {
+		jar := filepath.Join(home, ".gradle", "caches", "lib-1.0.jar")
+		os.MkdirAll(filepath.Dir(jar), 0755)
+		if err := os.WriteFile(jar, []byte("jar contents"), 0644); err != nil {
+			t.Fatalf("Failed to write jar: %v", err)
+		}
+		digests = append(digests, HashClasspathEntry(jar))
+	}
+	if digests[0] != digests[1] {
+		t.Errorf("Expected identical JARs to hash the same, got %s and %s", digests[0], digests[1])
+	}
+
+	// Directories and missing files are hashed by their path
+	SetWorkspaceRoot("/checkout/repo")
+	defer SetWorkspaceRoot("")
+	if entry := HashClasspathEntry("/checkout/repo/lib/classes"); entry != "lib/classes" {
+		t.Errorf("Expected a missing classes directory to be hashed by its path, got %s", entry)
+	}
+}
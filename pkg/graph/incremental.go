@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// incrementalDirName is the directory inside the result directory that records
+// the latest result of each incremental task
+const incrementalDirName = ".incremental"
+
+// previousOutputKey is the context key under which the runner stores the
+// output directory of a task's previous execution
+type previousOutputKey struct{}
+
+// IncrementalTask is implemented by tasks that can update the output of their
+// previous execution instead of starting from scratch, such as compilers that
+// only recompile the changed sources
+type IncrementalTask interface {
+	// IncrementalKey identifies the task across runs, since its ID changes
+	// with every change to its inputs
+	IncrementalKey() string
+}
+
+// WithPreviousOutput returns a context whose task can reuse the outputs in dir
+func WithPreviousOutput(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, previousOutputKey{}, dir)
+}
+
+// PreviousOutput returns the cached output directory of the latest successful
+// execution of an incremental task, or an empty string if there is none. The
+// directory belongs to the cache and must not be modified.
+func PreviousOutput(ctx context.Context) string {
+	dir, _ := ctx.Value(previousOutputKey{}).(string)
+	return dir
+}
+
+// previousOutput returns the output directory of the latest successful
+// execution of a task, if the task is incremental and the entry is still cached
+func (r *Runner) previousOutput(task Task) string {
+	incremental, ok := task.(IncrementalTask)
+	if !ok {
+		return ""
+	}
+
+	data, err := os.ReadFile(r.incrementalPath(incremental))
+	if err != nil {
+		return ""
+	}
+	outputDir := filepath.Join(r.resultDir, strings.TrimSpace(string(data)))
+	if !r.isCached(outputDir) {
+		return ""
+	}
+	return outputDir
+}
+
+// recordOutput remembers a task's result as the one its next execution can build on
+func (r *Runner) recordOutput(task Task, taskHash string) error {
+	incremental, ok := task.(IncrementalTask)
+	if !ok {
+		return nil
+	}
+
+	path := r.incrementalPath(incremental)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create incremental state directory: %w", err)
+	}
+
+	// Parallel builds of the same task must never leave a partial record behind
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".record-")
+	if err != nil {
+		return fmt.Errorf("failed to record incremental state: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.WriteString(taskHash); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to record incremental state: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to record incremental state: %w", err)
+	}
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to record incremental state: %w", err)
+	}
+	return nil
+}
+
+// incrementalPath returns the file recording the latest result of a task
+func (r *Runner) incrementalPath(task IncrementalTask) string {
+	return filepath.Join(r.resultDir, incrementalDirName, fmt.Sprintf("%x", sha256.Sum256([]byte(task.IncrementalKey()))))
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// MockIncrementalTask is a MockTask that builds on its previous output
+type MockIncrementalTask struct {
+	*MockTask
+	key string
+}
+
+func (m *MockIncrementalTask) IncrementalKey() string {
+	return m.key
+}
+
+// newAppendingTask returns an incremental task whose output counts its executions
+func newAppendingTask(hash string) *MockIncrementalTask {
+	task := &MockIncrementalTask{
+		MockTask: NewMockTask("append-"+hash, "append", "/src", hash, nil),
+		key:      "append:/src",
+	}
+	task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+		count := []byte("1")
+		if previous := PreviousOutput(ctx); previous != "" {
+			data, err := os.ReadFile(filepath.Join(previous, "count.txt"))
+			if err != nil {
+				return TaskResult{Error: err}
+			}
+			count = append(data, '1')
+		}
+		if err := os.WriteFile(filepath.Join(workDir, "count.txt"), count, 0644); err != nil {
+			return TaskResult{Error: err}
+		}
+		return TaskResult{Files: []string{"count.txt"}}
+	}
+	return task
+}
+
+func TestRunner_PreviousOutput(t *testing.T) {
+	runner := NewRunner(t.TempDir())
+	ctx := context.Background()
+
+	readCount := func(result ExecutionResult) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(result.OutputDir, "count.txt"))
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		return string(data)
+	}
+
+	for i, expected := range []string{"1", "11", "111"} {
+		result, err := runner.ExecuteTask(ctx, newAppendingTask(string(rune('a'+i))))
+		if err != nil || result.Result.Error != nil {
+			t.Fatalf("Execution %d failed: %v %v", i, err, result.Result.Error)
+		}
+		if count := readCount(result); count != expected {
+			t.Errorf("Execution %d: expected count %s, got %s", i, expected, count)
+		}
+	}
+
+	// A cache hit becomes the starting point of the next execution
+	if _, err := runner.ExecuteTask(ctx, newAppendingTask("a")); err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	result, err := runner.ExecuteTask(ctx, newAppendingTask("d"))
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if count := readCount(result); count != "11" {
+		t.Errorf("Expected to build on the cached result, got count %s", count)
+	}
+
+	// Sandboxed tasks only see their declared inputs
+	runner.SetSandbox(true)
+	result, err = runner.ExecuteTask(ctx, newAppendingTask("e"))
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if count := readCount(result); count != "1" {
+		t.Errorf("Expected a sandboxed task to start from scratch, got count %s", count)
+	}
+}
@@ -0,0 +1,300 @@
+package graph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stagingMaxAge is how old an abandoned staging directory must be before it is
+// considered left over from an interrupted run rather than in use
+const stagingMaxAge = time.Hour
+
+// CacheEntry describes a single entry in the local result cache
+type CacheEntry struct {
+	Hash       string
+	Size       int64
+	LastAccess time.Time
+}
+
+// CacheStats summarizes the contents of the local result cache
+type CacheStats struct {
+	Entries      int
+	TotalSize    int64
+	OldestAccess time.Time
+	NewestAccess time.Time
+}
+
+// CacheProblem describes an entry that failed verification
+type CacheProblem struct {
+	Hash   string
+	Reason string
+}
+
+// LocalCache manages the on-disk result directory used by the Runner
+type LocalCache struct {
+	dir string
+}
+
+// NewLocalCache creates a local cache for the given result directory
+func NewLocalCache(dir string) *LocalCache {
+	return &LocalCache{
+		dir: dir,
+	}
+}
+
+// Dir returns the result directory managed by this cache
+func (c *LocalCache) Dir() string {
+	return c.dir
+}
+
+// Entries returns all entries in the cache
+func (c *LocalCache) Entries() ([]CacheEntry, error) {
+	dirEntries, err := c.readDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+
+		hash := dirEntry.Name()
+		size, err := dirSize(filepath.Join(c.dir, hash))
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure cache entry %s: %w", hash, err)
+		}
+
+		entries = append(entries, CacheEntry{
+			Hash:       hash,
+			Size:       size,
+			LastAccess: c.lastAccess(hash),
+		})
+	}
+
+	return entries, nil
+}
+
+// Stats returns summary statistics for the cache
+func (c *LocalCache) Stats() (CacheStats, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return CacheStats{}, err
+	}
+
+	var stats CacheStats
+	for _, entry := range entries {
+		stats.Entries++
+		stats.TotalSize += entry.Size
+		if stats.OldestAccess.IsZero() || entry.LastAccess.Before(stats.OldestAccess) {
+			stats.OldestAccess = entry.LastAccess
+		}
+		if entry.LastAccess.After(stats.NewestAccess) {
+			stats.NewestAccess = entry.LastAccess
+		}
+	}
+
+	return stats, nil
+}
+
+// GarbageCollect evicts entries that were not used within olderThan and then
+// evicts least recently used entries until the cache fits in maxSize bytes.
+// A zero olderThan or maxSize disables the corresponding limit.
+// It returns the evicted entries.
+func (c *LocalCache) GarbageCollect(maxSize int64, olderThan time.Duration) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Least recently used first
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastAccess.Before(entries[j].LastAccess)
+	})
+
+	var totalSize int64
+	for _, entry := range entries {
+		totalSize += entry.Size
+	}
+
+	var evicted []CacheEntry
+	cutoff := time.Now().Add(-olderThan)
+	for _, entry := range entries {
+		expired := olderThan > 0 && entry.LastAccess.Before(cutoff)
+		oversized := maxSize > 0 && totalSize > maxSize
+		if !expired && !oversized {
+			continue
+		}
+
+		if err := c.Remove(entry.Hash); err != nil {
+			return evicted, err
+		}
+		totalSize -= entry.Size
+		evicted = append(evicted, entry)
+	}
+
+	if err := c.removeAbandonedStaging(); err != nil {
+		return evicted, err
+	}
+
+	return evicted, nil
+}
+
+// Clean removes every entry from the cache
+func (c *LocalCache) Clean() error {
+	dirEntries, err := c.readDir()
+	if err != nil {
+		return err
+	}
+
+	for _, dirEntry := range dirEntries {
+		if err := os.RemoveAll(filepath.Join(c.dir, dirEntry.Name())); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dirEntry.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// Verify checks every entry against its manifest and reports entries that are
+// partially written or whose files changed after they were stored
+func (c *LocalCache) Verify() ([]CacheProblem, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []CacheProblem
+	for _, entry := range entries {
+		if reason := c.verifyEntry(entry.Hash); reason != "" {
+			problems = append(problems, CacheProblem{Hash: entry.Hash, Reason: reason})
+		}
+	}
+
+	// Staging directories left behind by interrupted runs
+	dirEntries, err := c.readDir()
+	if err != nil {
+		return nil, err
+	}
+	for _, dirEntry := range dirEntries {
+		if isStagingDir(dirEntry.Name()) && c.isAbandoned(dirEntry) {
+			problems = append(problems, CacheProblem{Hash: dirEntry.Name(), Reason: "abandoned staging directory"})
+		}
+	}
+
+	return problems, nil
+}
+
+// Remove deletes a single entry
+func (c *LocalCache) Remove(hash string) error {
+	if err := os.RemoveAll(filepath.Join(c.dir, hash)); err != nil {
+		return fmt.Errorf("failed to remove cache entry %s: %w", hash, err)
+	}
+	return nil
+}
+
+// touch records that an entry was just used by updating its manifest's modification time
+func (c *LocalCache) touch(hash string) error {
+	now := time.Now()
+	return os.Chtimes(c.manifestPath(hash), now, now)
+}
+
+// lastAccess returns when an entry was last used, falling back to the
+// modification time of the entry itself when it has no manifest
+func (c *LocalCache) lastAccess(hash string) time.Time {
+	if info, err := os.Stat(c.manifestPath(hash)); err == nil {
+		return info.ModTime()
+	}
+	if info, err := os.Stat(filepath.Join(c.dir, hash)); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// verifyEntry returns a description of what is wrong with an entry, or an empty string
+func (c *LocalCache) verifyEntry(hash string) string {
+	entryDir := filepath.Join(c.dir, hash)
+	manifest, err := ReadManifest(entryDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "missing manifest (entry may be partially written)"
+		}
+		return fmt.Sprintf("unreadable manifest: %v", err)
+	}
+
+	if manifest.TaskHash != hash {
+		return fmt.Sprintf("manifest belongs to %s", manifest.TaskHash)
+	}
+
+	if err := verifyManifest(entryDir, manifest); err != nil {
+		return err.Error()
+	}
+
+	return ""
+}
+
+// removeAbandonedStaging deletes staging directories left behind by interrupted runs
+func (c *LocalCache) removeAbandonedStaging() error {
+	dirEntries, err := c.readDir()
+	if err != nil {
+		return err
+	}
+
+	for _, dirEntry := range dirEntries {
+		if isStagingDir(dirEntry.Name()) && c.isAbandoned(dirEntry) {
+			if err := os.RemoveAll(filepath.Join(c.dir, dirEntry.Name())); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", dirEntry.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isAbandoned reports whether a staging directory is old enough to no longer be in use
+func (c *LocalCache) isAbandoned(dirEntry os.DirEntry) bool {
+	info, err := dirEntry.Info()
+	return err == nil && time.Since(info.ModTime()) > stagingMaxAge
+}
+
+// readDir lists the result directory, treating a missing directory as empty
+func (c *LocalCache) readDir() ([]os.DirEntry, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	return dirEntries, nil
+}
+
+// manifestPath returns the path of the manifest file for an entry
+func (c *LocalCache) manifestPath(hash string) string {
+	return filepath.Join(c.dir, hash, ManifestFileName)
+}
+
+// isStagingDir reports whether a directory name belongs to an in-progress cache write
+func isStagingDir(name string) bool {
+	return strings.HasPrefix(name, ".stage-") || strings.HasPrefix(name, ".fetch-") || strings.HasPrefix(name, ".store-")
+}
+
+// dirSize returns the total size of all files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
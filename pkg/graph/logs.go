@@ -0,0 +1,156 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// taskLogKey is the context key under which the runner stores a task's log writer
+type taskLogKey struct{}
+
+// WithTaskLog returns a context whose task log is w
+func WithTaskLog(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, taskLogKey{}, w)
+}
+
+// TaskLog returns the writer that a task should send the output of the tools
+// it runs to. Output written here ends up in the task's log file.
+func TaskLog(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(taskLogKey{}).(io.Writer); ok {
+		return w
+	}
+	return io.Discard
+}
+
+// LogInfo describes a task log kept after a failed run
+type LogInfo struct {
+	TaskHash    string    `json:"taskHash"`
+	TaskID      string    `json:"taskId"`
+	TaskName    string    `json:"taskName"`
+	DisplayName string    `json:"displayName"`
+	TaskType    TaskType  `json:"taskType"`
+	Directory   string    `json:"directory"`
+	Error       string    `json:"error"`
+	Time        time.Time `json:"time"`
+}
+
+// LogStore keeps the output of task executions. Every executed task writes a
+// log; it is deleted when the task succeeds and kept when it fails.
+type LogStore struct {
+	dir string
+}
+
+// NewLogStore creates a log store in the given directory
+func NewLogStore(dir string) *LogStore {
+	return &LogStore{
+		dir: dir,
+	}
+}
+
+// LogPath returns the path of the log file for a task hash
+func (s *LogStore) LogPath(taskHash string) string {
+	return filepath.Join(s.dir, taskHash+".log")
+}
+
+// List returns all kept logs, most recent first
+func (s *LogStore) List() ([]LogInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	var infos []LogInfo
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var info LogInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Time.After(infos[j].Time)
+	})
+	return infos, nil
+}
+
+// create opens a fresh log file for a task execution
+func (s *LogStore) create(taskHash string) (*os.File, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	// A log from an earlier failed run is replaced by this one
+	os.Remove(s.infoPath(taskHash))
+	return os.Create(s.LogPath(taskHash))
+}
+
+// keep records the metadata of a failed task so that its log can be found
+// later. It replaces the logs of earlier runs of the same task under other
+// hashes, so that only the log of the last run is kept.
+func (s *LogStore) keep(task Task, taskHash string, taskErr error) error {
+	info := LogInfo{
+		TaskHash:    taskHash,
+		TaskID:      task.ID(),
+		TaskName:    task.Name(),
+		DisplayName: task.DisplayName(),
+		TaskType:    task.TaskType(),
+		Directory:   task.Directory(),
+		Error:       taskErr.Error(),
+		Time:        time.Now(),
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode log info: %w", err)
+	}
+	if err := os.WriteFile(s.infoPath(taskHash), data, 0644); err != nil {
+		return err
+	}
+	return s.discardEarlier(task, taskHash)
+}
+
+// discardEarlier deletes the logs of earlier runs of a task under hashes other
+// than taskHash. Task IDs and hashes change with the task's inputs, so tasks
+// are identified by their name and location instead.
+func (s *LogStore) discardEarlier(task Task, taskHash string) error {
+	earlier, err := s.List()
+	if err != nil {
+		return err
+	}
+	for _, other := range earlier {
+		if other.TaskHash != taskHash && other.TaskName == task.Name() && other.DisplayName == task.DisplayName() && other.Directory == task.Directory() {
+			s.discard(other.TaskHash)
+		}
+	}
+	return nil
+}
+
+// discard deletes the log of a task that succeeded
+func (s *LogStore) discard(taskHash string) {
+	os.Remove(s.LogPath(taskHash))
+	os.Remove(s.infoPath(taskHash))
+}
+
+// infoPath returns the path of the metadata file for a task hash
+func (s *LogStore) infoPath(taskHash string) string {
+	return filepath.Join(s.dir, taskHash+".json")
+}
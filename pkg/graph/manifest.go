@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ManifestFileName is the name of the manifest file inside each cache entry.
+// An entry without a manifest is incomplete and never served from the cache.
+const ManifestFileName = ".fbs-manifest.json"
+
+// manifestVersion is bumped whenever the manifest format changes incompatibly
+const manifestVersion = 1
+
+// ManifestFile describes a single output file of a cached task
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Digest string `json:"digest"`
+}
+
+// ResultManifest records the contents of a cache entry and the task that produced it
+type ResultManifest struct {
+	Version     int            `json:"version"`
+	TaskHash    string         `json:"taskHash"`
+	TaskName    string         `json:"taskName"`
+	DisplayName string         `json:"displayName"`
+	TaskType    TaskType       `json:"taskType"`
+	Directory   string         `json:"directory"`
+	Files       []ManifestFile `json:"files"`
+	ABI         string         `json:"abi,omitempty"`
+	DurationMs  int64          `json:"durationMs"`
+	CreatedAt   time.Time      `json:"createdAt"`
+}
+
+// FilePaths returns the relative paths of all files in the manifest
+func (m *ResultManifest) FilePaths() []string {
+	paths := make([]string, 0, len(m.Files))
+	for _, file := range m.Files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+// newResultManifest builds a manifest describing the files in entryDir and the
+// ABI fingerprint the task published
+func newResultManifest(task Task, taskHash, entryDir, abi string, duration time.Duration) (*ResultManifest, error) {
+	files, err := listManifestFiles(entryDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultManifest{
+		Version:     manifestVersion,
+		TaskHash:    taskHash,
+		TaskName:    task.Name(),
+		DisplayName: task.DisplayName(),
+		TaskType:    task.TaskType(),
+		Directory:   task.Directory(),
+		Files:       files,
+		ABI:         abi,
+		DurationMs:  duration.Milliseconds(),
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// listManifestFiles returns size and digest of every file under entryDir, sorted by path
+func listManifestFiles(entryDir string) ([]ManifestFile, error) {
+	var files []ManifestFile
+	err := filepath.Walk(entryDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(entryDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == ManifestFileName {
+			return nil
+		}
+
+		digest, err := digestFile(path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, ManifestFile{
+			Path:   relPath,
+			Size:   info.Size(),
+			Digest: digest,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", entryDir, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// writeManifest writes the manifest into entryDir
+func writeManifest(entryDir string, manifest *ResultManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(entryDir, ManifestFileName), data, 0644)
+}
+
+// ReadManifest reads the manifest of the cache entry in entryDir
+func ReadManifest(entryDir string) (*ResultManifest, error) {
+	data, err := os.ReadFile(filepath.Join(entryDir, ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest ResultManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+// verifyManifest checks that the files in entryDir match the manifest exactly
+func verifyManifest(entryDir string, manifest *ResultManifest) error {
+	actual, err := listManifestFiles(entryDir)
+	if err != nil {
+		return err
+	}
+
+	actualByPath := make(map[string]ManifestFile)
+	for _, file := range actual {
+		actualByPath[file.Path] = file
+	}
+
+	for _, expected := range manifest.Files {
+		file, exists := actualByPath[expected.Path]
+		if !exists {
+			return fmt.Errorf("missing file %s", expected.Path)
+		}
+		if file.Size != expected.Size {
+			return fmt.Errorf("file %s has size %d, expected %d", expected.Path, file.Size, expected.Size)
+		}
+		if file.Digest != expected.Digest {
+			return fmt.Errorf("file %s has been modified", expected.Path)
+		}
+		delete(actualByPath, expected.Path)
+	}
+
+	for path := range actualByPath {
+		return fmt.Errorf("unexpected file %s", path)
+	}
+
+	return nil
+}
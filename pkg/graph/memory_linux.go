@@ -0,0 +1,15 @@
+package graph
+
+import (
+	"syscall"
+)
+
+// systemMemoryMB returns the physical memory of the machine in megabytes, or
+// zero if it can't be determined
+func systemMemoryMB() int {
+	var info syscall.Sysinfo_t
+	if err := syscall.Sysinfo(&info); err != nil {
+		return 0
+	}
+	return int(uint64(info.Totalram) * uint64(info.Unit) / (1024 * 1024))
+}
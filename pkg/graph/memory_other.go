@@ -0,0 +1,9 @@
+//go:build !linux
+
+package graph
+
+// systemMemoryMB returns zero on platforms where the physical memory isn't
+// read, so that the fallback memory pool is used
+func systemMemoryMB() int {
+	return 0
+}
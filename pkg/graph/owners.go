@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OwningTasks returns the tasks that directly depend on the file or directory at path.
+//
+// A directory is owned by every task that reads a file inside it or was
+// discovered inside it. A file is owned by the tasks that declare it as an
+// input. A file that no task declares, such as a build script or a deleted
+// source file, is conservatively owned by every task at or below the closest
+// enclosing directory in which a task was discovered.
+func (g *Graph) OwningTasks(path string) []Task {
+	path = filepath.Clean(path)
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		var owners []Task
+		for _, task := range g.tasks {
+			if isWithin(task.Directory(), path) || readsWithin(task, path) {
+				owners = append(owners, task)
+			}
+		}
+		return owners
+	}
+
+	var owners []Task
+	for _, task := range g.tasks {
+		if provider, ok := task.(InputProvider); ok {
+			for _, input := range provider.Inputs() {
+				if filepath.Clean(input) == path {
+					owners = append(owners, task)
+					break
+				}
+			}
+		}
+	}
+	if len(owners) > 0 {
+		return owners
+	}
+
+	// Fall back to the closest enclosing directory in which a task was discovered
+	taskDirs := make(map[string]bool)
+	for _, task := range g.tasks {
+		taskDirs[filepath.Clean(task.Directory())] = true
+	}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if taskDirs[dir] {
+			for _, task := range g.tasks {
+				if isWithin(task.Directory(), dir) {
+					owners = append(owners, task)
+				}
+			}
+			return owners
+		}
+		if filepath.Dir(dir) == dir {
+			return nil
+		}
+	}
+}
+
+// readsWithin reports whether a task declares an input inside dir
+func readsWithin(task Task, dir string) bool {
+	provider, ok := task.(InputProvider)
+	if !ok {
+		return false
+	}
+	for _, input := range provider.Inputs() {
+		if isWithin(input, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(path, dir string) bool {
+	relPath, err := filepath.Rel(dir, filepath.Clean(path))
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
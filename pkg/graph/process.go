@@ -0,0 +1,28 @@
+package graph
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// processWaitDelay bounds how long a cancelled command may take to release its
+// output after it was killed
+const processWaitDelay = 5 * time.Second
+
+// Command creates a command for a task to run. The command runs in its own
+// process group, and when ctx is done the whole group is killed, so that
+// processes started by the command, such as a forked JVM, don't outlive it.
+// In sandbox mode the command runs in the sandbox directory with the HOME and
+// temporary directory of the sandbox; tasks add to cmd.Environ() rather than
+// replacing the environment.
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = processWaitDelay
+	configureProcessGroup(cmd)
+	if sandbox, ok := ctx.Value(sandboxKey{}).(*Sandbox); ok {
+		cmd.Dir = sandbox.dir
+		cmd.Env = sandbox.environ()
+	}
+	return cmd
+}
@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommand_KillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The shell starts a child that would outlive the shell if only the shell was killed
+	cmd := Command(ctx, "sh", "-c", "sleep 30 & echo $!; wait")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start command: %v", err)
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read child PID: %v", err)
+	}
+	childPID := strings.TrimSpace(line)
+
+	cancel()
+	cmd.Wait()
+
+	// The child is gone or a zombie waiting to be reaped
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stat, err := os.ReadFile(fmt.Sprintf("/proc/%s/stat", childPID))
+		if err != nil || strings.Contains(string(stat), ") Z ") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected child process %s to be killed, got %s", childPID, stat)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
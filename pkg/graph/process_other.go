@@ -0,0 +1,12 @@
+//go:build !unix
+
+package graph
+
+import (
+	"os/exec"
+)
+
+// configureProcessGroup keeps the default behaviour of killing only the
+// command itself on platforms without process groups
+func configureProcessGroup(cmd *exec.Cmd) {
+}
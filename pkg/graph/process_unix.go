@@ -0,0 +1,18 @@
+//go:build unix
+
+package graph
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// configureProcessGroup starts the command in a new process group and kills
+// the group when the command's context is done
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// A negative PID signals every process in the group
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,202 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Profile describes where the time of a build went, based on the results of
+// the tasks that were executed or restored from the cache
+type Profile struct {
+	results []ExecutionResult
+	start   time.Time
+	end     time.Time
+}
+
+// NewProfile creates a profile of the given results. Skipped tasks did not run
+// and are left out.
+func NewProfile(results []ExecutionResult) *Profile {
+	profile := &Profile{}
+	for _, result := range results {
+		if result.Skipped || result.StartTime.IsZero() {
+			continue
+		}
+		profile.results = append(profile.results, result)
+
+		end := result.StartTime.Add(result.Duration)
+		if profile.start.IsZero() || result.StartTime.Before(profile.start) {
+			profile.start = result.StartTime
+		}
+		if end.After(profile.end) {
+			profile.end = end
+		}
+	}
+	return profile
+}
+
+// WallTime returns the time from the start of the first task to the end of the last one
+func (p *Profile) WallTime() time.Duration {
+	return p.end.Sub(p.start)
+}
+
+// Slowest returns up to n results with the longest durations, slowest first
+func (p *Profile) Slowest(n int) []ExecutionResult {
+	slowest := append([]ExecutionResult(nil), p.results...)
+	sort.SliceStable(slowest, func(i, j int) bool {
+		return slowest[i].Duration > slowest[j].Duration
+	})
+	if len(slowest) > n {
+		slowest = slowest[:n]
+	}
+	return slowest
+}
+
+// CriticalPath returns the chain of tasks that determined the length of the
+// build, in execution order. It starts from the task that finished last and
+// repeatedly follows the dependency that finished last, since that is the
+// dependency the task had to wait for.
+func (p *Profile) CriticalPath() []ExecutionResult {
+	byID := make(map[string]ExecutionResult, len(p.results))
+	var last *ExecutionResult
+	for i, result := range p.results {
+		byID[result.Task.ID()] = result
+		if last == nil || endTime(result).After(endTime(*last)) {
+			last = &p.results[i]
+		}
+	}
+	if last == nil {
+		return nil
+	}
+
+	path := []ExecutionResult{*last}
+	for current := *last; ; {
+		var next *ExecutionResult
+		for _, dep := range current.Task.Dependencies() {
+			if depResult, exists := byID[dep.ID()]; exists {
+				if next == nil || endTime(depResult).After(endTime(*next)) {
+					next = &depResult
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		path = append(path, *next)
+		current = *next
+	}
+
+	// Reverse to execution order
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// traceEvent is an event in the Chrome trace event format, which is read by
+// chrome://tracing and Perfetto
+type traceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat,omitempty"`
+	Phase     string         `json:"ph"`
+	Timestamp int64          `json:"ts"`
+	Duration  int64          `json:"dur"`
+	ProcessID int            `json:"pid"`
+	ThreadID  int            `json:"tid"`
+	Args      map[string]any `json:"args,omitempty"`
+}
+
+// WriteTrace writes the profile in the Chrome trace event format, with one
+// lane per worker and timestamps relative to the start of the build
+func (p *Profile) WriteTrace(w io.Writer) error {
+	events := []traceEvent{}
+
+	workers := make(map[int]bool)
+	for _, result := range p.results {
+		workers[result.Worker] = true
+	}
+	for worker := range workers {
+		events = append(events, traceEvent{
+			Name:     "thread_name",
+			Phase:    "M",
+			ThreadID: worker,
+			Args:     map[string]any{"name": fmt.Sprintf("worker %d", worker)},
+		})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ThreadID < events[j].ThreadID
+	})
+
+	for _, result := range p.results {
+		status := "executed"
+		switch {
+		case result.Result.Error != nil:
+			status = "failed"
+		case result.RemoteHit:
+			status = "remote-cache-hit"
+		case result.CacheHit:
+			status = "cache-hit"
+		}
+
+		events = append(events, traceEvent{
+			Name:      result.Task.DisplayName(),
+			Category:  string(result.Task.TaskType()),
+			Phase:     "X",
+			Timestamp: result.StartTime.Sub(p.start).Microseconds(),
+			Duration:  result.Duration.Microseconds(),
+			ThreadID:  result.Worker,
+			Args: map[string]any{
+				"directory": result.Task.Directory(),
+				"taskHash":  result.TaskHash,
+				"status":    status,
+				"cacheHit":  result.CacheHit,
+			},
+		})
+	}
+
+	return json.NewEncoder(w).Encode(map[string]any{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
+
+// WriteSummary writes the slowest tasks and the critical path as text
+func (p *Profile) WriteSummary(w io.Writer, label func(task Task) string) {
+	fmt.Fprintf(w, "\nSlowest tasks:\n")
+	for _, result := range p.Slowest(10) {
+		fmt.Fprintf(w, "  %10s  %s%s\n", formatProfileDuration(result.Duration), label(result.Task), cacheNote(result))
+	}
+
+	path := p.CriticalPath()
+	var pathTime time.Duration
+	for _, result := range path {
+		pathTime += result.Duration
+	}
+	fmt.Fprintf(w, "\nCritical path (%s of %s wall time):\n", formatProfileDuration(pathTime), formatProfileDuration(p.WallTime()))
+	for _, result := range path {
+		fmt.Fprintf(w, "  %10s  %s%s\n", formatProfileDuration(result.Duration), label(result.Task), cacheNote(result))
+	}
+}
+
+// endTime returns when a task finished
+func endTime(result ExecutionResult) time.Time {
+	return result.StartTime.Add(result.Duration)
+}
+
+// cacheNote marks results that were restored from a cache
+func cacheNote(result ExecutionResult) string {
+	switch {
+	case result.RemoteHit:
+		return " (remote cache)"
+	case result.CacheHit:
+		return " (cached)"
+	}
+	return ""
+}
+
+// formatProfileDuration formats a duration with millisecond precision
+func formatProfileDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfile_CriticalPath(t *testing.T) {
+	start := time.Now()
+	compile := NewMockTask("compile", "kotlin-compile", "/test/main", "h1", nil)
+	download := NewMockTask("download", "artifact-download", "/test/deps", "h2", nil)
+	jar := NewMockTask("jar", "jar-compile", "/test", "h3", []Task{compile, download})
+	lint := NewMockTask("lint", "lint", "/test", "h4", nil)
+	
+	results := []ExecutionResult{
+		{Task: download, StartTime: start, Duration: 1 * time.Second, Worker: 1, CacheHit: true},
+		{Task: compile, StartTime: start, Duration: 4 * time.Second, Worker: 0},
+		{Task: lint, StartTime: start.Add(time.Second), Duration: 2 * time.Second, Worker: 1},
+		{Task: jar, StartTime: start.Add(4 * time.Second), Duration: 1 * time.Second, Worker: 0},
+		{Task: NewMockTask("skipped", "test", "/test", "h5", []Task{jar}), Skipped: true},
+	}
+	profile := NewProfile(results)
+	
+	if profile.WallTime() != 5*time.Second {
+		t.Errorf("Expected wall time of 5s, got %v", profile.WallTime())
+	}
+	
+	var path []string
+	for _, result := range profile.CriticalPath() {
+		path = append(path, result.Task.ID())
+	}
+	if strings.Join(path, ",") != "compile,jar" {
+		t.Errorf("Expected critical path compile,jar, got %v", path)
+	}
+	
+	slowest := profile.Slowest(2)
+	if len(slowest) != 2 || slowest[0].Task.ID() != "compile" || slowest[1].Task.ID() != "lint" {
+		t.Errorf("Unexpected slowest tasks %v", slowest)
+	}
+	
+	var summary bytes.Buffer
+	profile.WriteSummary(&summary, func(task Task) string { return task.DisplayName() })
+	for _, expected := range []string{"Slowest tasks:", "Critical path (5s of 5s wall time):", "1s  artifact-download (cached)"} {
+		if !strings.Contains(summary.String(), expected) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", expected, summary.String())
+		}
+	}
+}
+
+func TestProfile_WriteTrace(t *testing.T) {
+	graph := NewGraph()
+	taskA := NewMockTask("A", "task-a", "/test", "hashA", nil)
+	taskB := NewMockTask("B", "task-b", "/test", "hashB", []Task{taskA})
+	graph.AddTask(taskA)
+	graph.AddTask(taskB)
+	
+	runner := NewRunner(t.TempDir())
+	results, err := runner.ExecuteWithProgressParallel(context.Background(), graph, nil, 2)
+	if err != nil {
+		t.Fatalf("Failed to execute tasks: %v", err)
+	}
+	for _, result := range results {
+		if result.StartTime.IsZero() {
+			t.Errorf("Expected a start time for %s", result.Task.ID())
+		}
+	}
+	
+	var buf bytes.Buffer
+	if err := NewProfile(results).WriteTrace(&buf); err != nil {
+		t.Fatalf("Failed to write trace: %v", err)
+	}
+	
+	var trace struct {
+		TraceEvents []struct {
+			Name  string         `json:"name"`
+			Phase string         `json:"ph"`
+			Ts    int64          `json:"ts"`
+			Tid   int            `json:"tid"`
+			Args  map[string]any `json:"args"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("Failed to parse trace: %v", err)
+	}
+	
+	spans := make(map[string]int64)
+	for _, event := range trace.TraceEvents {
+		if event.Phase == "X" {
+			spans[event.Name] = event.Ts
+			if event.Args["status"] != "executed" {
+				t.Errorf("Expected status executed for %s, got %v", event.Name, event.Args["status"])
+			}
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 task spans, got %v", spans)
+	}
+	if spans["task-b"] < spans["task-a"] {
+		t.Errorf("Expected task-b to start after task-a, got %v", spans)
+	}
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"container/heap"
+	"time"
+)
+
+// readyQueue holds the tasks whose dependencies have completed. Tasks are
+// grouped by their resource needs, so the scheduler can find the highest
+// priority task that fits in the resource pools without scanning every ready
+// task. Within a group, tasks are ordered by priority and then by the order in
+// which they were added to the graph.
+type readyQueue struct {
+	groups     map[Resources]*taskHeap
+	priorities map[string]time.Duration
+	order      map[string]int
+	size       int
+}
+
+// readyTask is a task waiting in the ready queue
+type readyTask struct {
+	task     Task
+	priority time.Duration
+	order    int
+}
+
+// newReadyQueue creates an empty ready queue for tasks of the given graph
+func newReadyQueue(graph *Graph, priorities map[string]time.Duration) *readyQueue {
+	order := make(map[string]int, graph.Len())
+	for i, task := range graph.GetTasks() {
+		order[task.ID()] = i
+	}
+	return &readyQueue{
+		groups:     make(map[Resources]*taskHeap),
+		priorities: priorities,
+		order:      order,
+	}
+}
+
+// Len returns the number of ready tasks
+func (q *readyQueue) Len() int {
+	return q.size
+}
+
+// push adds a task that needs the given resources to the queue
+func (q *readyQueue) push(task Task, need Resources) {
+	group, exists := q.groups[need]
+	if !exists {
+		group = &taskHeap{}
+		q.groups[need] = group
+	}
+	heap.Push(group, readyTask{
+		task:     task,
+		priority: q.priorities[task.ID()],
+		order:    q.order[task.ID()],
+	})
+	q.size++
+}
+
+// pop removes and returns the highest priority task whose needs are accepted by fits
+func (q *readyQueue) pop(fits func(need Resources) bool) (Task, Resources, bool) {
+	var best *taskHeap
+	var bestNeed Resources
+	for need, group := range q.groups {
+		if group.Len() == 0 || !fits(need) {
+			continue
+		}
+		if best == nil || (*group)[0].before((*best)[0]) {
+			best = group
+			bestNeed = need
+		}
+	}
+	if best == nil {
+		return nil, Resources{}, false
+	}
+
+	q.size--
+	return heap.Pop(best).(readyTask).task, bestNeed, true
+}
+
+// before reports whether a task should run before another
+func (t readyTask) before(other readyTask) bool {
+	if t.priority != other.priority {
+		return t.priority > other.priority
+	}
+	return t.order < other.order
+}
+
+// taskHeap is a heap of ready tasks with the next task to run first
+type taskHeap []readyTask
+
+func (h taskHeap) Len() int {
+	return len(h)
+}
+
+func (h taskHeap) Less(i, j int) bool {
+	return h[i].before(h[j])
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *taskHeap) Push(x any) {
+	*h = append(*h, x.(readyTask))
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,93 @@
+package graph
+
+// DefaultNetworkSlots is the number of concurrent network transfers when no
+// network pool is configured
+const DefaultNetworkSlots = 8
+
+// fallbackMemoryMB is the memory pool when the physical memory of the machine
+// can't be determined
+const fallbackMemoryMB = 4096
+
+// DefaultMemoryMB returns the memory pool used when none is configured: three
+// quarters of the physical memory, leaving room for the system and fbs itself,
+// or 4096 MB where the physical memory can't be determined
+func DefaultMemoryMB() int {
+	if total := systemMemoryMB(); total > 0 {
+		return total * 3 / 4
+	}
+	return fallbackMemoryMB
+}
+
+// Resources describes what a task needs while it runs, or the capacity of the
+// pools that tasks are scheduled against
+type Resources struct {
+	// CPU is the number of CPU slots
+	CPU int
+	// MemoryMB is the memory in megabytes
+	MemoryMB int
+	// Network is the number of concurrent network transfers
+	Network int
+}
+
+// ResourceUser is implemented by tasks that declare their resource needs.
+// Tasks that don't implement it use a single CPU slot.
+type ResourceUser interface {
+	// Resources returns the resources the task needs while it runs
+	Resources() Resources
+}
+
+// TaskResources returns the resources a task needs while it runs
+func TaskResources(task Task) Resources {
+	if user, ok := task.(ResourceUser); ok {
+		return user.Resources()
+	}
+	return Resources{CPU: 1}
+}
+
+// resourcePool tracks the resources in use by running tasks against the
+// capacity of the pools. A capacity of zero means the resource is unlimited.
+type resourcePool struct {
+	capacity Resources
+	used     Resources
+}
+
+// clamp limits a task's needs to the capacity of the pools, so that a task
+// needing more than is available can still run on its own
+func (p *resourcePool) clamp(need Resources) Resources {
+	if p.capacity.CPU > 0 && need.CPU > p.capacity.CPU {
+		need.CPU = p.capacity.CPU
+	}
+	if p.capacity.MemoryMB > 0 && need.MemoryMB > p.capacity.MemoryMB {
+		need.MemoryMB = p.capacity.MemoryMB
+	}
+	if p.capacity.Network > 0 && need.Network > p.capacity.Network {
+		need.Network = p.capacity.Network
+	}
+	return need
+}
+
+// fits reports whether the pools have room for the needs of a task
+func (p *resourcePool) fits(need Resources) bool {
+	return fitsCapacity(p.capacity.CPU, p.used.CPU, need.CPU) &&
+		fitsCapacity(p.capacity.MemoryMB, p.used.MemoryMB, need.MemoryMB) &&
+		fitsCapacity(p.capacity.Network, p.used.Network, need.Network)
+}
+
+// acquire reserves resources for a task
+func (p *resourcePool) acquire(need Resources) {
+	p.used.CPU += need.CPU
+	p.used.MemoryMB += need.MemoryMB
+	p.used.Network += need.Network
+}
+
+// release returns the resources of a finished task to the pools
+func (p *resourcePool) release(need Resources) {
+	p.used.CPU -= need.CPU
+	p.used.MemoryMB -= need.MemoryMB
+	p.used.Network -= need.Network
+}
+
+// fitsCapacity reports whether need more units fit in a pool of the given capacity
+func fitsCapacity(capacity, used, need int) bool {
+	return capacity == 0 || used+need <= capacity
+}
@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// MockResourceTask is a mock task that declares its resource needs
+type MockResourceTask struct {
+	*MockTask
+	resources Resources
+}
+
+func (m *MockResourceTask) Resources() Resources {
+	return m.resources
+}
+
+func TestRunner_MemoryPool(t *testing.T) {
+	var running, maxRunning int32
+	graph := NewGraph()
+	for i := 0; i < 4; i++ {
+		task := NewMockTask(fmt.Sprintf("jvm-%d", i), "jvm", "/test", fmt.Sprintf("hash%d", i), nil)
+		task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				previous := atomic.LoadInt32(&maxRunning)
+				if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return TaskResult{}
+		}
+		graph.AddTask(&MockResourceTask{task, Resources{CPU: 1, MemoryMB: 600}})
+	}
+	
+	// An oversized task still runs on its own
+	graph.AddTask(&MockResourceTask{NewMockTask("huge", "jvm", "/test", "huge", nil), Resources{CPU: 1, MemoryMB: 5000}})
+	
+	runner := NewRunner(t.TempDir())
+	runner.SetResources(Resources{MemoryMB: 1000})
+	results, err := runner.ExecuteWithProgressParallel(context.Background(), graph, nil, 4)
+	if err != nil {
+		t.Fatalf("Failed to execute tasks: %v", err)
+	}
+	if len(results) != 5 {
+		t.Errorf("Expected 5 results, got %d", len(results))
+	}
+	if maxRunning != 1 {
+		t.Errorf("Expected at most one task within the memory pool at a time, got %d", maxRunning)
+	}
+}
+
+func TestRunner_NetworkTasksDontUseCPUSlots(t *testing.T) {
+	const downloads = 4
+	var started sync.WaitGroup
+	started.Add(downloads)
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+	
+	graph := NewGraph()
+	for i := 0; i < downloads; i++ {
+		task := NewMockTask(fmt.Sprintf("download-%d", i), "download", "/test", fmt.Sprintf("hash%d", i), nil)
+		task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+			// Every download waits for the others, so they must all run at once
+			started.Done()
+			select {
+			case <-allStarted:
+				return TaskResult{}
+			case <-time.After(5 * time.Second):
+				return TaskResult{Error: fmt.Errorf("downloads did not run concurrently")}
+			}
+		}
+		graph.AddTask(&MockResourceTask{task, Resources{Network: 1}})
+	}
+	
+	runner := NewRunner(t.TempDir())
+	runner.SetResources(Resources{Network: downloads})
+	if _, err := runner.ExecuteWithProgressParallel(context.Background(), graph, nil, 2); err != nil {
+		t.Fatalf("Failed to execute tasks: %v", err)
+	}
+}
+
+func TestTaskResources(t *testing.T) {
+	if resources := TaskResources(NewMockTask("A", "task", "/test", "hash", nil)); resources != (Resources{CPU: 1}) {
+		t.Errorf("Expected one CPU slot for tasks without declared resources, got %+v", resources)
+	}
+	
+	pool := &resourcePool{capacity: Resources{CPU: 2, MemoryMB: 1000}}
+	need := pool.clamp(Resources{CPU: 4, MemoryMB: 2000, Network: 3})
+	if need != (Resources{CPU: 2, MemoryMB: 1000, Network: 3}) {
+		t.Errorf("Unexpected clamped resources %+v", need)
+	}
+	pool.acquire(need)
+	if pool.fits(Resources{CPU: 1}) {
+		t.Error("Expected a full CPU pool to reject another task")
+	}
+	pool.release(need)
+	if !pool.fits(Resources{CPU: 1, MemoryMB: 500}) {
+		t.Error("Expected released resources to be available again")
+	}
+}
+
+func TestRunner_SetResourcesKeepsDefaults(t *testing.T) {
+	if DefaultMemoryMB() <= 0 {
+		t.Fatalf("Expected a finite default memory pool, got %d", DefaultMemoryMB())
+	}
+	
+	// Zero keeps the default of every pool
+	runner := NewRunner(t.TempDir())
+	runner.SetResources(Resources{})
+	if runner.resources != (Resources{MemoryMB: DefaultMemoryMB(), Network: DefaultNetworkSlots}) {
+		t.Errorf("Expected the default pools, got %+v", runner.resources)
+	}
+	
+	runner.SetResources(Resources{MemoryMB: 1000, Network: 2})
+	if runner.resources != (Resources{MemoryMB: 1000, Network: 2}) {
+		t.Errorf("Expected the configured pools, got %+v", runner.resources)
+	}
+}
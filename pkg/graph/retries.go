@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RetryPolicy describes how a failed task is executed again
+type RetryPolicy struct {
+	// Attempts is the maximum number of executions, including the first one
+	Attempts int
+	// Backoff is the delay before the first retry. It doubles for every further retry.
+	Backoff time.Duration
+	// MaxBackoff limits the delay between retries. Zero means no limit.
+	MaxBackoff time.Duration
+}
+
+// ShouldRetry reports whether a task that failed with err on the given attempt
+// should be executed again
+func (p RetryPolicy) ShouldRetry(attempt int, err error) bool {
+	var permanent *PermanentError
+	return attempt < p.Attempts && !errors.As(err, &permanent)
+}
+
+// Delay returns how long to wait after the given failed attempt
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// DefaultRetryKey is the key of the retry policy that applies to tasks without
+// a more specific policy
+const DefaultRetryKey = "default"
+
+// RetryPolicies maps task names (such as artifact-download) and task types
+// (build, test or deps) to their retry policy. A task name takes precedence
+// over its type, and DefaultRetryKey applies to every other task.
+type RetryPolicies map[string]RetryPolicy
+
+// DefaultRetryPolicies returns the policies that apply unless fbs.conf.json
+// overrides them. Downloads are retried since network failures are usually
+// transient.
+func DefaultRetryPolicies() RetryPolicies {
+	return RetryPolicies{
+		"artifact-download": {Attempts: 3, Backoff: time.Second, MaxBackoff: 10 * time.Second},
+	}
+}
+
+// For returns the retry policy of a task. Tasks without a policy are executed once.
+func (p RetryPolicies) For(task Task) RetryPolicy {
+	for _, key := range []string{task.Name(), string(task.TaskType()), DefaultRetryKey} {
+		if policy, exists := p[key]; exists {
+			return policy
+		}
+	}
+	return RetryPolicy{Attempts: 1}
+}
+
+// PermanentError marks a task error that retrying won't fix, such as an
+// artifact that doesn't exist in any repository
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps err so that the task failing with it is not retried
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// sleepContext waits for the given duration and reports whether it wasn't
+// interrupted by the context being cancelled
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
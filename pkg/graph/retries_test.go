@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newFlakyTask creates a task that fails the given number of times before it succeeds
+func newFlakyTask(id string, failures int, executions *int) *MockTask {
+	task := NewMockTask(id, "junit-test", "/test", "hash-"+id, nil)
+	task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+		*executions++
+		if *executions <= failures {
+			// Leftovers of a failed attempt must not reach the next attempt
+			os.WriteFile(filepath.Join(workDir, "partial.txt"), []byte("partial"), 0644)
+			return TaskResult{Error: fmt.Errorf("attempt %d failed", *executions)}
+		}
+		if _, err := os.Stat(filepath.Join(workDir, "partial.txt")); err == nil {
+			return TaskResult{Error: errors.New("work directory was not cleaned")}
+		}
+		os.WriteFile(filepath.Join(workDir, id+".txt"), []byte(id), 0644)
+		return TaskResult{Files: []string{id + ".txt"}}
+	}
+	return task
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{Attempts: 5, Backoff: time.Second, MaxBackoff: 3 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, delay := range expected {
+		if policy.Delay(i+1) != delay {
+			t.Errorf("Expected delay %v after attempt %d, got %v", delay, i+1, policy.Delay(i+1))
+		}
+	}
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	policy := RetryPolicy{Attempts: 2}
+	if !policy.ShouldRetry(1, errors.New("connection reset")) {
+		t.Error("Expected the first failure to be retried")
+	}
+	if policy.ShouldRetry(2, errors.New("connection reset")) {
+		t.Error("Expected no retry after the last attempt")
+	}
+	if policy.ShouldRetry(1, fmt.Errorf("download failed: %w", Permanent(errors.New("HTTP 404")))) {
+		t.Error("Expected permanent errors not to be retried")
+	}
+
+	policies := RetryPolicies{"build": {Attempts: 3}}
+	task := NewMockTask("A", "kotlin-compile", "/test", "hashA", nil)
+	if policies.For(task).Attempts != 3 {
+		t.Errorf("Expected the task type policy, got %+v", policies.For(task))
+	}
+	if (RetryPolicies{}).For(task).Attempts != 1 {
+		t.Error("Expected a single attempt without configuration")
+	}
+}
+
+func TestRunner_RetryMarksFlaky(t *testing.T) {
+	executions := 0
+	graph := NewGraph()
+	graph.AddTask(newFlakyTask("flaky", 2, &executions))
+
+	var buffer bytes.Buffer
+	writer := NewEventWriter(&buffer)
+	runner := NewRunner(t.TempDir())
+	runner.SetRetries(RetryPolicies{"junit-test": {Attempts: 3, Backoff: time.Millisecond}})
+	runner.AddEventHandler(writer.Handle)
+
+	results, err := runner.Execute(context.Background(), graph)
+	if err != nil {
+		t.Fatalf("Expected the retried task to succeed, got %v", err)
+	}
+	if executions != 3 || !results[0].Flaky || results[0].Attempts != 3 {
+		t.Errorf("Expected a flaky result after 3 attempts, got %d executions and %+v", executions, results[0])
+	}
+
+	types, last := decodeEvents(t, buffer.Bytes())
+	assertEventTypes(t, types, "flaky", EventTaskQueued, EventTaskStarted, EventTaskRetrying, EventTaskRetrying, EventTaskSucceeded)
+	if !last["flaky"].Flaky || last["flaky"].Attempt != 3 {
+		t.Errorf("Expected the final event to be marked flaky, got %+v", last["flaky"])
+	}
+}
+
+func TestRunner_RetryGivesUp(t *testing.T) {
+	executions := 0
+	graph := NewGraph()
+	graph.AddTask(newFlakyTask("broken", 5, &executions))
+
+	runner := NewRunner(t.TempDir())
+	runner.SetRetries(RetryPolicies{DefaultRetryKey: {Attempts: 2, Backoff: time.Millisecond}})
+	results, err := runner.Execute(context.Background(), graph)
+	if err == nil {
+		t.Fatal("Expected the task to fail")
+	}
+	if executions != 2 || results[0].Flaky || results[0].Attempts != 2 {
+		t.Errorf("Expected a failed result after 2 attempts, got %d executions and %+v", executions, results[0])
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 // ExecutionResult represents the result of executing a task with its output location
@@ -14,7 +16,37 @@ type ExecutionResult struct {
 	TaskHash   string
 	OutputDir  string
 	Result     TaskResult
-	CacheHit   bool // Whether this result came from cache
+	CacheHit   bool          // Whether this result came from cache
+	RemoteHit  bool          // Whether the cached result was fetched from the remote cache
+	Skipped    bool          // Whether the task was skipped because a dependency failed
+	StartTime  time.Time     // When the task started executing or restoring
+	Duration   time.Duration // Wall-clock time spent executing or restoring the task
+	Worker     int           // Index of the worker that ran the task
+	Attempts   int           // Number of times the task was executed
+	Flaky      bool          // Whether the task succeeded only after being retried
+	LogPath    string        // Log of the task's output, kept only when the task failed
+	CacheError error         // Non-fatal remote cache error encountered for this task
+}
+
+// BuildError is returned in keep-going mode when one or more tasks failed
+type BuildError struct {
+	// Failures contains the results of all failed tasks
+	Failures []ExecutionResult
+	// Skipped is the number of tasks that were not run because a dependency failed
+	Skipped int
+}
+
+// Error lists every failed task with its error
+func (e *BuildError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d task(s) failed", len(e.Failures))
+	if e.Skipped > 0 {
+		fmt.Fprintf(&b, ", %d skipped", e.Skipped)
+	}
+	for _, failure := range e.Failures {
+		fmt.Fprintf(&b, "\n  %s (%s): %v", failure.Task.DisplayName(), failure.Task.Directory(), failure.Result.Error)
+	}
+	return b.String()
 }
 
 // ProgressCallback is called when task execution status changes
@@ -22,13 +54,104 @@ type ProgressCallback func(task Task, status string, finished bool, cached bool)
 
 // Runner executes tasks in a graph
 type Runner struct {
-	resultDir string
+	resultDir   string
+	localCache  *LocalCache
+	remoteCache CacheBackend
+	uploadCache bool
+	keepGoing   bool
+	logStore    *LogStore
+	durations   *DurationHistory
+	resources   Resources
+	timeouts    Timeouts
+	retries     RetryPolicies
+	sandbox     bool
+	
+	eventHandlers []EventHandler
+	eventMu       sync.Mutex
 }
 
 // NewRunner creates a new runner that stores results in the specified directory
 func NewRunner(resultDir string) *Runner {
 	return &Runner{
-		resultDir: resultDir,
+		resultDir:  resultDir,
+		localCache: NewLocalCache(resultDir),
+		resources:  Resources{MemoryMB: DefaultMemoryMB(), Network: DefaultNetworkSlots},
+	}
+}
+
+// SetRemoteCache configures a remote cache that is consulted when a result is not
+// in the local result directory. If upload is true, results of executed tasks
+// are stored in the remote cache as well.
+func (r *Runner) SetRemoteCache(cache CacheBackend, upload bool) {
+	r.remoteCache = cache
+	r.uploadCache = upload
+}
+
+// SetKeepGoing configures whether execution continues after a task fails.
+// In keep-going mode every task whose dependencies succeeded is run, dependents
+// of failed tasks are skipped, and a BuildError listing all failures is returned.
+func (r *Runner) SetKeepGoing(keepGoing bool) {
+	r.keepGoing = keepGoing
+}
+
+// SetLogStore configures where the output of executed tasks is logged.
+// Logs of failed tasks are kept so that they can be shown after the build.
+func (r *Runner) SetLogStore(store *LogStore) {
+	r.logStore = store
+}
+
+// SetDurationHistory configures where task durations are recorded and read
+// from to schedule the tasks on the longest remaining path first
+func (r *Runner) SetDurationHistory(history *DurationHistory) {
+	r.durations = history
+}
+
+// SetResources configures the memory and network pools that parallel tasks are
+// scheduled against. The CPU pool is the number of parallel workers. A capacity
+// of zero keeps the default of the pool, DefaultMemoryMB or DefaultNetworkSlots.
+func (r *Runner) SetResources(pools Resources) {
+	if pools.MemoryMB > 0 {
+		r.resources.MemoryMB = pools.MemoryMB
+	}
+	if pools.Network > 0 {
+		r.resources.Network = pools.Network
+	}
+}
+
+// SetTimeouts configures how long tasks may run before they are cancelled and fail
+func (r *Runner) SetTimeouts(timeouts Timeouts) {
+	r.timeouts = timeouts
+}
+
+// SetRetries configures how often failed tasks are executed again before they fail the build
+func (r *Runner) SetRetries(retries RetryPolicies) {
+	r.retries = retries
+}
+
+// SetSandbox configures whether tasks run in sandbox mode, where each task
+// only sees its declared inputs and the outputs of its dependencies, staged
+// in its work directory. Its tools run in the sandbox with a HOME of their
+// own, and reading an input or putting a classpath entry on the command line
+// that wasn't staged fails the task.
+func (r *Runner) SetSandbox(enabled bool) {
+	r.sandbox = enabled
+}
+
+// AddEventHandler registers a handler that receives an event for every task state change
+func (r *Runner) AddEventHandler(handler EventHandler) {
+	r.eventHandlers = append(r.eventHandlers, handler)
+}
+
+// emit delivers an event to all registered handlers, one event at a time
+func (r *Runner) emit(event Event) {
+	if len(r.eventHandlers) == 0 {
+		return
+	}
+	
+	r.eventMu.Lock()
+	defer r.eventMu.Unlock()
+	for _, handler := range r.eventHandlers {
+		handler(event)
 	}
 }
 
@@ -59,9 +182,13 @@ func (r *Runner) executeSequential(ctx context.Context, graph *Graph, progressCa
 	if err != nil {
 		return nil, fmt.Errorf("failed to sort tasks: %w", err)
 	}
+	hashes := ComputeTaskHashes(orderedTasks)
 	
 	var results []ExecutionResult
+	var failures []ExecutionResult
+	skippedCount := 0
 	executedTasks := make(map[string]ExecutionResult)
+	unsuccessful := make(map[string]bool) // IDs of failed and skipped tasks
 	
 	for _, task := range orderedTasks {
 		// Check if context is cancelled
@@ -71,13 +198,19 @@ func (r *Runner) executeSequential(ctx context.Context, graph *Graph, progressCa
 		default:
 		}
 		
-		// Notify progress callback that task is starting
-		if progressCallback != nil {
-			progressCallback(task, "running", false, false)
+		// Skip tasks that depend on a failed or skipped task
+		if hasUnsuccessfulDependency(task, unsuccessful) {
+			result := r.skipTask(task, hashes[task.ID()], progressCallback)
+			results = append(results, result)
+			unsuccessful[task.ID()] = true
+			skippedCount++
+			continue
 		}
 		
+		r.emit(newTaskEvent(EventTaskQueued, task))
+		
 		// Execute task
-		result, err := r.executeTask(ctx, task, executedTasks)
+		result, err := r.runTask(ctx, task, hashes[task.ID()], executedTasks, progressCallback)
 		if err != nil {
 			return results, fmt.Errorf("failed to execute task %s: %w", task.ID(), err)
 		}
@@ -85,72 +218,115 @@ func (r *Runner) executeSequential(ctx context.Context, graph *Graph, progressCa
 		results = append(results, result)
 		executedTasks[task.ID()] = result
 		
-		// Notify progress callback that task is finished
-		if progressCallback != nil {
-			status := "completed"
-			if result.Result.Error != nil {
-				status = "failed"
-			}
-			progressCallback(task, status, true, result.CacheHit)
-		}
-		
-		// Stop execution if task failed
+		// Stop execution if task failed, unless in keep-going mode
 		if result.Result.Error != nil {
-			return results, fmt.Errorf("task %s failed: %w", task.ID(), result.Result.Error)
+			if !r.keepGoing {
+				return results, fmt.Errorf("task %s (%s) failed: %w", task.DisplayName(), task.Directory(), result.Result.Error)
+			}
+			failures = append(failures, result)
+			unsuccessful[task.ID()] = true
 		}
 	}
 	
+	if len(failures) > 0 {
+		return results, &BuildError{Failures: failures, Skipped: skippedCount}
+	}
+	
 	return results, nil
 }
 
-// executeParallel runs tasks in parallel using worker goroutines
+// executeParallel runs tasks in parallel using worker goroutines. A task is
+// handed to a worker once its dependencies completed and the resource pools
+// have room for it, with the CPU pool holding one slot per worker.
 func (r *Runner) executeParallel(ctx context.Context, graph *Graph, progressCallback ProgressCallback, parallelWorkers int) ([]ExecutionResult, error) {
+	// Reject cycles and missing dependencies up front, since tasks waiting on them would never be queued
+	orderedTasks, err := graph.TopologicalSort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sort tasks: %w", err)
+	}
+	
 	allTasks := graph.GetTasks()
+	hashes := ComputeTaskHashes(allTasks)
 	
-	// Track task dependencies and completion status
-	taskDeps := make(map[string]map[string]bool) // taskID -> set of dependency task IDs
-	taskInDegree := make(map[string]int)         // taskID -> number of uncompleted dependencies
+	// On every return, cancel the tasks that are still running and wait for the
+	// workers, so no process outlives the build and temporary directories are removed
+	ctx, cancel := context.WithCancel(ctx)
+	var workerGroup sync.WaitGroup
+	defer func() {
+		cancel()
+		workerGroup.Wait()
+	}()
 	
-	// Initialize dependency tracking
+	// Prioritize tasks by their longest remaining path, using the durations of
+	// previous runs where they are known
+	history := r.durations
+	if history == nil {
+		history = NewDurationHistory("")
+	}
+	ready := newReadyQueue(graph, criticalPathPriorities(graph, orderedTasks, history))
+	
+	// Track the number of uncompleted dependencies of each task
+	taskInDegree := make(map[string]int, len(allTasks))
 	for _, task := range allTasks {
-		taskID := task.ID()
-		deps := make(map[string]bool)
-		for _, dep := range task.Dependencies() {
-			deps[dep.ID()] = true
-		}
-		taskDeps[taskID] = deps
-		taskInDegree[taskID] = len(deps)
+		taskInDegree[task.ID()] = len(graph.Edges(task.ID()))
 	}
 	
-	// Channels for communication
-	taskQueue := make(chan Task, len(allTasks))
+	// Tasks that need no CPU, such as downloads, run on additional workers
+	pool := &resourcePool{capacity: r.resources}
+	pool.capacity.CPU = parallelWorkers
+	workers := parallelWorkers + pool.capacity.Network
+	
+	// Channels for communication. Tasks are only handed to the workers when one
+	// is idle, so the ready queue decides which task runs next.
+	taskQueue := make(chan Task, workers)
 	resultChan := make(chan ExecutionResult, len(allTasks))
-	errorChan := make(chan error, parallelWorkers)
+	errorChan := make(chan error, workers)
 	
 	// Shared executed tasks map with mutex for thread safety
 	executedTasks := &SafeExecutedTasks{
-		tasks: make(map[string]ExecutionResult),
+		tasks: make(map[string]ExecutionResult, len(allTasks)),
 	}
 	
 	// Add tasks with no dependencies to the initial queue
 	for _, task := range allTasks {
 		if taskInDegree[task.ID()] == 0 {
-			select {
-			case taskQueue <- task:
-			case <-ctx.Done():
-				return nil, ctx.Err()
+			r.emit(newTaskEvent(EventTaskQueued, task))
+			ready.push(task, pool.clamp(TaskResources(task)))
+		}
+	}
+	
+	// Hand the highest priority ready tasks that fit in the resource pools to
+	// idle workers. Lower priority tasks may start ahead of a task that doesn't
+	// fit, so smaller tasks fill the pools while a large task waits for room.
+	running := make(map[string]Resources)
+	dispatch := func() {
+		for len(running) < workers {
+			task, need, ok := ready.pop(pool.fits)
+			if !ok {
+				break
 			}
+			pool.acquire(need)
+			running[task.ID()] = need
+			taskQueue <- task
 		}
 	}
+	dispatch()
 	
 	// Start worker goroutines
-	for i := 0; i < parallelWorkers; i++ {
-		go r.workerParallel(ctx, taskQueue, resultChan, errorChan, progressCallback, executedTasks)
+	workerGroup.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(worker int) {
+			defer workerGroup.Done()
+			r.workerParallel(ctx, worker, taskQueue, resultChan, errorChan, progressCallback, executedTasks, hashes)
+		}(i)
 	}
 	
 	// Collect results and manage task queue
 	var results []ExecutionResult
+	var failures []ExecutionResult
+	skippedCount := 0
 	completedCount := 0
+	blocked := make(map[string]bool) // IDs of tasks with a failed or skipped dependency
 	
 	for completedCount < len(allTasks) {
 		select {
@@ -163,40 +339,124 @@ func (r *Runner) executeParallel(ctx context.Context, graph *Graph, progressCall
 			results = append(results, result)
 			executedTasks.Set(result.Task.ID(), result)
 			completedCount++
+			pool.release(running[result.Task.ID()])
+			delete(running, result.Task.ID())
 			
-			// Stop execution if task failed
+			// Stop execution if task failed, unless in keep-going mode
 			if result.Result.Error != nil {
-				return results, fmt.Errorf("task %s failed: %w", result.Task.ID(), result.Result.Error)
+				if !r.keepGoing {
+					return results, fmt.Errorf("task %s (%s) failed: %w", result.Task.DisplayName(), result.Task.Directory(), result.Result.Error)
+				}
+				failures = append(failures, result)
 			}
 			
-			// Update dependency counts and queue newly available tasks
-			completedTaskID := result.Task.ID()
-			for _, task := range allTasks {
-				taskID := task.ID()
-				if deps, exists := taskDeps[taskID]; exists {
-					if deps[completedTaskID] {
-						// This task was waiting for the completed task
-						taskInDegree[taskID]--
-						if taskInDegree[taskID] == 0 {
-							// All dependencies are now complete, queue this task
-							select {
-							case taskQueue <- task:
-							case <-ctx.Done():
-								return results, ctx.Err()
-							}
-						}
+			// Update the dependency counts of the dependents of the completed task
+			// and queue newly available tasks. Tasks that depend on a failed task
+			// are skipped, which in turn completes them.
+			finished := []ExecutionResult{result}
+			for len(finished) > 0 {
+				completed := finished[0]
+				finished = finished[1:]
+				completedOK := completed.Result.Error == nil && !completed.Skipped
+				
+				for _, task := range graph.Dependents(completed.Task.ID()) {
+					taskID := task.ID()
+					if !completedOK {
+						blocked[taskID] = true
+					}
+					taskInDegree[taskID]--
+					if taskInDegree[taskID] > 0 {
+						continue
+					}
+					
+					if blocked[taskID] {
+						skipped := r.skipTask(task, hashes[taskID], progressCallback)
+						results = append(results, skipped)
+						completedCount++
+						skippedCount++
+						finished = append(finished, skipped)
+						continue
 					}
+					
+					// All dependencies are now complete, queue this task
+					r.emit(newTaskEvent(EventTaskQueued, task))
+					ready.push(task, pool.clamp(TaskResources(task)))
 				}
 			}
+			dispatch()
 		}
 	}
 	
 	// Close the task queue to signal workers to stop
 	close(taskQueue)
 	
+	if len(failures) > 0 {
+		return results, &BuildError{Failures: failures, Skipped: skippedCount}
+	}
+	
 	return results, nil
 }
 
+// runTask executes a single task, reporting its start and outcome to the
+// progress callback and event handlers
+func (r *Runner) runTask(ctx context.Context, task Task, taskHash string, executedTasks map[string]ExecutionResult, progressCallback ProgressCallback) (ExecutionResult, error) {
+	if progressCallback != nil {
+		progressCallback(task, "running", false, false)
+	}
+	r.emit(newTaskEvent(EventTaskStarted, task))
+	
+	startTime := time.Now()
+	result, err := r.executeTask(ctx, task, taskHash, executedTasks)
+	if err != nil {
+		return result, err
+	}
+	result.StartTime = startTime
+	result.Duration = time.Since(startTime)
+	
+	// Remember how long the task took to schedule it better next time
+	if r.durations != nil && !result.CacheHit && !result.RemoteHit && result.Result.Error == nil {
+		r.durations.Record(task, result.Duration)
+	}
+	
+	if progressCallback != nil {
+		status := "completed"
+		if result.Result.Error != nil {
+			status = "failed"
+		}
+		progressCallback(task, status, true, result.CacheHit)
+	}
+	r.emit(newResultEvent(result))
+	
+	return result, nil
+}
+
+// skipTask creates the result for a task that is not run because a dependency
+// failed and reports it to the progress callback and event handlers
+func (r *Runner) skipTask(task Task, taskHash string, progressCallback ProgressCallback) ExecutionResult {
+	result := ExecutionResult{
+		Task:     task,
+		TaskHash: taskHash,
+		Skipped:  true,
+	}
+	
+	if progressCallback != nil {
+		progressCallback(task, "skipped", true, false)
+	}
+	r.emit(newResultEvent(result))
+	
+	return result
+}
+
+// hasUnsuccessfulDependency reports whether any direct dependency of the task failed or was skipped
+func hasUnsuccessfulDependency(task Task, unsuccessful map[string]bool) bool {
+	for _, dep := range task.Dependencies() {
+		if unsuccessful[dep.ID()] {
+			return true
+		}
+	}
+	return false
+}
+
 // SafeExecutedTasks provides thread-safe access to executed tasks
 type SafeExecutedTasks struct {
 	tasks map[string]ExecutionResult
@@ -209,6 +469,21 @@ func (s *SafeExecutedTasks) Set(taskID string, result ExecutionResult) {
 	s.tasks[taskID] = result
 }
 
+// Dependencies returns the results of the direct dependencies of a task that have completed
+func (s *SafeExecutedTasks) Dependencies(task Task) map[string]ExecutionResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	deps := task.Dependencies()
+	result := make(map[string]ExecutionResult, len(deps))
+	for _, dep := range deps {
+		if depResult, exists := s.tasks[dep.ID()]; exists {
+			result[dep.ID()] = depResult
+		}
+	}
+	return result
+}
+
 func (s *SafeExecutedTasks) ToMap() map[string]ExecutionResult {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -222,7 +497,7 @@ func (s *SafeExecutedTasks) ToMap() map[string]ExecutionResult {
 }
 
 // workerParallel executes tasks from the queue with access to shared executed tasks
-func (r *Runner) workerParallel(ctx context.Context, taskQueue <-chan Task, resultChan chan<- ExecutionResult, errorChan chan<- error, progressCallback ProgressCallback, executedTasks *SafeExecutedTasks) {
+func (r *Runner) workerParallel(ctx context.Context, worker int, taskQueue <-chan Task, resultChan chan<- ExecutionResult, errorChan chan<- error, progressCallback ProgressCallback, executedTasks *SafeExecutedTasks, hashes map[string]string) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -232,15 +507,11 @@ func (r *Runner) workerParallel(ctx context.Context, taskQueue <-chan Task, resu
 				return // Channel closed, worker should exit
 			}
 			
-			// Process the task
-			if progressCallback != nil {
-				progressCallback(task, "running", false, false)
-			}
-			
-			// Get current executed tasks for dependency resolution
-			currentExecutedTasks := executedTasks.ToMap()
+			// Get the results of the task's dependencies, copying only what the task needs
+			dependencyResults := executedTasks.Dependencies(task)
 			
-			result, err := r.executeTask(ctx, task, currentExecutedTasks)
+			// Process the task
+			result, err := r.runTask(ctx, task, hashes[task.ID()], dependencyResults, progressCallback)
 			if err != nil {
 				select {
 				case errorChan <- fmt.Errorf("failed to execute task %s: %w", task.ID(), err):
@@ -248,15 +519,7 @@ func (r *Runner) workerParallel(ctx context.Context, taskQueue <-chan Task, resu
 				}
 				return
 			}
-			
-			// Notify progress callback
-			if progressCallback != nil {
-				status := "completed"
-				if result.Result.Error != nil {
-					status = "failed"
-				}
-				progressCallback(task, status, true, result.CacheHit)
-			}
+			result.Worker = worker
 			
 			// Send result
 			select {
@@ -269,9 +532,10 @@ func (r *Runner) workerParallel(ctx context.Context, taskQueue <-chan Task, resu
 }
 
 // executeTask executes a single task and stores its results
-func (r *Runner) executeTask(ctx context.Context, task Task, executedTasks map[string]ExecutionResult) (ExecutionResult, error) {
-	// Compute task hash including dependencies
-	taskHash := ComputeTaskHash(task)
+func (r *Runner) executeTask(ctx context.Context, task Task, taskHash string, executedTasks map[string]ExecutionResult) (ExecutionResult, error) {
+	// ABI consumers are cached by the ABI fingerprints of their dependencies,
+	// which are only known now
+	taskHash = executionHash(task, taskHash, executedTasks)
 	
 	// Create output directory for this task
 	outputDir := filepath.Join(r.resultDir, taskHash)
@@ -280,10 +544,31 @@ func (r *Runner) executeTask(ctx context.Context, task Task, executedTasks map[s
 	if r.isCached(outputDir) {
 		// Load cached result
 		cachedResult, err := r.loadCachedResult(task, taskHash, outputDir)
+		if err == nil {
+			// The next change builds on the result that is checked out now, which
+			// is best effort like the access tracking
+			r.recordOutput(task, taskHash)
+			return cachedResult, nil
+		}
+		// An unreadable entry is treated as a miss and replaced below
+	}
+	
+	// Try the remote cache before executing the task
+	var remoteErr error
+	if r.remoteCache != nil {
+		fetched, err := r.fetchRemote(ctx, taskHash, outputDir)
 		if err != nil {
-			return ExecutionResult{}, fmt.Errorf("failed to load cached result for task %s: %w", task.ID(), err)
+			// Remote cache failures are not fatal, the task is executed instead
+			remoteErr = fmt.Errorf("%s cache fetch failed: %w", r.remoteCache.Name(), err)
+		} else if fetched {
+			cachedResult, err := r.loadCachedResult(task, taskHash, outputDir)
+			if err != nil {
+				return ExecutionResult{}, fmt.Errorf("failed to load cached result for task %s: %w", task.ID(), err)
+			}
+			cachedResult.RemoteHit = true
+			r.recordOutput(task, taskHash)
+			return cachedResult, nil
 		}
-		return cachedResult, nil
 	}
 	
 	// Create temporary directory for task execution
@@ -305,82 +590,273 @@ func (r *Runner) executeTask(ctx context.Context, task Task, executedTasks map[s
 			TaskID:    dep.ID(),
 			OutputDir: depResult.OutputDir,
 			Files:     depResult.Result.Files,
+			ABI:       depResult.Result.ABI,
 		})
 	}
 	
-	// Execute the task in the temporary directory
-	taskResult := task.Execute(ctx, tempDir, dependencyInputs)
+	// Capture the output of the task in its log file
+	var logFile *os.File
+	if r.logStore != nil {
+		logFile, err = r.logStore.create(taskHash)
+		if err != nil {
+			return ExecutionResult{}, fmt.Errorf("failed to create task log: %w", err)
+		}
+		defer logFile.Close()
+		ctx = WithTaskLog(ctx, logFile)
+	}
+	
+	// Incremental tasks build on their previous result, unless the sandbox
+	// requires them to see nothing but their declared inputs
+	if !r.sandbox {
+		if previous := r.previousOutput(task); previous != "" {
+			ctx = WithPreviousOutput(ctx, previous)
+		}
+	}
+	
+	// Execute the task in the temporary directory, retrying failures as configured
+	policy := r.retries.For(task)
+	startTime := time.Now()
+	attempt := 1
+	taskResult := r.executeAttempt(ctx, task, tempDir, dependencyInputs)
+	for taskResult.Error != nil && ctx.Err() == nil && policy.ShouldRetry(attempt, taskResult.Error) {
+		delay := policy.Delay(attempt)
+		attempt++
+		
+		event := newTaskEvent(EventTaskRetrying, task)
+		event.Attempt = attempt
+		event.Error = taskResult.Error.Error()
+		r.emit(event)
+		fmt.Fprintf(TaskLog(ctx), "\n--- attempt %d of %d failed: %v, retrying in %s ---\n\n", attempt-1, policy.Attempts, taskResult.Error, delay)
+		
+		if !sleepContext(ctx, delay) {
+			break
+		}
+		
+		// Start every attempt from an empty work directory
+		if err := os.RemoveAll(tempDir); err != nil {
+			return ExecutionResult{}, fmt.Errorf("failed to clean temp directory: %w", err)
+		}
+		if err := os.MkdirAll(tempDir, 0755); err != nil {
+			return ExecutionResult{}, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		taskResult = r.executeAttempt(ctx, task, tempDir, dependencyInputs)
+	}
+	duration := time.Since(startTime)
+	
+	// A cancelled build leaves neither a result nor a log behind
+	if ctx.Err() != nil {
+		if logFile != nil {
+			logFile.Close()
+			r.logStore.discard(taskHash)
+		}
+		return ExecutionResult{}, ctx.Err()
+	}
 	
-	// Only move to cache if the task succeeded
+	// Keep the log only if the task failed, and drop the logs of earlier runs either way
+	logPath := ""
+	if logFile != nil {
+		if taskResult.Error != nil {
+			if err := r.logStore.keep(task, taskHash, taskResult.Error); err != nil {
+				return ExecutionResult{}, fmt.Errorf("failed to save task log: %w", err)
+			}
+			logPath = logFile.Name()
+		} else {
+			logFile.Close()
+			r.logStore.discard(taskHash)
+			if err := r.logStore.discardEarlier(task, taskHash); err != nil {
+				return ExecutionResult{}, fmt.Errorf("failed to discard task logs: %w", err)
+			}
+		}
+	}
+	
+	// Only commit to cache if the task succeeded
 	if taskResult.Error == nil {
-		// Create the final cache directory
-		err := os.MkdirAll(outputDir, 0755)
+		committed, err := r.commitResult(task, taskHash, tempDir, outputDir, taskResult.ABI, duration)
 		if err != nil {
-			return ExecutionResult{}, fmt.Errorf("failed to create cache directory %s: %w", outputDir, err)
+			return ExecutionResult{}, fmt.Errorf("failed to commit task results to cache: %w", err)
+		}
+		if committed {
+			if err := r.recordOutput(task, taskHash); err != nil {
+				return ExecutionResult{}, err
+			}
 		}
 		
-		// Move contents from temp directory to cache directory
-		err = r.moveTempToCache(tempDir, outputDir)
-		if err != nil {
-			return ExecutionResult{}, fmt.Errorf("failed to move temp results to cache: %w", err)
+		// Share the result through the remote cache
+		if committed && r.remoteCache != nil && r.uploadCache && remoteErr == nil {
+			if err := r.remoteCache.Store(ctx, taskHash, outputDir); err != nil {
+				remoteErr = fmt.Errorf("%s cache upload failed: %w", r.remoteCache.Name(), err)
+			}
 		}
 	}
 	// If task failed, temp directory will be cleaned up by defer
 	
 	return ExecutionResult{
-		Task:      task,
-		TaskHash:  taskHash,
-		OutputDir: outputDir,
-		Result:    taskResult,
-		CacheHit:  false,
+		Task:       task,
+		TaskHash:   taskHash,
+		OutputDir:  outputDir,
+		Result:     taskResult,
+		CacheHit:   false,
+		LogPath:    logPath,
+		CacheError: remoteErr,
+		Attempts:   attempt,
+		Flaky:      taskResult.Error == nil && attempt > 1,
 	}, nil
 }
 
-// isCached checks if a cached result exists for the given output directory
-func (r *Runner) isCached(outputDir string) bool {
-	// Check if the output directory exists and is not empty
-	if info, err := os.Stat(outputDir); err != nil || !info.IsDir() {
-		return false
+// executeAttempt executes a task once, giving it at most its configured time
+func (r *Runner) executeAttempt(ctx context.Context, task Task, workDir string, dependencyInputs []DependencyInput) TaskResult {
+	// Stage the inputs for this attempt, and remove them before the outputs are committed
+	if r.sandbox {
+		sandbox, stagedInputs, err := newSandbox(task, workDir, dependencyInputs)
+		if err != nil {
+			return TaskResult{Error: fmt.Errorf("failed to prepare sandbox: %w", err)}
+		}
+		defer sandbox.remove()
+		
+		ctx = WithSandbox(ctx, sandbox)
+		dependencyInputs = stagedInputs
+	}
+	
+	timeout := r.timeouts.For(task)
+	if timeout <= 0 {
+		return task.Execute(ctx, workDir, dependencyInputs)
+	}
+	
+	taskCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	
+	taskResult := task.Execute(taskCtx, workDir, dependencyInputs)
+	
+	// Report a hung task as timed out rather than with the error of the killed process
+	if ctx.Err() == nil && taskCtx.Err() == context.DeadlineExceeded {
+		taskResult = TaskResult{Error: &TimeoutError{Timeout: timeout}}
+	}
+	return taskResult
+}
+
+// commitResult moves the outputs of a task into a staging directory, writes
+// the entry's manifest and renames the staging directory into place, so that
+// an interrupted run never leaves a partially written entry behind.
+// Tasks that produce no files in their work directory are not cached.
+func (r *Runner) commitResult(task Task, taskHash, tempDir, outputDir, abi string, duration time.Duration) (bool, error) {
+	if !dirHasEntries(tempDir) {
+		return false, nil
+	}
+	
+	if err := os.MkdirAll(r.resultDir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create result directory: %w", err)
 	}
 	
-	// Check if directory has any files
-	entries, err := os.ReadDir(outputDir)
+	// Stage next to the final entry so the rename stays on one filesystem
+	stagingDir, err := os.MkdirTemp(r.resultDir, ".stage-")
 	if err != nil {
-		return false
+		return false, fmt.Errorf("failed to create staging directory: %w", err)
 	}
+	defer os.RemoveAll(stagingDir)
 	
-	return len(entries) > 0
+	if err := r.moveTempToCache(tempDir, stagingDir); err != nil {
+		return false, fmt.Errorf("failed to move temp results to staging directory: %w", err)
+	}
+	
+	manifest, err := newResultManifest(task, taskHash, stagingDir, abi, duration)
+	if err != nil {
+		return false, err
+	}
+	if err := writeManifest(stagingDir, manifest); err != nil {
+		return false, err
+	}
+	
+	if err := r.installEntry(stagingDir, outputDir); err != nil {
+		return false, err
+	}
+	
+	return true, nil
 }
 
-// loadCachedResult loads a cached result from the output directory
-func (r *Runner) loadCachedResult(task Task, taskHash, outputDir string) (ExecutionResult, error) {
-	// Walk the output directory to find all files (including subdirectories)
-	var files []string
-	err := filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// installEntry renames a complete staging directory to its final location,
+// replacing any incomplete entry that may be there
+func (r *Runner) installEntry(stagingDir, outputDir string) error {
+	if !r.isCached(outputDir) {
+		if err := os.RemoveAll(outputDir); err != nil {
+			return fmt.Errorf("failed to remove incomplete cache entry %s: %w", outputDir, err)
 		}
-		if !info.IsDir() {
-			// Get relative path from the output directory
-			relPath, err := filepath.Rel(outputDir, path)
-			if err != nil {
-				return err
-			}
-			files = append(files, relPath)
+	}
+	
+	if err := os.Rename(stagingDir, outputDir); err != nil {
+		// Another worker may have committed the same entry in the meantime
+		if r.isCached(outputDir) {
+			return nil
 		}
-		return nil
-	})
+		return fmt.Errorf("failed to commit cache entry %s: %w", outputDir, err)
+	}
 	
+	return nil
+}
+
+// fetchRemote restores a result from the remote cache into outputDir
+func (r *Runner) fetchRemote(ctx context.Context, taskHash, outputDir string) (bool, error) {
+	if err := os.MkdirAll(r.resultDir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create result directory: %w", err)
+	}
+	
+	// Fetch into a staging directory next to the result so a failed
+	// download never leaves a partial entry behind
+	stagingDir, err := os.MkdirTemp(r.resultDir, ".fetch-")
+	if err != nil {
+		return false, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+	
+	fetched, err := r.remoteCache.Fetch(ctx, taskHash, stagingDir)
+	if err != nil || !fetched {
+		return false, err
+	}
+	
+	// Entries without a manifest are incomplete and treated as a miss
+	manifest, err := ReadManifest(stagingDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if manifest.TaskHash != taskHash {
+		return false, fmt.Errorf("entry %s contains the result of %s", taskHash, manifest.TaskHash)
+	}
+	if err := verifyManifest(stagingDir, manifest); err != nil {
+		return false, fmt.Errorf("entry %s is corrupted: %w", taskHash, err)
+	}
+	
+	if err := r.installEntry(stagingDir, outputDir); err != nil {
+		return false, err
+	}
+	
+	return true, nil
+}
+
+// isCached checks if a complete cached result exists for the given output directory
+func (r *Runner) isCached(outputDir string) bool {
+	_, err := ReadManifest(outputDir)
+	return err == nil
+}
+
+// loadCachedResult loads a cached result from the manifest in the output directory
+func (r *Runner) loadCachedResult(task Task, taskHash, outputDir string) (ExecutionResult, error) {
+	manifest, err := ReadManifest(outputDir)
 	if err != nil {
-		return ExecutionResult{}, fmt.Errorf("failed to walk cached output directory: %w", err)
+		return ExecutionResult{}, fmt.Errorf("failed to read cache manifest: %w", err)
 	}
 	
+	// Access tracking is best effort, a failure must not fail the build
+	r.localCache.touch(taskHash)
+	
 	return ExecutionResult{
 		Task:      task,
 		TaskHash:  taskHash,
 		OutputDir: outputDir,
 		Result: TaskResult{
-			Files: files,
+			Files: manifest.FilePaths(),
+			ABI:   manifest.ABI,
 			Error: nil,
 		},
 		CacheHit: true,
@@ -418,13 +894,17 @@ func (r *Runner) moveTempToCache(tempDir, cacheDir string) error {
 				return err
 			}
 			
-			// Move file from temp to cache
-			return os.Rename(path, destPath)
+			// Move file from temp to cache, copying when the temp directory
+			// is on a different filesystem
+			if err := os.Rename(path, destPath); err != nil {
+				return copyFile(path, destPath, info.Mode())
+			}
+			return nil
 		}
 	})
 }
 
 // ExecuteTask executes a single task (useful for testing or selective execution)
 func (r *Runner) ExecuteTask(ctx context.Context, task Task) (ExecutionResult, error) {
-	return r.executeTask(ctx, task, make(map[string]ExecutionResult))
+	return r.executeTask(ctx, task, ComputeTaskHash(task), make(map[string]ExecutionResult))
 }
\ No newline at end of file
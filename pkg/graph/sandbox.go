@@ -0,0 +1,235 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sandboxDirName is the directory inside a task's work directory that its
+// inputs are staged in. It is removed before the outputs are committed.
+const sandboxDirName = ".sandbox"
+
+// sandboxKey is the context key under which the runner stores a task's sandbox
+type sandboxKey struct{}
+
+// Sandbox holds the inputs staged for a task that runs in sandbox mode. Each
+// declared input and each output file of a dependency is copied into the
+// sandbox directory, and the task reads them from there. The tools of the
+// task run in the sandbox directory with a HOME and temporary directory of
+// their own, so that they can't pick up files from the workspace or the
+// user's home directory by relative paths or through their configuration.
+type Sandbox struct {
+	dir    string
+	inputs map[string]string // staged path of each declared input by its original path
+}
+
+// UndeclaredInputError is the error of a task in sandbox mode that reads a
+// file it didn't declare as an input
+type UndeclaredInputError struct {
+	Path string
+}
+
+func (e *UndeclaredInputError) Error() string {
+	return fmt.Sprintf("undeclared input %s: tasks in the sandbox can only read their declared inputs and the outputs of their dependencies", e.Path)
+}
+
+// WithSandbox returns a context whose task reads its inputs from sandbox
+func WithSandbox(ctx context.Context, sandbox *Sandbox) context.Context {
+	return context.WithValue(ctx, sandboxKey{}, sandbox)
+}
+
+// Sandboxed reports whether the task of ctx runs in sandbox mode
+func Sandboxed(ctx context.Context) bool {
+	_, ok := ctx.Value(sandboxKey{}).(*Sandbox)
+	return ok
+}
+
+// Input returns the path a task should read one of its input files from.
+// Outside of sandbox mode this is the path itself. In sandbox mode it is the
+// staged copy, and reading a file the task didn't declare fails.
+func Input(ctx context.Context, path string) (string, error) {
+	sandbox, ok := ctx.Value(sandboxKey{}).(*Sandbox)
+	if !ok {
+		return path, nil
+	}
+	if staged, exists := sandbox.inputs[filepath.Clean(path)]; exists {
+		return staged, nil
+	}
+	return "", &UndeclaredInputError{Path: path}
+}
+
+// CheckStaged verifies that paths a task hands to its tools, such as the
+// entries of a classpath, are staged in the sandbox. Outside of sandbox mode
+// every path passes.
+func CheckStaged(ctx context.Context, paths ...string) error {
+	sandbox, ok := ctx.Value(sandboxKey{}).(*Sandbox)
+	if !ok {
+		return nil
+	}
+	for _, path := range paths {
+		rel, err := filepath.Rel(sandbox.dir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return &UndeclaredInputError{Path: path}
+		}
+	}
+	return nil
+}
+
+// ToolDir returns the directory a task runs its tools in: the sandbox
+// directory in sandbox mode, and workDir otherwise
+func ToolDir(ctx context.Context, workDir string) string {
+	if sandbox, ok := ctx.Value(sandboxKey{}).(*Sandbox); ok {
+		return sandbox.dir
+	}
+	return workDir
+}
+
+// environ returns the environment of the tools run in the sandbox. HOME and
+// the temporary directory point into the sandbox, and so does the JVM's
+// user.home, which it reads from the password database rather than HOME.
+func (s *Sandbox) environ() []string {
+	home := filepath.Join(s.dir, "home")
+	env := os.Environ()
+	javaOptions := "-Duser.home=" + home
+	if options := os.Getenv("JAVA_TOOL_OPTIONS"); options != "" {
+		javaOptions = options + " " + javaOptions
+	}
+	return append(env, "HOME="+home, "TMPDIR="+filepath.Join(s.dir, "tmp"), "JAVA_TOOL_OPTIONS="+javaOptions)
+}
+
+// newSandbox stages the declared inputs of a task and the outputs of its
+// dependencies in its work directory. It returns the dependency inputs
+// rewritten to point at the staged outputs.
+func newSandbox(task Task, workDir string, dependencyInputs []DependencyInput) (*Sandbox, []DependencyInput, error) {
+	sandbox := &Sandbox{
+		dir:    filepath.Join(workDir, sandboxDirName),
+		inputs: make(map[string]string),
+	}
+	for _, dir := range []string{"home", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(sandbox.dir, dir), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+		}
+	}
+
+	// Declared inputs keep their absolute path below the sandbox directory
+	if provider, ok := task.(InputProvider); ok {
+		for _, input := range provider.Inputs() {
+			if _, err := sandbox.stageExternal(input); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	// Dependency outputs are staged per dependency, with only the files the dependency reported
+	staged := make([]DependencyInput, 0, len(dependencyInputs))
+	for _, dep := range dependencyInputs {
+		outputDir := filepath.Join(sandbox.dir, "deps", dep.TaskID)
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+		}
+
+		files := make([]string, 0, len(dep.Files))
+		for _, file := range dep.Files {
+			// Files outside the cache, such as downloaded artifacts, are staged like declared inputs
+			if filepath.IsAbs(file) {
+				stagedFile, err := sandbox.stageExternal(file)
+				if err != nil {
+					return nil, nil, err
+				}
+				files = append(files, stagedFile)
+				continue
+			}
+
+			if err := stageFile(filepath.Join(dep.OutputDir, file), filepath.Join(outputDir, file)); err != nil {
+				return nil, nil, err
+			}
+			files = append(files, file)
+		}
+
+		staged = append(staged, DependencyInput{
+			TaskID:    dep.TaskID,
+			OutputDir: outputDir,
+			Files:     files,
+			ABI:       dep.ABI,
+		})
+	}
+
+	return sandbox, staged, nil
+}
+
+// stageExternal copies a file outside the cache into the sandbox and returns its staged path
+func (s *Sandbox) stageExternal(path string) (string, error) {
+	path = filepath.Clean(path)
+	if staged, exists := s.inputs[path]; exists {
+		return staged, nil
+	}
+
+	staged := filepath.Join(s.dir, "inputs", path)
+	if err := stageFile(path, staged); err != nil {
+		return "", err
+	}
+	s.inputs[path] = staged
+	return staged, nil
+}
+
+// remove deletes the staged inputs, leaving the originals untouched
+func (s *Sandbox) remove() error {
+	return os.RemoveAll(s.dir)
+}
+
+// stageFile copies source to target, so that a task writing to a staged file
+// changes neither the original input nor a shared cache entry. Directories are
+// copied with their contents.
+func stageFile(source, target string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to stage input: %w", err)
+	}
+	if !info.IsDir() {
+		return copyStagedFile(source, target, info.Mode())
+	}
+
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to stage input: %w", err)
+		}
+		relPath, err := filepath.Rel(source, path)
+		if err != nil {
+			return fmt.Errorf("failed to stage input: %w", err)
+		}
+		if info.IsDir() {
+			if err := os.MkdirAll(filepath.Join(target, relPath), 0755); err != nil {
+				return fmt.Errorf("failed to create sandbox directory: %w", err)
+			}
+			return nil
+		}
+		return copyStagedFile(path, filepath.Join(target, relPath), info.Mode())
+	})
+}
+
+// copyStagedFile copies a single file into the sandbox, keeping its permissions
+func copyStagedFile(source, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to stage input %s: %w", source, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return fmt.Errorf("failed to stage input %s: %w", source, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to stage input %s: %w", source, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunner_Sandbox(t *testing.T) {
+	sourceDir := t.TempDir()
+	declared := filepath.Join(sourceDir, "Declared.kt")
+	undeclared := filepath.Join(sourceDir, "Undeclared.kt")
+	os.WriteFile(declared, []byte("class Declared"), 0644)
+	os.WriteFile(undeclared, []byte("class Undeclared"), 0644)
+
+	depTask := NewMockTask("dep", "dep-task", sourceDir, "hashSbDep", nil)
+	compile := &MockSourceTask{
+		MockTask: NewMockTask("compile", "compile-task", sourceDir, "hashSbCompile", []Task{depTask}),
+		inputs:   []string{declared},
+	}
+	compile.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+		staged, err := Input(ctx, declared)
+		if err != nil {
+			return TaskResult{Error: err}
+		}
+		if !strings.HasPrefix(staged, workDir) {
+			return TaskResult{Error: fmt.Errorf("expected %s to be staged in the work directory", staged)}
+		}
+		if data, err := os.ReadFile(staged); err != nil || string(data) != "class Declared" {
+			return TaskResult{Error: fmt.Errorf("failed to read staged input: %v", err)}
+		}
+
+		dep := dependencyInputs[0]
+		if !strings.HasPrefix(dep.OutputDir, workDir) {
+			return TaskResult{Error: fmt.Errorf("expected dependency output %s to be staged in the work directory", dep.OutputDir)}
+		}
+		if _, err := os.Stat(filepath.Join(dep.OutputDir, "dep.txt")); err != nil {
+			return TaskResult{Error: fmt.Errorf("expected staged dependency output: %v", err)}
+		}
+
+		// Writing to staged files leaves the originals alone
+		os.WriteFile(staged, []byte("modified"), 0644)
+		os.WriteFile(filepath.Join(dep.OutputDir, "dep.txt"), []byte("modified"), 0644)
+
+		if _, err := Input(ctx, undeclared); err == nil {
+			return TaskResult{Error: errors.New("expected reading an undeclared input to fail")}
+		}
+		os.WriteFile(filepath.Join(workDir, "compile.txt"), []byte("compiled"), 0644)
+		return TaskResult{Files: []string{"compile.txt"}}
+	}
+
+	graph := NewGraph()
+	graph.AddTask(depTask)
+	graph.AddTask(compile)
+
+	runner := NewRunner(t.TempDir())
+	runner.SetSandbox(true)
+	results, err := runner.Execute(context.Background(), graph)
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	// The staged inputs are not part of the cached outputs
+	outputDir := results[1].OutputDir
+	if _, err := os.Stat(filepath.Join(outputDir, sandboxDirName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the sandbox to be removed before committing, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "compile.txt")); err != nil {
+		t.Errorf("Expected the task output to be cached: %v", err)
+	}
+	if data, err := os.ReadFile(declared); err != nil || string(data) != "class Declared" {
+		t.Errorf("Expected the original input to be untouched, got %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(results[0].OutputDir, "dep.txt")); err != nil || string(data) == "modified" {
+		t.Errorf("Expected the cached dependency output to be untouched, got %q, %v", data, err)
+	}
+}
+
+func TestInput_UndeclaredFails(t *testing.T) {
+	if path, err := Input(context.Background(), "/src/Foo.kt"); err != nil || path != "/src/Foo.kt" {
+		t.Errorf("Expected inputs to be read in place outside the sandbox, got %q, %v", path, err)
+	}
+
+	ctx := WithSandbox(context.Background(), &Sandbox{inputs: map[string]string{}})
+	var undeclared *UndeclaredInputError
+	if _, err := Input(ctx, "/src/Foo.kt"); !errors.As(err, &undeclared) || undeclared.Path != "/src/Foo.kt" {
+		t.Errorf("Expected an undeclared input error, got %v", err)
+	}
+}
+
+func TestSandbox_ToolsOnlySeeStagedPaths(t *testing.T) {
+	workDir := t.TempDir()
+	sandbox, _, err := newSandbox(NewMockTask("A", "task", "/test", "hash", nil), workDir, nil)
+	if err != nil {
+		t.Fatalf("Failed to create sandbox: %v", err)
+	}
+	ctx := WithSandbox(context.Background(), sandbox)
+
+	// Classpath entries must be staged
+	if err := CheckStaged(ctx, filepath.Join(sandbox.dir, "deps", "B", "classes")); err != nil {
+		t.Errorf("Expected a staged dependency output to pass, got %v", err)
+	}
+	var undeclared *UndeclaredInputError
+	if err := CheckStaged(ctx, "/home/user/.gradle/caches/lib.jar"); !errors.As(err, &undeclared) {
+		t.Errorf("Expected a classpath entry outside the sandbox to fail, got %v", err)
+	}
+	if err := CheckStaged(context.Background(), "/home/user/.gradle/caches/lib.jar"); err != nil {
+		t.Errorf("Expected any path to pass outside of sandbox mode, got %v", err)
+	}
+
+	// Tools run in the sandbox with a HOME of their own
+	cmd := Command(ctx, "true")
+	if cmd.Dir != sandbox.dir || ToolDir(ctx, workDir) != sandbox.dir {
+		t.Errorf("Expected tools to run in %s, got %s", sandbox.dir, cmd.Dir)
+	}
+	home := ""
+	for _, entry := range cmd.Env {
+		if value, found := strings.CutPrefix(entry, "HOME="); found {
+			home = value
+		}
+	}
+	if home != filepath.Join(sandbox.dir, "home") {
+		t.Errorf("Expected HOME in the sandbox, got %q", home)
+	}
+}
@@ -0,0 +1,182 @@
+package graph
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// TargetNamer is implemented by tasks that can be selected by names other than
+// Name() and DisplayName(), such as the test class a JUnit task runs
+type TargetNamer interface {
+	// TargetNames returns the additional names this task can be selected by
+	TargetNames() []string
+}
+
+// Selector picks tasks from a planned graph. Its syntax is
+//
+//	[//]<directory>[:<task>]
+//
+// A directory starting with // is relative to the workspace root, any other
+// relative directory is relative to the working directory. Without a task part
+// a selector matches every task in the directory and its subdirectories; with a
+// task part it matches the tasks in exactly that directory whose name, display
+// name or target names match. Both parts may contain globs, and ** in the
+// directory matches any number of path segments.
+type Selector struct {
+	raw         string
+	dirPattern  string
+	taskPattern string
+}
+
+// ParseSelector parses a target selector
+func ParseSelector(raw, workDir, workspaceRoot string) (*Selector, error) {
+	dirPart, taskPart, hasTask := strings.Cut(raw, ":")
+	if hasTask && taskPart == "" {
+		return nil, fmt.Errorf("invalid target %q: missing task after ':'", raw)
+	}
+
+	var dir string
+	switch {
+	case strings.HasPrefix(dirPart, "//"):
+		dir = filepath.Join(workspaceRoot, strings.TrimPrefix(dirPart, "//"))
+	case filepath.IsAbs(dirPart):
+		dir = dirPart
+	default:
+		dir = filepath.Join(workDir, dirPart)
+	}
+
+	dirPattern := filepath.ToSlash(filepath.Clean(dir))
+	if err := validatePattern(dirPattern); err != nil {
+		return nil, fmt.Errorf("invalid target %q: %w", raw, err)
+	}
+	if err := validatePattern(taskPart); err != nil {
+		return nil, fmt.Errorf("invalid target %q: %w", raw, err)
+	}
+
+	return &Selector{
+		raw:         raw,
+		dirPattern:  dirPattern,
+		taskPattern: taskPart,
+	}, nil
+}
+
+// String returns the selector as it was written
+func (s *Selector) String() string {
+	return s.raw
+}
+
+// HasTask reports whether the selector names specific tasks rather than a whole directory
+func (s *Selector) HasTask() bool {
+	return s.taskPattern != ""
+}
+
+// BaseDir returns the longest directory prefix of the selector that contains no globs.
+// Planning this directory is enough to find every task the selector can match.
+func (s *Selector) BaseDir() string {
+	var literal []string
+	for _, segment := range strings.Split(s.dirPattern, "/") {
+		if hasGlob(segment) {
+			break
+		}
+		literal = append(literal, segment)
+	}
+
+	base := strings.Join(literal, "/")
+	if base == "" {
+		base = "/"
+	}
+	return filepath.FromSlash(base)
+}
+
+// Matches reports whether the selector matches the task
+func (s *Selector) Matches(task Task) bool {
+	taskDir := filepath.ToSlash(filepath.Clean(task.Directory()))
+
+	if !s.HasTask() {
+		// The directory itself or any of its subdirectories
+		for dir := taskDir; ; dir = path.Dir(dir) {
+			if matchPath(s.dirPattern, dir) {
+				return true
+			}
+			if dir == "/" || dir == "." {
+				return false
+			}
+		}
+	}
+
+	if !matchPath(s.dirPattern, taskDir) {
+		return false
+	}
+
+	names := []string{task.Name(), task.DisplayName()}
+	if namer, ok := task.(TargetNamer); ok {
+		names = append(names, namer.TargetNames()...)
+	}
+	for _, name := range names {
+		if matched, _ := path.Match(s.taskPattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the tasks matched by the selector
+func (s *Selector) Select(tasks []Task) []Task {
+	var selected []Task
+	for _, task := range tasks {
+		if s.Matches(task) {
+			selected = append(selected, task)
+		}
+	}
+	return selected
+}
+
+// matchPath matches a slash-separated path against a pattern in which ** matches
+// zero or more path segments and every other segment is a path.Match pattern
+func matchPath(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
+
+// validatePattern checks that every segment of a pattern is a valid glob
+func validatePattern(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("malformed pattern %q", segment)
+		}
+	}
+	return nil
+}
+
+// hasGlob reports whether a path segment contains glob characters
+func hasGlob(segment string) bool {
+	return strings.ContainsAny(segment, "*?[")
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"testing"
+)
+
+// MockTestTask is a mock task with additional target names
+type MockTestTask struct {
+	*MockTask
+	className string
+}
+
+func (m *MockTestTask) TargetNames() []string {
+	return []string{m.className}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	mainCompile := NewMockTask("main", "kotlin-compile", "/ws/service/api/src/main/kotlin", "h1", nil)
+	jar := NewMockTask("jar", "jar-compile", "/ws/service/api", "h2", nil)
+	otherJar := NewMockTask("other-jar", "jar-compile", "/ws/service/web", "h3", nil)
+	fooTest := &MockTestTask{NewMockTask("foo", "junit-test", "/ws/service/api/src/test/kotlin", "h4", nil), "FooTest"}
+	barTest := &MockTestTask{NewMockTask("bar", "junit-test", "/ws/service/api/src/test/kotlin", "h5", nil), "BarTest"}
+	tasks := []Task{mainCompile, jar, otherJar, fooTest, barTest}
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{"service/api", []string{"main", "jar", "foo", "bar"}},
+		{"//service/api:jar-compile", []string{"jar"}},
+		{"service/api/src/test/kotlin:FooTest", []string{"foo"}},
+		{"service/api/src/test/kotlin:*Test", []string{"foo", "bar"}},
+		{"//service/*:jar-compile", []string{"jar", "other-jar"}},
+		{"//service/**:kotlin-compile", []string{"main"}},
+		{"//**/src/test/**", []string{"foo", "bar"}},
+		{"service/api:FooTest", nil},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseSelector(test.selector, "/ws", "/ws")
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", test.selector, err)
+		}
+
+		var ids []string
+		for _, task := range selector.Select(tasks) {
+			ids = append(ids, task.ID())
+		}
+		if len(ids) != len(test.expected) {
+			t.Errorf("Selector %s: expected %v, got %v", test.selector, test.expected, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != test.expected[i] {
+				t.Errorf("Selector %s: expected %v, got %v", test.selector, test.expected, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestSelector_Parse(t *testing.T) {
+	selector, err := ParseSelector("//service/*/src:jar-compile", "/ws/service/api", "/ws")
+	if err != nil {
+		t.Fatalf("Failed to parse selector: %v", err)
+	}
+	if selector.BaseDir() != "/ws/service" {
+		t.Errorf("Expected base dir /ws/service, got %s", selector.BaseDir())
+	}
+	if !selector.HasTask() {
+		t.Error("Expected selector to name a task")
+	}
+
+	selector, err = ParseSelector("src/main/kotlin", "/ws/service/api", "/ws")
+	if err != nil {
+		t.Fatalf("Failed to parse selector: %v", err)
+	}
+	if selector.BaseDir() != "/ws/service/api/src/main/kotlin" || selector.HasTask() {
+		t.Errorf("Unexpected selector for a plain directory: base %s", selector.BaseDir())
+	}
+
+	for _, invalid := range []string{"src:", "src/[:jar-compile", "src:[abc"} {
+		if _, err := ParseSelector(invalid, "/ws", "/ws"); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -20,6 +20,9 @@ const (
 type TaskResult struct {
 	// Files contains the relative paths to files produced by the task
 	Files []string
+	// ABI is the ABI fingerprint of the classes produced by the task, if it
+	// compiles code. ABI consumers depending on the task are cached by it.
+	ABI string
 	// Error contains any error that occurred during task execution
 	Error error
 }
@@ -32,6 +35,8 @@ type DependencyInput struct {
 	OutputDir string
 	// Files are the relative paths to the files produced by the dependency
 	Files []string
+	// ABI is the ABI fingerprint the dependency published, if any
+	ABI string
 }
 
 // Task represents a unit of work in the build graph
@@ -63,3 +68,18 @@ type Task interface {
 	// DisplayName returns a detailed display name for this task with context-specific information
 	DisplayName() string
 }
+
+// InputProvider is implemented by tasks that know which source files they read
+type InputProvider interface {
+	// Inputs returns the absolute paths of the files this task reads
+	Inputs() []string
+}
+
+// ABIConsumer is implemented by tasks whose output only depends on the ABI of
+// the classes their dependencies produce, such as compilers. They are cached
+// by the ABI fingerprints of their dependencies rather than by the
+// dependencies' hashes, so that changing a method body doesn't invalidate them.
+type ABIConsumer interface {
+	// ConsumesABI marks the task as an ABI consumer
+	ConsumesABI()
+}
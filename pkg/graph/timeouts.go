@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultTimeoutKey is the key of the timeout that applies to tasks without a
+// more specific timeout
+const DefaultTimeoutKey = "default"
+
+// Timeouts maps task names (such as junit-test) and task types (build, test or
+// deps) to the maximum time a task may run. A task name takes precedence over
+// its type, and DefaultTimeoutKey applies to every other task.
+type Timeouts map[string]time.Duration
+
+// For returns the timeout of a task, or zero if the task may run indefinitely
+func (t Timeouts) For(task Task) time.Duration {
+	for _, key := range []string{task.Name(), string(task.TaskType()), DefaultTimeoutKey} {
+		if timeout, exists := t[key]; exists {
+			return timeout
+		}
+	}
+	return 0
+}
+
+// TimeoutError is the error of a task that ran longer than its timeout
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s (see \"timeouts\" in fbs.conf.json)", e.Timeout)
+}
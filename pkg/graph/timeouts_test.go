@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBlockingTask creates a task that runs until its context is done
+func newBlockingTask(id, name string, started chan<- string) *MockTask {
+	task := NewMockTask(id, name, "/test", "hash-"+id, nil)
+	task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+		if started != nil {
+			started <- id
+		}
+		<-ctx.Done()
+		return TaskResult{Error: ctx.Err()}
+	}
+	return task
+}
+
+func TestTimeouts_For(t *testing.T) {
+	timeouts := Timeouts{
+		"junit-test":      time.Minute,
+		"build":           10 * time.Minute,
+		DefaultTimeoutKey: 5 * time.Minute,
+	}
+
+	junit := NewMockTask("A", "junit-test", "/test", "hashA", nil)
+	compile := NewMockTask("B", "kotlin-compile", "/test", "hashB", nil)
+	if timeouts.For(junit) != time.Minute {
+		t.Errorf("Expected the task name timeout, got %v", timeouts.For(junit))
+	}
+	if timeouts.For(compile) != 10*time.Minute {
+		t.Errorf("Expected the task type timeout, got %v", timeouts.For(compile))
+	}
+	if (Timeouts{}).For(compile) != 0 {
+		t.Error("Expected no timeout without configuration")
+	}
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	graph := NewGraph()
+	graph.AddTask(newBlockingTask("hung", "junit-test", nil))
+
+	runner := NewRunner(t.TempDir())
+	runner.SetTimeouts(Timeouts{"junit-test": 50 * time.Millisecond})
+	results, err := runner.Execute(context.Background(), graph)
+	if err == nil {
+		t.Fatal("Expected the hung task to fail")
+	}
+
+	var timeoutErr *TimeoutError
+	if len(results) != 1 || !errors.As(results[0].Result.Error, &timeoutErr) {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if timeoutErr.Timeout != 50*time.Millisecond {
+		t.Errorf("Expected timeout of 50ms, got %v", timeoutErr.Timeout)
+	}
+}
+
+func TestRunner_CancelWaitsForRunningTasks(t *testing.T) {
+	const tasks = 3
+	started := make(chan string, tasks)
+	var finished int32
+
+	graph := NewGraph()
+	for _, id := range []string{"A", "B", "C"} {
+		task := newBlockingTask(id, "mock-task", started)
+		execute := task.executeFunc
+		task.executeFunc = func(ctx context.Context, workDir string, dependencyInputs []DependencyInput) TaskResult {
+			defer atomic.AddInt32(&finished, 1)
+			return execute(ctx, workDir, dependencyInputs)
+		}
+		graph.AddTask(task)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for i := 0; i < tasks; i++ {
+			<-started
+		}
+		cancel()
+	}()
+
+	runner := NewRunner(t.TempDir())
+	_, err := runner.ExecuteWithProgressParallel(ctx, graph, nil, tasks)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the build to be cancelled, got %v", err)
+	}
+	if atomic.LoadInt32(&finished) != tasks {
+		t.Errorf("Expected all %d running tasks to finish before returning, got %d", tasks, finished)
+	}
+}
@@ -0,0 +1,145 @@
+package java
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fbs/pkg/discoverer"
+	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
+)
+
+// sourceSets are the Gradle source sets whose java directories are source roots
+var sourceSets = []string{"main", "test", "dev", "testFixtures", "integrationTest"}
+
+// JavaDiscoverer discovers Java compilation tasks from directories
+type JavaDiscoverer struct{}
+
+// NewJavaDiscoverer creates a new Java discoverer
+func NewJavaDiscoverer() *JavaDiscoverer {
+	return &JavaDiscoverer{}
+}
+
+// Name returns the name of this discoverer
+func (d *JavaDiscoverer) Name() string {
+	return "JavaDiscoverer"
+}
+
+// Discover finds Java files in the given path and creates compilation tasks
+func (d *JavaDiscoverer) Discover(ctx context.Context, path string, potentialDependencies []graph.Task, buildContext *discoverer.BuildContext) (*discoverer.DiscoveryResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &discoverer.DiscoveryResult{
+				Tasks: []graph.Task{},
+				Path:  path,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to stat path %s: %w", path, err)
+	}
+
+	searchDir := path
+	if !info.IsDir() {
+		searchDir = filepath.Dir(path)
+	}
+
+	// Source roots are compiled as a whole, other directories on their own
+	// unless they belong to a source root
+	var javaFiles []string
+	if IsSourceRoot(searchDir) {
+		javaFiles, err = FindJavaFiles(searchDir)
+	} else if !d.isPartOfSourceTree(searchDir) {
+		javaFiles, err = d.findJavaFiles(searchDir)
+	}
+	if err != nil {
+		return &discoverer.DiscoveryResult{
+			Tasks:  []graph.Task{},
+			Errors: []error{err},
+			Path:   path,
+		}, nil
+	}
+
+	if len(javaFiles) == 0 {
+		return &discoverer.DiscoveryResult{
+			Tasks: []graph.Task{},
+			Path:  path,
+		}, nil
+	}
+
+	task := NewJavaCompile(searchDir, javaFiles)
+	task.SetToolchain(toolchain.FromBuildContext(buildContext))
+
+	// Java compilations in subdirectories are compiled first
+	for _, dep := range potentialDependencies {
+		if javaDep, ok := dep.(*JavaCompile); ok {
+			task.AddDependency(javaDep)
+		}
+	}
+
+	return &discoverer.DiscoveryResult{
+		Tasks: []graph.Task{task},
+		Path:  path,
+	}, nil
+}
+
+// IsSourceRoot checks if the given directory is a Java source root, such as src/main/java
+func IsSourceRoot(dir string) bool {
+	for _, sourceSet := range sourceSets {
+		if strings.HasSuffix(dir, "/src/"+sourceSet+"/java") {
+			return true
+		}
+	}
+	return false
+}
+
+// FindJavaFiles finds all .java files in the given directory tree, relative to it
+func FindJavaFiles(rootDir string) ([]string, error) {
+	var javaFiles []string
+	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".java") {
+			return nil
+		}
+		relPath, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return err
+		}
+		javaFiles = append(javaFiles, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %w", rootDir, err)
+	}
+	return javaFiles, nil
+}
+
+// findJavaFiles finds all .java files in the given directory (non-recursive)
+func (d *JavaDiscoverer) findJavaFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	var javaFiles []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".java") {
+			javaFiles = append(javaFiles, entry.Name())
+		}
+	}
+	return javaFiles, nil
+}
+
+// isPartOfSourceTree checks if any parent directory is a source root
+func (d *JavaDiscoverer) isPartOfSourceTree(dir string) bool {
+	for current := filepath.Dir(dir); current != "/" && current != filepath.Dir(current); current = filepath.Dir(current) {
+		if IsSourceRoot(current) {
+			return true
+		}
+	}
+	return false
+}
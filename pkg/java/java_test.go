@@ -0,0 +1,238 @@
+package java
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"fbs/pkg/discoverer"
+	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
+)
+
+// TestMain lets the test binary stand in for javac
+func TestMain(m *testing.M) {
+	if os.Getenv("FBS_FAKE_JAVAC") != "" {
+		if err := fakeJavac(os.Args[1:]); err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// packagePattern matches the package declaration of a Java file
+var packagePattern = regexp.MustCompile(`(?m)^package\s+([\w.]+);`)
+
+// fakeJavac writes a class for every source and logs its classpath
+func fakeJavac(args []string) error {
+	var outputDir, classpath string
+	var sources []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-d":
+			outputDir = args[i+1]
+			i++
+		case "-classpath":
+			classpath = args[i+1]
+			i++
+		default:
+			sources = append(sources, args[i])
+		}
+	}
+
+	// Some tools read configuration from the user's home directory
+	if name := os.Getenv("FBS_FAKE_JAVAC_HOME_FILE"); name != "" {
+		if _, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), name)); err != nil {
+			return err
+		}
+	}
+
+	for _, source := range sources {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(source), ".java")
+		if matches := packagePattern.FindSubmatch(data); matches != nil {
+			name = strings.ReplaceAll(string(matches[1]), ".", "/") + "/" + name
+		}
+		classPath := filepath.Join(outputDir, name+".class")
+		if err := os.MkdirAll(filepath.Dir(classPath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(classPath, fakeClass(name), 0644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(os.Getenv("FBS_FAKE_JAVAC"), []byte(classpath), 0644)
+}
+
+// fakeClass builds an empty public class
+func fakeClass(name string) []byte {
+	var pool []byte
+	count := uint16(0)
+	class := func(s string) []byte {
+		pool = append(pool, 1)
+		pool = binary.BigEndian.AppendUint16(pool, uint16(len(s)))
+		pool = append(pool, s...)
+		pool = append(pool, 7)
+		pool = binary.BigEndian.AppendUint16(pool, count+1)
+		count += 2
+		return binary.BigEndian.AppendUint16(nil, count)
+	}
+	this := class(name)
+	super := class("java/lang/Object")
+
+	data := []byte{0xCA, 0xFE, 0xBA, 0xBE, 0, 0, 0, 61}
+	data = binary.BigEndian.AppendUint16(data, count+1)
+	data = append(data, pool...)
+	data = append(data, 0, 0x21)
+	data = append(data, this...)
+	data = append(data, super...)
+	return append(data, 0, 0, 0, 0, 0, 0, 0, 0) // no interfaces, fields, methods or attributes
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestJavaDiscoverer_Discover(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceRoot := filepath.Join(tempDir, "src", "main", "java")
+	writeFile(t, filepath.Join(sourceRoot, "com", "example", "Greeter.java"), "package com.example;\n")
+	writeFile(t, filepath.Join(sourceRoot, "com", "example", "Util.java"), "package com.example;\n")
+	writeFile(t, filepath.Join(sourceRoot, "com", "example", "Extensions.kt"), "package com.example\n")
+
+	d := NewJavaDiscoverer()
+	ctx := context.Background()
+	buildContext := discoverer.NewBuildContext()
+
+	// Source roots compile all their Java files
+	result, err := d.Discover(ctx, sourceRoot, nil, buildContext)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(result.Tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(result.Tasks))
+	}
+	task := result.Tasks[0].(*JavaCompile)
+	expected := []string{filepath.Join("com", "example", "Greeter.java"), filepath.Join("com", "example", "Util.java")}
+	if strings.Join(task.GetJavaFiles(), ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected Java files %v, got %v", expected, task.GetJavaFiles())
+	}
+
+	// Packages inside a source root are left to the source root
+	result, err = d.Discover(ctx, filepath.Join(sourceRoot, "com", "example"), nil, buildContext)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(result.Tasks) != 0 {
+		t.Errorf("Expected no tasks inside a source root, got %d", len(result.Tasks))
+	}
+
+	// Other directories compile the Java files they contain
+	otherDir := filepath.Join(tempDir, "scripts")
+	writeFile(t, filepath.Join(otherDir, "Tool.java"), "")
+	result, err = d.Discover(ctx, otherDir, nil, buildContext)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(result.Tasks) != 1 || len(result.Tasks[0].(*JavaCompile).GetJavaFiles()) != 1 {
+		t.Errorf("Expected a task compiling Tool.java, got %v", result.Tasks)
+	}
+}
+
+func TestJavaCompile_Execute(t *testing.T) {
+	sourceDir := t.TempDir()
+	compilerLog := filepath.Join(t.TempDir(), "javac.log")
+	t.Setenv("FBS_FAKE_JAVAC", compilerLog)
+	writeFile(t, filepath.Join(sourceDir, "com", "example", "Greeter.java"), "package com.example;\npublic class Greeter {}\n")
+
+	task := NewJavaCompile(sourceDir, []string{filepath.Join("com", "example", "Greeter.java")})
+	task.SetToolchain(&toolchain.Toolchain{Javac: toolchain.Tool{Name: "javac", Path: os.Args[0], Version: "test"}})
+
+	// The Kotlin classes of the source set are on the classpath
+	kotlinOutput := t.TempDir()
+	os.MkdirAll(filepath.Join(kotlinOutput, "classes"), 0755)
+
+	workDir := t.TempDir()
+	result := task.Execute(context.Background(), workDir, []graph.DependencyInput{{OutputDir: kotlinOutput}})
+	if result.Error != nil {
+		t.Fatalf("Compilation failed: %v", result.Error)
+	}
+
+	expected := filepath.Join("classes", "com", "example", "Greeter.class")
+	if len(result.Files) != 1 || result.Files[0] != expected {
+		t.Errorf("Expected %s, got %v", expected, result.Files)
+	}
+	if result.ABI == "" {
+		t.Error("Expected the compilation to publish an ABI fingerprint")
+	}
+
+	classpath, _ := os.ReadFile(compilerLog)
+	if string(classpath) != filepath.Join(kotlinOutput, "classes") {
+		t.Errorf("Expected the dependency's classes on the classpath, got %q", classpath)
+	}
+}
+
+func TestJavaCompile_HashUsesFileContents(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeFile(t, filepath.Join(sourceDir, "Main.java"), "class Main {}")
+	tools := &toolchain.Toolchain{Javac: toolchain.Tool{Name: "javac", Version: "21"}}
+
+	task := NewJavaCompile(sourceDir, []string{"Main.java"})
+	task.SetToolchain(tools)
+	before := task.Hash()
+
+	writeFile(t, filepath.Join(sourceDir, "Main.java"), "class Main { void run() {} }")
+	if task.Hash() == before {
+		t.Error("Expected the hash to change with the file contents")
+	}
+
+	before = task.Hash()
+	task.SetToolchain(&toolchain.Toolchain{Javac: toolchain.Tool{Name: "javac", Version: "17"}})
+	if task.Hash() == before {
+		t.Error("Expected the hash to change with the javac version")
+	}
+}
+
+func TestJavaCompile_SandboxRejectsUndeclaredReads(t *testing.T) {
+	sourceDir := t.TempDir()
+	t.Setenv("FBS_FAKE_JAVAC", filepath.Join(t.TempDir(), "javac.log"))
+	writeFile(t, filepath.Join(sourceDir, "Main.java"), "public class Main {}\n")
+
+	// javac reads a file from the home directory that the task didn't declare
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FBS_FAKE_JAVAC_HOME_FILE", "settings.xml")
+	writeFile(t, filepath.Join(home, "settings.xml"), "<settings/>")
+
+	for _, sandbox := range []bool{false, true} {
+		task := NewJavaCompile(sourceDir, []string{"Main.java"})
+		task.SetToolchain(&toolchain.Toolchain{Javac: toolchain.Tool{Name: "javac", Path: os.Args[0], Version: "test"}})
+		buildGraph := graph.NewGraph()
+		buildGraph.AddTask(task)
+
+		runner := graph.NewRunner(t.TempDir())
+		runner.SetSandbox(sandbox)
+		_, err := runner.Execute(context.Background(), buildGraph)
+		if !sandbox && err != nil {
+			t.Fatalf("Expected the compilation to succeed outside the sandbox: %v", err)
+		}
+		if sandbox && err == nil {
+			t.Error("Expected reading an undeclared file to fail the compilation in the sandbox")
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package java
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fbs/pkg/discoverer"
+	"fbs/pkg/graph"
+)
+
+// ResourcesDiscoverer discovers the resources of source sets, such as src/main/resources
+type ResourcesDiscoverer struct{}
+
+// NewResourcesDiscoverer creates a new resources discoverer
+func NewResourcesDiscoverer() *ResourcesDiscoverer {
+	return &ResourcesDiscoverer{}
+}
+
+// Name returns the name of this discoverer
+func (d *ResourcesDiscoverer) Name() string {
+	return "ResourcesDiscoverer"
+}
+
+// Discover creates a resources processing task for a resources root with files
+func (d *ResourcesDiscoverer) Discover(ctx context.Context, path string, potentialDependencies []graph.Task, buildContext *discoverer.BuildContext) (*discoverer.DiscoveryResult, error) {
+	if !IsResourceRoot(path) {
+		return &discoverer.DiscoveryResult{
+			Tasks: []graph.Task{},
+			Path:  path,
+		}, nil
+	}
+
+	files, err := d.findResourceFiles(path)
+	if err != nil {
+		return &discoverer.DiscoveryResult{
+			Tasks:  []graph.Task{},
+			Errors: []error{err},
+			Path:   path,
+		}, nil
+	}
+	if len(files) == 0 {
+		return &discoverer.DiscoveryResult{
+			Tasks: []graph.Task{},
+			Path:  path,
+		}, nil
+	}
+
+	return &discoverer.DiscoveryResult{
+		Tasks: []graph.Task{NewProcessResources(path, files)},
+		Path:  path,
+	}, nil
+}
+
+// IsResourceRoot checks if the given directory holds the resources of a source set
+func IsResourceRoot(dir string) bool {
+	for _, sourceSet := range sourceSets {
+		if strings.HasSuffix(dir, "/src/"+sourceSet+"/resources") {
+			return true
+		}
+	}
+	return false
+}
+
+// findResourceFiles finds all files in the given directory tree, relative to it
+func (d *ResourcesDiscoverer) findResourceFiles(rootDir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == rootDir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %w", rootDir, err)
+	}
+	return files, nil
+}
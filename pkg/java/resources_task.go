@@ -0,0 +1,154 @@
+package java
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"fbs/pkg/graph"
+)
+
+// resourcesABI is the ABI fingerprint of every ProcessResources task. Resources
+// don't take part in compilation, so compilations against a JAR aren't
+// repeated when only its resources change.
+const resourcesABI = "resources"
+
+// ProcessResources represents a task that copies the resources of a source
+// set, such as configuration files and SQL migrations, into the resources
+// directory of its output, from where they are packaged and put on the classpath
+type ProcessResources struct {
+	resourceDir  string
+	files        []string
+	dependencies []graph.Task
+}
+
+// NewProcessResources creates a new resources processing task for files relative to resourceDir
+func NewProcessResources(resourceDir string, files []string) *ProcessResources {
+	return &ProcessResources{
+		resourceDir:  resourceDir,
+		files:        files,
+		dependencies: []graph.Task{},
+	}
+}
+
+// ID returns the unique identifier for this task (using hash)
+func (p *ProcessResources) ID() string {
+	return p.Hash()
+}
+
+// Name returns the human-readable name for this task type
+func (p *ProcessResources) Name() string {
+	return "process-resources"
+}
+
+// Directory returns the directory where this task was discovered
+func (p *ProcessResources) Directory() string {
+	return p.resourceDir
+}
+
+// TaskType returns the type of task
+func (p *ProcessResources) TaskType() graph.TaskType {
+	return graph.TaskTypeBuild
+}
+
+// Inputs returns the absolute paths of the resource files
+func (p *ProcessResources) Inputs() []string {
+	inputs := make([]string, 0, len(p.files))
+	for _, file := range p.files {
+		inputs = append(inputs, filepath.Join(p.resourceDir, file))
+	}
+	return inputs
+}
+
+// Hash returns a hash of the paths and contents of the resource files
+func (p *ProcessResources) Hash() string {
+	h := sha256.New()
+
+	h.Write([]byte("ProcessResources"))
+	h.Write([]byte(graph.HashPath(p.resourceDir)))
+
+	for _, file := range p.files {
+		h.Write([]byte(file))
+		if digest, err := graph.FileDigest(filepath.Join(p.resourceDir, file)); err == nil {
+			h.Write([]byte(digest))
+		}
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// Dependencies returns the list of tasks that must complete before this task can run
+func (p *ProcessResources) Dependencies() []graph.Task {
+	return p.dependencies
+}
+
+// Execute copies the resource files into the resources directory of the work directory
+func (p *ProcessResources) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
+	resourcesDir := filepath.Join(workDir, "resources")
+	if err := os.MkdirAll(resourcesDir, 0755); err != nil {
+		return graph.TaskResult{Error: fmt.Errorf("failed to create resources directory: %w", err)}
+	}
+
+	files := make([]string, 0, len(p.files))
+	for _, file := range p.files {
+		sourcePath, err := graph.Input(ctx, filepath.Join(p.resourceDir, file))
+		if err != nil {
+			return graph.TaskResult{Error: err}
+		}
+		if err := copyResource(sourcePath, filepath.Join(resourcesDir, file)); err != nil {
+			return graph.TaskResult{Error: err}
+		}
+		files = append(files, filepath.Join("resources", file))
+	}
+
+	return graph.TaskResult{
+		Files: files,
+		ABI:   resourcesABI,
+	}
+}
+
+// copyResource copies a resource file, creating its directory
+func copyResource(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create resource directory: %w", err)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to copy resource: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to copy resource: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy resource: %w", err)
+	}
+	return out.Close()
+}
+
+// GetResourceDir returns the resources directory
+func (p *ProcessResources) GetResourceDir() string {
+	return p.resourceDir
+}
+
+// GetFiles returns the resource files, relative to the resources directory
+func (p *ProcessResources) GetFiles() []string {
+	return p.files
+}
+
+// AddDependency adds a task as a dependency
+func (p *ProcessResources) AddDependency(task graph.Task) {
+	p.dependencies = append(p.dependencies, task)
+}
+
+// DisplayName returns a detailed display name
+func (p *ProcessResources) DisplayName() string {
+	return p.Name()
+}
@@ -0,0 +1,66 @@
+package java
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fbs/pkg/discoverer"
+)
+
+func TestResourcesDiscoverer_Discover(t *testing.T) {
+	resourceDir := filepath.Join(t.TempDir(), "src", "main", "resources")
+	writeFile(t, filepath.Join(resourceDir, "application.conf"), "port = 8080\n")
+	writeFile(t, filepath.Join(resourceDir, "db", "migration", "V1__init.sql"), "CREATE TABLE users (id INT);\n")
+
+	d := NewResourcesDiscoverer()
+	result, err := d.Discover(context.Background(), resourceDir, nil, discoverer.NewBuildContext())
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(result.Tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(result.Tasks))
+	}
+	task := result.Tasks[0].(*ProcessResources)
+	if len(task.GetFiles()) != 2 {
+		t.Errorf("Expected 2 resource files, got %v", task.GetFiles())
+	}
+
+	// Only resource roots are processed
+	result, err = d.Discover(context.Background(), filepath.Join(resourceDir, "db"), nil, discoverer.NewBuildContext())
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if len(result.Tasks) != 0 {
+		t.Errorf("Expected no tasks outside a resource root, got %d", len(result.Tasks))
+	}
+}
+
+func TestProcessResources_Execute(t *testing.T) {
+	resourceDir := t.TempDir()
+	migration := filepath.Join("db", "migration", "V1__init.sql")
+	writeFile(t, filepath.Join(resourceDir, migration), "CREATE TABLE users (id INT);\n")
+
+	task := NewProcessResources(resourceDir, []string{migration})
+	before := task.Hash()
+
+	workDir := t.TempDir()
+	result := task.Execute(context.Background(), workDir, nil)
+	if result.Error != nil {
+		t.Fatalf("Processing failed: %v", result.Error)
+	}
+	if len(result.Files) != 1 || result.Files[0] != filepath.Join("resources", migration) {
+		t.Errorf("Expected the migration in the output, got %v", result.Files)
+	}
+	data, err := os.ReadFile(filepath.Join(workDir, "resources", migration))
+	if err != nil || string(data) != "CREATE TABLE users (id INT);\n" {
+		t.Errorf("Expected the migration to be copied, got %q: %v", data, err)
+	}
+
+	// Resources are content-hashed
+	writeFile(t, filepath.Join(resourceDir, migration), "CREATE TABLE accounts (id INT);\n")
+	if task.Hash() == before {
+		t.Error("Expected the hash to change with the file contents")
+	}
+}
@@ -0,0 +1,254 @@
+package java
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fbs/pkg/classfile"
+	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
+)
+
+// JavaCompile represents a task that compiles Java source files
+type JavaCompile struct {
+	sourceDir    string
+	javaFiles    []string
+	classpath    []string
+	dependencies []graph.Task
+	toolchain    *toolchain.Toolchain
+}
+
+// NewJavaCompile creates a new Java compilation task
+func NewJavaCompile(sourceDir string, javaFiles []string) *JavaCompile {
+	return &JavaCompile{
+		sourceDir:    sourceDir,
+		javaFiles:    javaFiles,
+		classpath:    []string{},
+		dependencies: []graph.Task{},
+	}
+}
+
+// ID returns the unique identifier for this task (using hash)
+func (j *JavaCompile) ID() string {
+	return j.Hash()
+}
+
+// Name returns the human-readable name for this task type
+func (j *JavaCompile) Name() string {
+	return "java-compile"
+}
+
+// Directory returns the directory where this task was discovered
+func (j *JavaCompile) Directory() string {
+	return j.sourceDir
+}
+
+// TaskType returns the type of task (build for compilation)
+func (j *JavaCompile) TaskType() graph.TaskType {
+	return graph.TaskTypeBuild
+}
+
+// Resources returns the resources of a javac JVM
+func (j *JavaCompile) Resources() graph.Resources {
+	return graph.Resources{CPU: 1, MemoryMB: 512}
+}
+
+// Inputs returns the absolute paths of the Java source files this task
+// compiles and of the entries of its configured classpath
+func (j *JavaCompile) Inputs() []string {
+	inputs := make([]string, 0, len(j.javaFiles)+len(j.classpath))
+	for _, file := range j.javaFiles {
+		inputs = append(inputs, filepath.Join(j.sourceDir, file))
+	}
+	inputs = append(inputs, j.classpath...)
+	return inputs
+}
+
+// Hash returns a hash representing the task's configuration and inputs
+func (j *JavaCompile) Hash() string {
+	h := sha256.New()
+
+	h.Write([]byte("JavaCompile"))
+	h.Write([]byte(graph.HashPath(j.sourceDir)))
+
+	for _, file := range j.javaFiles {
+		h.Write([]byte(file))
+		if digest, err := graph.FileDigest(filepath.Join(j.sourceDir, file)); err == nil {
+			h.Write([]byte(digest))
+		}
+	}
+
+	for _, cp := range j.classpath {
+		h.Write([]byte(graph.HashClasspathEntry(cp)))
+	}
+
+	// The JDK's javac determines the bytecode
+	h.Write([]byte(j.tools().Javac.Fingerprint()))
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// Dependencies returns the list of tasks that must complete before this task can run
+func (j *JavaCompile) Dependencies() []graph.Task {
+	return j.dependencies
+}
+
+// Execute compiles the Java sources against the configured classpath and the
+// classes and JARs of the dependencies, which include the Kotlin classes of
+// the same source set
+func (j *JavaCompile) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
+	tools := j.tools()
+	if tools.Err != nil {
+		return graph.TaskResult{Error: tools.Err}
+	}
+
+	classesDir := filepath.Join(workDir, "classes")
+	if err := os.MkdirAll(classesDir, 0755); err != nil {
+		return graph.TaskResult{Error: fmt.Errorf("failed to create classes directory: %w", err)}
+	}
+
+	var classpath []string
+	for _, entry := range j.classpath {
+		path, err := graph.Input(ctx, entry)
+		if err != nil {
+			return graph.TaskResult{Error: err}
+		}
+		classpath = append(classpath, path)
+	}
+	for _, dep := range dependencyInputs {
+		depClassesDir := filepath.Join(dep.OutputDir, "classes")
+		if _, err := os.Stat(depClassesDir); err == nil {
+			classpath = append(classpath, depClassesDir)
+		}
+		for _, file := range dep.Files {
+			if !strings.HasSuffix(file, ".jar") {
+				continue
+			}
+			jarPath := file
+			if !filepath.IsAbs(file) {
+				jarPath = filepath.Join(dep.OutputDir, file)
+			}
+			if _, err := os.Stat(jarPath); err == nil {
+				classpath = append(classpath, jarPath)
+			}
+		}
+	}
+
+	if err := graph.CheckStaged(ctx, classpath...); err != nil {
+		return graph.TaskResult{Error: err}
+	}
+
+	args := []string{"-d", classesDir}
+	if len(classpath) > 0 {
+		args = append(args, "-classpath", strings.Join(classpath, ":"))
+	}
+	for _, file := range j.javaFiles {
+		sourcePath, err := graph.Input(ctx, filepath.Join(j.sourceDir, file))
+		if err != nil {
+			return graph.TaskResult{Error: err}
+		}
+		args = append(args, sourcePath)
+	}
+
+	cmd := graph.Command(ctx, tools.Javac.Path, args...)
+	cmd.Dir = graph.ToolDir(ctx, workDir)
+	cmd.Stdout = graph.TaskLog(ctx)
+	cmd.Stderr = cmd.Stdout
+
+	// Compiler diagnostics end up in the task log
+	if err := cmd.Run(); err != nil {
+		return graph.TaskResult{Error: fmt.Errorf("java compilation failed: %w", err)}
+	}
+
+	var classFiles []string
+	err := filepath.Walk(classesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".class") {
+			relPath, err := filepath.Rel(workDir, path)
+			if err != nil {
+				return err
+			}
+			classFiles = append(classFiles, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return graph.TaskResult{Error: fmt.Errorf("failed to enumerate class files: %w", err)}
+	}
+
+	return graph.TaskResult{
+		Files: classFiles,
+		ABI:   classesABI(workDir, classFiles),
+	}
+}
+
+// classesABI returns the ABI fingerprint of the class files, or an empty
+// string if one of them can't be parsed
+func classesABI(workDir string, classFiles []string) string {
+	var abis []string
+	for _, file := range classFiles {
+		class, err := classfile.ParseFile(filepath.Join(workDir, file))
+		if err != nil {
+			return ""
+		}
+		if !class.Private {
+			abis = append(abis, class.Name+" "+class.ABI())
+		}
+	}
+	sort.Strings(abis)
+
+	h := sha256.New()
+	for _, abi := range abis {
+		fmt.Fprintf(h, "%s\n", abi)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// ConsumesABI marks the task as only depending on the ABI of the classes it
+// compiles against, so that it isn't recompiled when only their implementation changes
+func (j *JavaCompile) ConsumesABI() {}
+
+// SetToolchain sets the JDK whose javac compiles the sources
+func (j *JavaCompile) SetToolchain(tools *toolchain.Toolchain) {
+	j.toolchain = tools
+}
+
+// tools returns the toolchain of this task, defaulting to the tools on the PATH
+func (j *JavaCompile) tools() *toolchain.Toolchain {
+	if j.toolchain == nil {
+		return toolchain.Default()
+	}
+	return j.toolchain
+}
+
+// SetClasspath sets the classpath for compilation
+func (j *JavaCompile) SetClasspath(classpath []string) {
+	j.classpath = classpath
+}
+
+// GetSourceDir returns the source directory
+func (j *JavaCompile) GetSourceDir() string {
+	return j.sourceDir
+}
+
+// GetJavaFiles returns the list of Java files
+func (j *JavaCompile) GetJavaFiles() []string {
+	return j.javaFiles
+}
+
+// AddDependency adds a task as a dependency
+func (j *JavaCompile) AddDependency(task graph.Task) {
+	j.dependencies = append(j.dependencies, task)
+}
+
+// DisplayName returns a detailed display name
+func (j *JavaCompile) DisplayName() string {
+	return j.Name()
+}
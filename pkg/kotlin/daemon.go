@@ -0,0 +1,321 @@
+package kotlin
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fbs/pkg/toolchain"
+)
+
+// daemonSource is the compiler daemon, which the JVM runs from source
+//
+//go:embed daemon/FbsKotlinDaemon.java
+var daemonSource []byte
+
+const (
+	// daemonIdleTimeout is how long a daemon stays alive without requests
+	daemonIdleTimeout = 3 * time.Hour
+	// daemonStartTimeout is how long starting a daemon may take
+	daemonStartTimeout = 60 * time.Second
+	// daemonDialTimeout limits connecting to a daemon that is listening
+	daemonDialTimeout = 2 * time.Second
+)
+
+// CompilerDaemon compiles Kotlin in persistent JVMs that keep the compiler
+// loaded, so that only the first compilation pays for the JVM startup. Each
+// daemon compiles one source root at a time, so parallel tasks are spread over
+// a pool of daemons. Daemons outlive the fbs process and are reused by later
+// runs until they have been idle for daemonIdleTimeout.
+type CompilerDaemon struct {
+	dir        string
+	java       string
+	kotlinHome string
+	key        string
+
+	mu    sync.Mutex
+	idle  []int // slots whose daemon isn't compiling for this process
+	slots int   // number of slots handed out so far
+}
+
+// daemonState is the state file a daemon writes once it is listening
+type daemonState struct {
+	Port  int    `json:"port"`
+	PID   int    `json:"pid"`
+	Token string `json:"token"`
+}
+
+var (
+	daemons   = make(map[string]*CompilerDaemon)
+	daemonsMu sync.Mutex
+)
+
+// DaemonDirectory returns the directory holding the state of running compiler daemons
+func DaemonDirectory() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".fbs", "daemons"), nil
+}
+
+// compilerDaemon returns the daemon pool for a toolchain, shared by all tasks of the process
+func compilerDaemon(tools *toolchain.Toolchain) (*CompilerDaemon, error) {
+	if tools.KotlinHome == "" {
+		return nil, fmt.Errorf("kotlin home of %s is unknown", tools.Kotlinc.Path)
+	}
+	if _, err := os.Stat(compilerJar(tools.KotlinHome)); err != nil {
+		return nil, fmt.Errorf("kotlin compiler not found: %w", err)
+	}
+	dir, err := DaemonDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	// Daemons are shared by every run that uses the same compiler and JVM
+	h := sha256.New()
+	h.Write(daemonSource)
+	h.Write([]byte(tools.KotlinHome))
+	h.Write([]byte(tools.Kotlinc.Version))
+	h.Write([]byte(tools.Java.Path))
+	h.Write([]byte(tools.Java.Version))
+	key := fmt.Sprintf("%x", h.Sum(nil))[:16]
+
+	daemonsMu.Lock()
+	defer daemonsMu.Unlock()
+	if daemon, exists := daemons[key]; exists {
+		return daemon, nil
+	}
+
+	daemon := &CompilerDaemon{
+		dir:        dir,
+		java:       tools.Java.Path,
+		kotlinHome: tools.KotlinHome,
+		key:        key,
+	}
+	daemons[key] = daemon
+	return daemon, nil
+}
+
+// Compile runs the compiler with args in a daemon and copies its output to out.
+// It returns the compiler's exit code, or an error if no daemon could compile.
+func (d *CompilerDaemon) Compile(ctx context.Context, args []string, out io.Writer) (int, error) {
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "\r\n") {
+			return 0, fmt.Errorf("argument %q can't be sent to the compiler daemon", arg)
+		}
+	}
+
+	slot := d.acquire()
+	defer d.release(slot)
+
+	conn, token, err := d.connect(ctx, slot)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	// Give up on the response when the task is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	request := []string{token, "compile", strconv.Itoa(len(args))}
+	request = append(request, args...)
+	if _, err := io.WriteString(conn, strings.Join(request, "\n")+"\n"); err != nil {
+		return 0, fmt.Errorf("failed to send request to compiler daemon: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	exitLine, err := reader.ReadString('\n')
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("failed to read response of compiler daemon: %w", err)
+	}
+	exitCode, err := strconv.Atoi(strings.TrimSpace(exitLine))
+	if err != nil {
+		return 0, fmt.Errorf("invalid response of compiler daemon: %q", exitLine)
+	}
+	if _, err := io.Copy(out, reader); err != nil && ctx.Err() == nil {
+		return 0, fmt.Errorf("failed to read output of compiler daemon: %w", err)
+	}
+	return exitCode, ctx.Err()
+}
+
+// acquire returns a slot whose daemon this process isn't using
+func (d *CompilerDaemon) acquire() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.idle) > 0 {
+		slot := d.idle[len(d.idle)-1]
+		d.idle = d.idle[:len(d.idle)-1]
+		return slot
+	}
+	d.slots++
+	return d.slots - 1
+}
+
+// release makes a slot available to other tasks
+func (d *CompilerDaemon) release(slot int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.idle = append(d.idle, slot)
+}
+
+// statePath returns the state file of the daemon in a slot
+func (d *CompilerDaemon) statePath(slot int) string {
+	return filepath.Join(d.dir, fmt.Sprintf("kotlin-%s-%d.json", d.key, slot))
+}
+
+// connect connects to the daemon in a slot, starting it if it isn't running
+func (d *CompilerDaemon) connect(ctx context.Context, slot int) (net.Conn, string, error) {
+	if state, err := readDaemonState(d.statePath(slot)); err == nil {
+		if conn, err := dialDaemon(ctx, state); err == nil {
+			return conn, state.Token, nil
+		}
+	}
+
+	state, err := d.start(ctx, slot)
+	if err != nil {
+		return nil, "", err
+	}
+	conn, err := dialDaemon(ctx, state)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to compiler daemon: %w", err)
+	}
+	return conn, state.Token, nil
+}
+
+// start launches the daemon for a slot and waits until it is listening
+func (d *CompilerDaemon) start(ctx context.Context, slot int) (*daemonState, error) {
+	if err := os.MkdirAll(d.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create daemon directory: %w", err)
+	}
+
+	// The JVM compiles the daemon from source when it starts
+	sourcePath := filepath.Join(d.dir, "FbsKotlinDaemon-"+d.key+".java")
+	if err := os.WriteFile(sourcePath, daemonSource, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write compiler daemon: %w", err)
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate daemon token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	statePath := d.statePath(slot)
+	os.Remove(statePath)
+
+	logFile, err := os.Create(filepath.Join(d.dir, fmt.Sprintf("kotlin-%s-%d.log", d.key, slot)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create daemon log: %w", err)
+	}
+	defer logFile.Close()
+
+	// The daemon must outlive this process, so it isn't tied to ctx
+	cmd := exec.Command(d.java,
+		"-Dkotlin.home="+d.kotlinHome,
+		"-cp", compilerJar(d.kotlinHome),
+		sourcePath, statePath, strconv.Itoa(int(daemonIdleTimeout.Seconds())))
+	cmd.Stdin = strings.NewReader(token + "\n")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachDaemon(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start compiler daemon: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	deadline := time.NewTimer(daemonStartTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if state, err := readDaemonState(statePath); err == nil && state.Token == token {
+			return state, nil
+		}
+
+		select {
+		case err := <-exited:
+			return nil, fmt.Errorf("compiler daemon exited during startup (see %s): %v", logFile.Name(), err)
+		case <-deadline.C:
+			cmd.Process.Kill()
+			return nil, fmt.Errorf("compiler daemon didn't start within %s (see %s)", daemonStartTimeout, logFile.Name())
+		case <-ctx.Done():
+			cmd.Process.Kill()
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// StopDaemons shuts down every compiler daemon with a state file in dir and
+// returns how many were stopped
+func StopDaemons(dir string) (int, error) {
+	statePaths, err := filepath.Glob(filepath.Join(dir, "kotlin-*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list compiler daemons: %w", err)
+	}
+
+	stopped := 0
+	for _, statePath := range statePaths {
+		state, err := readDaemonState(statePath)
+		if err == nil {
+			if conn, err := dialDaemon(context.Background(), state); err == nil {
+				io.WriteString(conn, state.Token+"\nshutdown\n")
+				io.Copy(io.Discard, conn)
+				conn.Close()
+				stopped++
+			}
+		}
+		os.Remove(statePath)
+	}
+	return stopped, nil
+}
+
+// readDaemonState reads the state file of a daemon
+func readDaemonState(path string) (*daemonState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state daemonState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid daemon state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// dialDaemon connects to a daemon's loopback port
+func dialDaemon(ctx context.Context, state *daemonState) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: daemonDialTimeout}
+	return dialer.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(state.Port)))
+}
+
+// compilerJar returns the path of the compiler in a Kotlin distribution
+func compilerJar(kotlinHome string) string {
+	return filepath.Join(kotlinHome, "lib", "kotlin-compiler.jar")
+}
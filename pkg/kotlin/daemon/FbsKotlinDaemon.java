@@ -0,0 +1,141 @@
+import java.io.BufferedReader;
+import java.io.ByteArrayOutputStream;
+import java.io.IOException;
+import java.io.InputStreamReader;
+import java.io.OutputStream;
+import java.io.PrintStream;
+import java.lang.reflect.Method;
+import java.net.InetAddress;
+import java.net.ServerSocket;
+import java.net.Socket;
+import java.net.SocketTimeoutException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.Paths;
+import java.nio.file.StandardCopyOption;
+
+/**
+ * Kotlin compiler daemon started by fbs. It keeps the compiler loaded in a
+ * long-lived JVM and compiles one request at a time on a loopback port. It
+ * exits when it has been idle for the given number of seconds or when fbs asks
+ * it to shut down.
+ *
+ * Usage: java -cp kotlin-compiler.jar FbsKotlinDaemon.java STATE_FILE IDLE_SECONDS
+ *
+ * The token clients must send is read from the first line of standard input.
+ * Once listening, the daemon writes its port, process ID and token to
+ * STATE_FILE.
+ *
+ * A request is the token, the command ("compile" or "shutdown"), the number of
+ * compiler arguments and the arguments, each on its own line. The response is
+ * the compiler's exit code on its own line followed by the compiler output.
+ */
+public class FbsKotlinDaemon {
+    private static final int ACCEPT_TIMEOUT_MILLIS = 60_000;
+    private static final int REQUEST_TIMEOUT_MILLIS = 30_000;
+    private static final int INTERNAL_ERROR = 2;
+
+    public static void main(String[] args) throws Exception {
+        Path stateFile = Paths.get(args[0]);
+        long idleMillis = Long.parseLong(args[1]) * 1000;
+        String token = new BufferedReader(new InputStreamReader(System.in, StandardCharsets.UTF_8)).readLine();
+
+        Class<?> compilerClass = Class.forName("org.jetbrains.kotlin.cli.jvm.K2JVMCompiler");
+        Method exec = compilerClass.getMethod("exec", PrintStream.class, String[].class);
+
+        try (ServerSocket server = new ServerSocket(0, 50, InetAddress.getLoopbackAddress())) {
+            server.setSoTimeout(ACCEPT_TIMEOUT_MILLIS);
+            writeState(stateFile, server.getLocalPort(), token);
+
+            long lastUsed = System.currentTimeMillis();
+            while (true) {
+                Socket socket;
+                try {
+                    socket = server.accept();
+                } catch (SocketTimeoutException e) {
+                    if (System.currentTimeMillis() - lastUsed > idleMillis) {
+                        break;
+                    }
+                    continue;
+                }
+
+                try (Socket connection = socket) {
+                    if (!handle(connection, token, compilerClass, exec)) {
+                        break;
+                    }
+                } catch (IOException e) {
+                    // The client went away, for example because its build was cancelled
+                }
+                lastUsed = System.currentTimeMillis();
+            }
+        } finally {
+            removeState(stateFile, token);
+        }
+        System.exit(0);
+    }
+
+    /** Handles a request and returns false if the daemon should shut down. */
+    private static boolean handle(Socket socket, String token, Class<?> compilerClass, Method exec) throws IOException {
+        socket.setSoTimeout(REQUEST_TIMEOUT_MILLIS);
+        BufferedReader in = new BufferedReader(new InputStreamReader(socket.getInputStream(), StandardCharsets.UTF_8));
+        OutputStream out = socket.getOutputStream();
+
+        if (!token.equals(in.readLine())) {
+            return true;
+        }
+        String command = in.readLine();
+        if ("shutdown".equals(command)) {
+            out.write("0\n".getBytes(StandardCharsets.UTF_8));
+            out.flush();
+            return false;
+        }
+        if (!"compile".equals(command)) {
+            return true;
+        }
+
+        int count = Integer.parseInt(in.readLine());
+        String[] compilerArgs = new String[count];
+        for (int i = 0; i < count; i++) {
+            compilerArgs[i] = in.readLine();
+        }
+
+        ByteArrayOutputStream output = new ByteArrayOutputStream();
+        int code;
+        try (PrintStream printStream = new PrintStream(output, true, "UTF-8")) {
+            try {
+                Object compiler = compilerClass.getDeclaredConstructor().newInstance();
+                Object exitCode = exec.invoke(compiler, printStream, compilerArgs);
+                code = (Integer) exitCode.getClass().getMethod("getCode").invoke(exitCode);
+            } catch (Throwable t) {
+                t.printStackTrace(printStream);
+                code = INTERNAL_ERROR;
+            }
+        }
+
+        out.write((code + "\n").getBytes(StandardCharsets.UTF_8));
+        output.writeTo(out);
+        out.flush();
+        return true;
+    }
+
+    /** Writes the state file atomically so that clients never read a partial file. */
+    private static void writeState(Path stateFile, int port, String token) throws IOException {
+        String state = String.format("{\"port\":%d,\"pid\":%d,\"token\":\"%s\"}%n", port, ProcessHandle.current().pid(), token);
+        Path temp = stateFile.resolveSibling(stateFile.getFileName() + ".tmp");
+        Files.write(temp, state.getBytes(StandardCharsets.UTF_8));
+        Files.move(temp, stateFile, StandardCopyOption.REPLACE_EXISTING, StandardCopyOption.ATOMIC_MOVE);
+    }
+
+    /** Removes the state file unless another daemon has replaced it in the meantime. */
+    private static void removeState(Path stateFile, String token) {
+        try {
+            String state = new String(Files.readAllBytes(stateFile), StandardCharsets.UTF_8);
+            if (state.contains("\"" + token + "\"")) {
+                Files.delete(stateFile);
+            }
+        } catch (IOException e) {
+            // Already removed
+        }
+    }
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package kotlin
+
+import (
+	"os/exec"
+)
+
+// detachDaemon keeps the default process attributes on platforms without sessions
+func detachDaemon(cmd *exec.Cmd) {
+}
@@ -0,0 +1,115 @@
+package kotlin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeDaemon accepts one connection, records the request and replies with response
+func fakeDaemon(t *testing.T, statePath, token, response string) <-chan []string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	state, _ := json.Marshal(daemonState{Port: listener.Addr().(*net.TCPAddr).Port, PID: os.Getpid(), Token: token})
+	if err := os.WriteFile(statePath, state, 0600); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	requests := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		var request []string
+		readLine := func() string {
+			line, _ := reader.ReadString('\n')
+			line = strings.TrimSuffix(line, "\n")
+			request = append(request, line)
+			return line
+		}
+		readLine()
+		if readLine() == "compile" {
+			count, _ := strconv.Atoi(readLine())
+			for i := 0; i < count; i++ {
+				readLine()
+			}
+		}
+		conn.Write([]byte(response))
+		requests <- request
+	}()
+	return requests
+}
+
+func TestCompilerDaemon_Compile(t *testing.T) {
+	daemon := &CompilerDaemon{dir: t.TempDir(), key: "test"}
+	requests := fakeDaemon(t, daemon.statePath(0), "secret", "1\nerror: unresolved reference\n")
+
+	var out bytes.Buffer
+	exitCode, err := daemon.Compile(context.Background(), []string{"-d", "classes", "Main.kt"}, &out)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if out.String() != "error: unresolved reference\n" {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+
+	expected := []string{"secret", "compile", "3", "-d", "classes", "Main.kt"}
+	if request := <-requests; fmt.Sprint(request) != fmt.Sprint(expected) {
+		t.Errorf("Expected request %q, got %q", expected, request)
+	}
+}
+
+func TestCompilerDaemon_RejectsMultilineArguments(t *testing.T) {
+	daemon := &CompilerDaemon{dir: t.TempDir(), key: "test"}
+	if _, err := daemon.Compile(context.Background(), []string{"a\nb"}, &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an argument containing a newline")
+	}
+}
+
+func TestStopDaemons(t *testing.T) {
+	dir := t.TempDir()
+	requests := fakeDaemon(t, filepath.Join(dir, "kotlin-test-0.json"), "secret", "0\n")
+
+	// A daemon that is no longer running only leaves its state file behind
+	stalePath := filepath.Join(dir, "kotlin-test-1.json")
+	if err := os.WriteFile(stalePath, []byte(`{"port":1,"pid":1,"token":"stale"}`), 0600); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	stopped, err := StopDaemons(dir)
+	if err != nil {
+		t.Fatalf("StopDaemons failed: %v", err)
+	}
+	if stopped != 1 {
+		t.Errorf("Expected 1 stopped daemon, got %d", stopped)
+	}
+	if request := <-requests; fmt.Sprint(request) != fmt.Sprint([]string{"secret", "shutdown"}) {
+		t.Errorf("Unexpected shutdown request %q", request)
+	}
+
+	remaining, _ := filepath.Glob(filepath.Join(dir, "kotlin-*.json"))
+	if len(remaining) != 0 {
+		t.Errorf("Expected state files to be removed, found %v", remaining)
+	}
+}
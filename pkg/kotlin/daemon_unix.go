@@ -0,0 +1,14 @@
+//go:build unix
+
+package kotlin
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachDaemon starts the daemon in its own session, so that it survives the
+// fbs process and doesn't receive the Ctrl-C meant for the build
+func detachDaemon(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
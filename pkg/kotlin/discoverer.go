@@ -9,6 +9,8 @@ import (
 
 	"fbs/pkg/discoverer"
 	"fbs/pkg/graph"
+	"fbs/pkg/java"
+	"fbs/pkg/toolchain"
 )
 
 // KotlinDiscoverer discovers Kotlin compilation tasks from directories
@@ -93,6 +95,20 @@ func (d *KotlinDiscoverer) Discover(ctx context.Context, path string, potentialD
 	
 	// Create Kotlin compilation task
 	task := NewKotlinCompile(searchDir, kotlinFiles)
+	task.SetToolchain(toolchain.FromBuildContext(buildContext))
+	
+	// Kotlin code of a source set can use its Java code, which kotlinc reads from source
+	if isSourceRoot {
+		javaSources, err := d.findJavaSources(searchDir)
+		if err != nil {
+			return &discoverer.DiscoveryResult{
+				Tasks:  []graph.Task{},
+				Errors: []error{err},
+				Path:   path,
+			}, nil
+		}
+		task.SetJavaSources(javaSources)
+	}
 	
 	// Add potential dependencies as dependencies for this task
 	// Filter to only include other Kotlin compilation tasks as dependencies
@@ -129,14 +145,34 @@ func (d *KotlinDiscoverer) findKotlinFiles(dir string) ([]string, error) {
 	return kotlinFiles, nil
 }
 
-// isSourceRoot checks if the given directory is a Kotlin source root
+// isSourceRoot checks if the given directory is a Kotlin source root. Like
+// Gradle, Kotlin files in Java source roots are compiled as well.
 func (d *KotlinDiscoverer) isSourceRoot(dir string) bool {
 	// Check if the directory ends with common Kotlin source root patterns
 	return strings.HasSuffix(dir, "/src/main/kotlin") ||
 		strings.HasSuffix(dir, "/src/test/kotlin") ||
 		strings.HasSuffix(dir, "/src/dev/kotlin") ||
 		strings.HasSuffix(dir, "/src/testFixtures/kotlin") ||
-		strings.HasSuffix(dir, "/src/integrationTest/kotlin")
+		strings.HasSuffix(dir, "/src/integrationTest/kotlin") ||
+		java.IsSourceRoot(dir)
+}
+
+// findJavaSources returns the absolute paths of the Java files in the Java
+// source root of the source set that a Kotlin source root belongs to
+func (d *KotlinDiscoverer) findJavaSources(sourceRoot string) ([]string, error) {
+	javaRoot := filepath.Join(filepath.Dir(sourceRoot), "java")
+	if _, err := os.Stat(javaRoot); os.IsNotExist(err) {
+		return nil, nil
+	}
+	
+	javaFiles, err := java.FindJavaFiles(javaRoot)
+	if err != nil {
+		return nil, err
+	}
+	for i, file := range javaFiles {
+		javaFiles[i] = filepath.Join(javaRoot, file)
+	}
+	return javaFiles, nil
 }
 
 // isPartOfSourceTree checks if a directory appears to be part of a larger source tree
@@ -0,0 +1,488 @@
+package kotlin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fbs/pkg/classfile"
+	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
+)
+
+// incrementalStateFile records in the output of a KotlinCompile what each
+// source file compiled to, so that its next execution can recompile only
+// what changed
+const incrementalStateFile = "kotlin-incremental.json"
+
+// incrementalStateVersion is bumped whenever the state format or the
+// recompilation rules change, forcing a full build
+const incrementalStateVersion = 1
+
+// maxIncrementalRounds limits how often dependents are recompiled because ABIs
+// changed before the whole source root is recompiled instead
+const maxIncrementalRounds = 10
+
+var (
+	// packagePattern matches the package declaration of a Kotlin file
+	packagePattern = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)`)
+	// inlinePattern matches declarations whose bodies are copied into their callers
+	inlinePattern = regexp.MustCompile(`\binline\b`)
+)
+
+// incrementalState is what a KotlinCompile knows about its output
+type incrementalState struct {
+	Version int `json:"version"`
+	// Environment identifies the compiler, JDK and classpath. The output can
+	// only be reused by an execution with the same environment.
+	Environment string `json:"environment"`
+	// Sources maps the Kotlin files, relative to the source directory, to what they compiled to
+	Sources map[string]*sourceState `json:"sources"`
+}
+
+// sourceState records what a single source file compiled to
+type sourceState struct {
+	// Digest is the digest of the source file's contents
+	Digest string `json:"digest"`
+	// Classes are the class files compiled from the source, relative to the classes directory
+	Classes []string `json:"classes"`
+	// ABI is the digest of the ABI of the classes
+	ABI string `json:"abi"`
+	// References are the classes that the classes of the source refer to
+	References []string `json:"references"`
+	// Inlined is whether other sources may contain copies of constants or
+	// inline functions of this source, which leave no reference to it behind
+	Inlined bool `json:"inlined"`
+	// Facade is whether the source has top-level declarations
+	Facade bool `json:"facade"`
+}
+
+// compilation is a single execution of a KotlinCompile
+type compilation struct {
+	task       *KotlinCompile
+	tools      *toolchain.Toolchain
+	workDir    string
+	classesDir string
+	classpath  []string
+	sources    map[string]string // path to compile of each Kotlin file, which is a staged copy in sandbox mode
+	digests    map[string]string // digest of each Kotlin file
+	// javaSources are the paths of the Java files that kotlinc reads along
+	// with the Kotlin files, which are staged copies in sandbox mode
+	javaSources []string
+}
+
+// run compiles the sources, recompiling only what changed since the previous
+// execution where possible, and records the incremental state in the work
+// directory. It returns the ABI fingerprint of the classes, or an empty string
+// if the classes couldn't be attributed to their sources.
+func (c *compilation) run(ctx context.Context) (string, error) {
+	environment := c.environment()
+	taskLog := graph.TaskLog(ctx)
+
+	var state *incrementalState
+	if previous := loadIncrementalState(graph.PreviousOutput(ctx), environment); previous != nil {
+		var err error
+		state, err = c.incremental(ctx, previous)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			fmt.Fprintf(taskLog, "Incremental compilation failed, recompiling all sources: %v\n", err)
+			state = nil
+		}
+	}
+
+	if state == nil {
+		var err error
+		state, err = c.full(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Without a state the next execution is a full build
+	if state == nil {
+		return "", nil
+	}
+	state.Environment = environment
+	if err := writeIncrementalState(c.workDir, state); err != nil {
+		return "", err
+	}
+	return state.abi(), nil
+}
+
+// abi returns the ABI fingerprint of the classes of all sources
+func (s *incrementalState) abi() string {
+	abis := make([]string, 0, len(s.Sources))
+	for _, source := range s.Sources {
+		abis = append(abis, source.ABI)
+	}
+	return graph.CombineABI(abis...)
+}
+
+// full compiles every source from scratch. It returns no state if the
+// classes can't be attributed to their sources.
+func (c *compilation) full(ctx context.Context) (*incrementalState, error) {
+	if err := os.RemoveAll(c.classesDir); err != nil {
+		return nil, fmt.Errorf("failed to clean classes directory: %w", err)
+	}
+	if err := os.MkdirAll(c.classesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create classes directory: %w", err)
+	}
+
+	if err := c.compile(ctx, c.task.kotlinFiles, false); err != nil {
+		return nil, err
+	}
+
+	state := &incrementalState{Version: incrementalStateVersion, Sources: make(map[string]*sourceState)}
+	if err := c.attribute(state, c.task.kotlinFiles); err != nil {
+		fmt.Fprintf(graph.TaskLog(ctx), "Next compilation won't be incremental: %v\n", err)
+		return nil, nil
+	}
+	return state, nil
+}
+
+// incremental recompiles the changed sources on top of the previous output,
+// and then the sources using classes whose ABI changed until no more ABIs
+// change. It returns no state if a full build is needed instead.
+func (c *compilation) incremental(ctx context.Context, previous *incrementalState) (*incrementalState, error) {
+	state := &incrementalState{Version: incrementalStateVersion, Sources: make(map[string]*sourceState)}
+	var changed, removed []string
+	for _, file := range c.task.kotlinFiles {
+		if source, exists := previous.Sources[file]; exists && source.Digest == c.digests[file] {
+			state.Sources[file] = source
+		} else {
+			changed = append(changed, file)
+		}
+	}
+	for file := range previous.Sources {
+		if _, exists := c.digests[file]; !exists {
+			removed = append(removed, file)
+		}
+	}
+	sort.Strings(removed)
+
+	// Start from the classes of the unchanged sources
+	prevClassesDir := filepath.Join(graph.PreviousOutput(ctx), "classes")
+	for _, source := range state.Sources {
+		for _, class := range source.Classes {
+			if err := copyFile(filepath.Join(prevClassesDir, class), filepath.Join(c.classesDir, class)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	moduleFiles, _ := filepath.Glob(filepath.Join(prevClassesDir, "META-INF", "*.kotlin_module"))
+
+	fmt.Fprintf(graph.TaskLog(ctx), "Incremental compilation: %d changed, %d removed of %d sources\n", len(changed), len(removed), len(c.task.kotlinFiles))
+
+	// The ABI of each source when the sources using it were last compiled
+	abis := make(map[string]string, len(previous.Sources))
+	for file, source := range previous.Sources {
+		abis[file] = source.ABI
+	}
+
+	affected := removed
+	batch := changed
+	for round := 1; len(batch) > 0 || len(affected) > 0; round++ {
+		if round > maxIncrementalRounds {
+			fmt.Fprintf(graph.TaskLog(ctx), "Recompiling all sources: ABI changes didn't settle after %d rounds\n", maxIncrementalRounds)
+			return nil, nil
+		}
+		inBatch := make(map[string]bool, len(batch))
+		if len(batch) > 0 {
+			if err := c.compile(ctx, batch, true); err != nil {
+				return nil, err
+			}
+			if err := c.attribute(state, batch); err != nil {
+				fmt.Fprintf(graph.TaskLog(ctx), "Recompiling all sources: %v\n", err)
+				return nil, nil
+			}
+			for _, file := range batch {
+				inBatch[file] = true
+			}
+		}
+
+		// Collect the classes whose ABI changed with this batch. New sources
+		// can only be used by sources that were compiled together with them.
+		changedClasses := make(map[string]bool)
+		for _, file := range append(batch, affected...) {
+			before, after := previous.Sources[file], state.Sources[file]
+			abi, known := abis[file]
+			if after != nil {
+				abis[file] = after.ABI
+			}
+			if !known || (after != nil && after.ABI == abi) {
+				continue
+			}
+			if (before != nil && before.Inlined) || (after != nil && after.Inlined) {
+				fmt.Fprintf(graph.TaskLog(ctx), "Recompiling all sources: %s declares constants or inline functions\n", file)
+				return nil, nil
+			}
+			for _, source := range []*sourceState{before, after} {
+				if source == nil {
+					continue
+				}
+				for _, class := range source.Classes {
+					changedClasses[strings.TrimSuffix(filepath.ToSlash(class), ".class")] = true
+				}
+			}
+		}
+
+		// Recompile the sources that use them, unless they were compiled
+		// together with the changes. Sources compiled in an earlier round are
+		// recompiled if a class they use changed after them.
+		batch, affected = nil, nil
+		for _, file := range c.task.kotlinFiles {
+			source := state.Sources[file]
+			if inBatch[file] || source == nil {
+				continue
+			}
+			for _, reference := range source.References {
+				if changedClasses[reference] {
+					batch = append(batch, file)
+					if err := removeClasses(c.classesDir, source); err != nil {
+						return nil, err
+					}
+					delete(state.Sources, file)
+					break
+				}
+			}
+		}
+	}
+
+	// Top-level declarations are listed in the module file, which the compiler
+	// only writes for the sources it compiled
+	for _, file := range append(changed, removed...) {
+		before, after := previous.Sources[file], state.Sources[file]
+		if (before != nil && before.Facade) != (after != nil && after.Facade) {
+			fmt.Fprintf(graph.TaskLog(ctx), "Recompiling all sources: top-level declarations of %s were added or removed\n", file)
+			return nil, nil
+		}
+	}
+	for _, moduleFile := range moduleFiles {
+		if err := copyFile(moduleFile, filepath.Join(c.classesDir, "META-INF", filepath.Base(moduleFile))); err != nil {
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// compile runs the compiler on some of the sources. Incremental compilations
+// compile against the classes of the sources that aren't recompiled.
+func (c *compilation) compile(ctx context.Context, files []string, incremental bool) error {
+	args := []string{"-d", c.classesDir}
+	if c.tools.JavaHome != "" {
+		args = append(args, "-jdk-home", c.tools.JavaHome)
+	}
+
+	classpath := c.classpath
+	if incremental {
+		classpath = append([]string{c.classesDir}, classpath...)
+		args = append(args, "-Xfriend-paths="+c.classesDir)
+	}
+	if len(classpath) > 0 {
+		args = append(args, "-classpath", strings.Join(classpath, ":"))
+	}
+	for _, file := range files {
+		args = append(args, c.sources[file])
+	}
+	args = append(args, c.javaSources...)
+	return c.task.runCompiler(ctx, c.tools, c.workDir, args)
+}
+
+// attribute records the state of the given sources from the classes they
+// compiled to. Classes are attributed by their package and source file name.
+func (c *compilation) attribute(state *incrementalState, files []string) error {
+	owned := make(map[string]bool)
+	for _, source := range state.Sources {
+		for _, class := range source.Classes {
+			owned[class] = true
+		}
+	}
+
+	// Sources are identified the same way as the classes compiled from them
+	byOrigin := make(map[string]string, len(files))
+	inlineFunctions := make(map[string]bool, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(c.sources[file])
+		if err != nil {
+			return fmt.Errorf("failed to read source file: %w", err)
+		}
+		pkg := ""
+		if matches := packagePattern.FindSubmatch(data); matches != nil {
+			pkg = strings.ReplaceAll(string(matches[1]), ".", "/")
+		}
+		origin := pkg + "/" + filepath.Base(file)
+		if _, exists := byOrigin[origin]; exists {
+			return fmt.Errorf("%s and %s have the same package and file name", byOrigin[origin], file)
+		}
+		byOrigin[origin] = file
+		inlineFunctions[file] = inlinePattern.Match(data)
+		state.Sources[file] = &sourceState{
+			Digest:  c.digests[file],
+			Inlined: inlineFunctions[file],
+		}
+	}
+
+	classes := make(map[string][]*classfile.Class)
+	err := filepath.Walk(c.classesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".class") {
+			return err
+		}
+		relPath, err := filepath.Rel(c.classesDir, path)
+		if err != nil || owned[relPath] {
+			return err
+		}
+
+		class, err := classfile.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		file, exists := byOrigin[class.Package()+"/"+class.SourceFile]
+		if !exists {
+			return fmt.Errorf("no source file found for %s", relPath)
+		}
+		source := state.Sources[file]
+		source.Classes = append(source.Classes, relPath)
+		source.Facade = source.Facade || class.KotlinFacade
+		source.Inlined = source.Inlined || class.HasConstants
+		classes[file] = append(classes[file], class)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		state.Sources[file].ABI, state.Sources[file].References = sourceABI(classes[file], inlineFunctions[file], c.classesDir)
+	}
+	return nil
+}
+
+// sourceABI returns the ABI of the classes of a source file and the classes
+// they refer to. The ABI of sources with inline functions covers their entire
+// bytecode, since callers contain copies of the function bodies.
+func sourceABI(classes []*classfile.Class, inlineFunctions bool, classesDir string) (string, []string) {
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Name < classes[j].Name
+	})
+
+	h := sha256.New()
+	references := make(map[string]bool)
+	for _, class := range classes {
+		for _, reference := range class.References() {
+			references[reference] = true
+		}
+		if class.Private {
+			continue
+		}
+		fmt.Fprintf(h, "%s %s\n", class.Name, class.ABI())
+		if inlineFunctions {
+			if digest, err := graph.FileDigest(filepath.Join(classesDir, class.Name+".class")); err == nil {
+				h.Write([]byte(digest))
+			}
+		}
+	}
+
+	// References between the classes of the source itself don't matter
+	for _, class := range classes {
+		delete(references, class.Name)
+	}
+	sorted := make([]string, 0, len(references))
+	for reference := range references {
+		sorted = append(sorted, reference)
+	}
+	sort.Strings(sorted)
+	return fmt.Sprintf("%x", h.Sum(nil)), sorted
+}
+
+// environment returns a digest of what every class depends on: the compiler,
+// the JDK, the classpath and the Java sources. Java sources aren't tracked
+// like Kotlin sources, so changing them recompiles all Kotlin sources.
+func (c *compilation) environment() string {
+	h := sha256.New()
+	h.Write([]byte(c.tools.Kotlinc.Fingerprint()))
+	h.Write([]byte(c.tools.Java.Fingerprint()))
+	for _, entry := range c.classpath {
+		fmt.Fprintf(h, "\n%s", entry)
+	}
+	for _, file := range c.task.javaSources {
+		digest, _ := graph.FileDigest(file)
+		fmt.Fprintf(h, "\n%s %s", file, digest)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// loadIncrementalState reads the state of a previous output, returning nil if
+// there is none or it was compiled in another environment
+func loadIncrementalState(outputDir, environment string) *incrementalState {
+	if outputDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, incrementalStateFile))
+	if err != nil {
+		return nil
+	}
+	var state incrementalState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+	if state.Version != incrementalStateVersion || state.Environment != environment {
+		return nil
+	}
+	return &state
+}
+
+// writeIncrementalState writes the state next to the classes directory
+func writeIncrementalState(workDir string, state *incrementalState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode incremental state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, incrementalStateFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write incremental state: %w", err)
+	}
+	return nil
+}
+
+// removeClasses deletes the classes of a source that is recompiled
+func removeClasses(classesDir string, source *sourceState) error {
+	for _, class := range source.Classes {
+		if err := os.Remove(filepath.Join(classesDir, class)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale class: %w", err)
+		}
+	}
+	return nil
+}
+
+// copyFile copies a file of the previous output, which belongs to the cache
+// and must not be linked into the new output
+func copyFile(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to copy previous output: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to copy previous output: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy previous output: %w", err)
+	}
+	return out.Close()
+}
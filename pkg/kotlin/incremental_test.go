@@ -0,0 +1,218 @@
+package kotlin
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
+)
+
+// TestMain lets the test binary stand in for kotlinc
+func TestMain(m *testing.M) {
+	if os.Getenv("FBS_FAKE_KOTLINC") != "" {
+		if err := fakeKotlinc(os.Args[1:]); err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeClassPattern declares the classes a fake source compiles to
+var fakeClassPattern = regexp.MustCompile(`(?m)^// class (\w+) abi=(\w+)(?: uses=([\w/,]+))?$`)
+
+// fakeKotlinc writes a class for every "// class NAME abi=X uses=A,B" line of
+// the Kotlin sources and logs the names of the sources it compiled. Java
+// sources are only read, like kotlinc does.
+func fakeKotlinc(args []string) error {
+	var outputDir string
+	var sources []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-d":
+			outputDir = args[i+1]
+			i++
+		case args[i] == "-classpath" || args[i] == "-jdk-home":
+			i++
+		case strings.HasPrefix(args[i], "-"), strings.HasSuffix(args[i], ".java"):
+		default:
+			sources = append(sources, args[i])
+		}
+	}
+
+	var names []string
+	for _, source := range sources {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		pkg := ""
+		if matches := packagePattern.FindSubmatch(data); matches != nil {
+			pkg = strings.ReplaceAll(string(matches[1]), ".", "/") + "/"
+		}
+		for _, match := range fakeClassPattern.FindAllStringSubmatch(string(data), -1) {
+			var uses []string
+			if match[3] != "" {
+				uses = strings.Split(match[3], ",")
+			}
+			classPath := filepath.Join(outputDir, pkg+match[1]+".class")
+			if err := os.MkdirAll(filepath.Dir(classPath), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(classPath, fakeClass(pkg+match[1], filepath.Base(source), match[2], uses), 0644); err != nil {
+				return err
+			}
+		}
+		names = append(names, filepath.Base(source))
+	}
+
+	log, err := os.OpenFile(os.Getenv("FBS_FAKE_KOTLINC"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	_, err = log.WriteString(strings.Join(names, " ") + "\n")
+	return err
+}
+
+// fakeClass builds a class file whose ABI is a public method named after abi
+func fakeClass(name, sourceFile, abi string, uses []string) []byte {
+	var pool [][]byte
+	add := func(entry []byte) []byte {
+		pool = append(pool, entry)
+		return binary.BigEndian.AppendUint16(nil, uint16(len(pool)))
+	}
+	utf8 := func(s string) []byte {
+		return add(append(binary.BigEndian.AppendUint16([]byte{1}, uint16(len(s))), s...))
+	}
+	class := func(s string) []byte {
+		return add(append([]byte{7}, utf8(s)...))
+	}
+
+	this := class(name)
+	super := class("java/lang/Object")
+	for _, use := range uses {
+		class(use)
+	}
+	method := append([]byte{0, 1}, utf8("abi"+abi)...)
+	method = append(method, utf8("()V")...)
+	method = append(method, 0, 0)
+	sourceFileAttribute := append(utf8("SourceFile"), 0, 0, 0, 2)
+	sourceFileAttribute = append(sourceFileAttribute, utf8(sourceFile)...)
+
+	data := []byte{0xCA, 0xFE, 0xBA, 0xBE, 0, 0, 0, 61}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(pool)+1))
+	for _, entry := range pool {
+		data = append(data, entry...)
+	}
+	data = append(data, 0, 0x21)
+	data = append(data, this...)
+	data = append(data, super...)
+	data = append(data, 0, 0, 0, 0, 0, 1) // no interfaces or fields, one method
+	data = append(data, method...)
+	data = append(data, 0, 1)
+	return append(data, sourceFileAttribute...)
+}
+
+func TestKotlinCompile_Incremental(t *testing.T) {
+	sourceDir := t.TempDir()
+	compilerLog := filepath.Join(t.TempDir(), "kotlinc.log")
+	t.Setenv("FBS_FAKE_KOTLINC", compilerLog)
+
+	tools := &toolchain.Toolchain{
+		Kotlinc: toolchain.Tool{Name: "kotlinc", Path: os.Args[0], Version: "test"},
+		Java:    toolchain.Tool{Name: "java", Version: "test"},
+	}
+	runner := graph.NewRunner(t.TempDir())
+
+	writeSource := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte("package com.example\n"+content), 0644); err != nil {
+			t.Fatalf("Failed to write source: %v", err)
+		}
+	}
+
+	// build compiles the sources and returns the compiler invocations
+	var javaSources []string
+	build := func(files ...string) (graph.ExecutionResult, []string) {
+		t.Helper()
+		os.Remove(compilerLog)
+		task := NewKotlinCompile(sourceDir, files)
+		task.SetToolchain(tools)
+		task.SetJavaSources(javaSources)
+		result, err := runner.ExecuteTask(context.Background(), task)
+		if err != nil || result.Result.Error != nil {
+			t.Fatalf("Compilation failed: %v %v", err, result.Result.Error)
+		}
+		data, _ := os.ReadFile(compilerLog)
+		return result, strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+
+	expectInvocations := func(step string, actual []string, expected ...string) {
+		t.Helper()
+		if strings.Join(actual, "|") != strings.Join(expected, "|") {
+			t.Errorf("%s: expected compiler invocations %q, got %q", step, expected, actual)
+		}
+	}
+
+	writeSource("A.kt", "// class A abi=1\n")
+	writeSource("B.kt", "// class B abi=1 uses=com/example/A\n")
+	writeSource("C.kt", "// class C abi=1\n")
+	initial, invocations := build("A.kt", "B.kt", "C.kt")
+	expectInvocations("initial build", invocations, "A.kt B.kt C.kt")
+	if initial.Result.ABI == "" {
+		t.Error("Expected the compilation to publish an ABI fingerprint")
+	}
+
+	// A change that keeps the ABI only recompiles the changed source
+	writeSource("A.kt", "// class A abi=1\n// a new function body\n")
+	bodyChange, invocations := build("A.kt", "B.kt", "C.kt")
+	expectInvocations("body change", invocations, "A.kt")
+	if bodyChange.Result.ABI != initial.Result.ABI {
+		t.Error("Expected a body change to keep the ABI fingerprint")
+	}
+
+	// An ABI change recompiles the sources using the changed classes
+	writeSource("A.kt", "// class A abi=2\n")
+	result, invocations := build("A.kt", "B.kt", "C.kt")
+	expectInvocations("ABI change", invocations, "A.kt", "B.kt")
+	if result.Result.ABI == initial.Result.ABI {
+		t.Error("Expected an ABI change to change the ABI fingerprint")
+	}
+	for _, class := range []string{"A", "B", "C"} {
+		if _, err := os.Stat(filepath.Join(result.OutputDir, "classes", "com", "example", class+".class")); err != nil {
+			t.Errorf("Expected %s.class in the output: %v", class, err)
+		}
+	}
+
+	// Removing a source removes its classes
+	result, invocations = build("A.kt", "B.kt")
+	expectInvocations("removed source", invocations, "")
+	if _, err := os.Stat(filepath.Join(result.OutputDir, "classes", "com", "example", "C.class")); !os.IsNotExist(err) {
+		t.Errorf("Expected the class of the removed source to be removed")
+	}
+	if len(result.Result.Files) != 2 {
+		t.Errorf("Expected 2 class files, got %v", result.Result.Files)
+	}
+
+	// Callers contain copies of inline functions, so changing them recompiles everything
+	writeSource("A.kt", "// class A abi=3\ninline fun twice(block: () -> Unit) { block(); block() }\n")
+	_, invocations = build("A.kt", "B.kt")
+	expectInvocations("inline function", invocations, "A.kt", "A.kt B.kt")
+
+	// Changes to Java sources aren't tracked per class, so they recompile everything
+	javaSource := filepath.Join(t.TempDir(), "Util.java")
+	os.WriteFile(javaSource, []byte("class Util {}\n"), 0644)
+	javaSources = []string{javaSource}
+	_, invocations = build("A.kt", "B.kt")
+	expectInvocations("added Java source", invocations, "A.kt B.kt")
+	os.WriteFile(javaSource, []byte("class Util { void run() {} }\n"), 0644)
+	_, invocations = build("A.kt", "B.kt")
+	expectInvocations("changed Java source", invocations, "A.kt B.kt")
+}
@@ -9,6 +9,7 @@ import (
 
 	"fbs/pkg/discoverer"
 	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
 )
 
 // JunitDiscoverer discovers JUnit test tasks from Kotlin test files
@@ -70,6 +71,7 @@ func (d *JunitDiscoverer) Discover(ctx context.Context, path string, potentialDe
 	for _, testFile := range testFiles {
 		className := d.extractClassName(testFile)
 		task := NewJunitTest(testFile, searchDir, className)
+		task.SetToolchain(toolchain.FromBuildContext(buildContext))
 		
 		// Add potential dependencies (typically KotlinCompile tasks)
 		for _, dep := range potentialDependencies {
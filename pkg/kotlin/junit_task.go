@@ -1,15 +1,17 @@
 package kotlin
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
 )
 
 // JunitTest represents a task that runs JUnit tests for a specific Kotlin test file
@@ -18,6 +20,7 @@ type JunitTest struct {
 	sourceDir    string
 	className    string
 	dependencies []graph.Task
+	toolchain    *toolchain.Toolchain
 }
 
 // NewJunitTest creates a new JUnit test task
@@ -50,6 +53,16 @@ func (j *JunitTest) TaskType() graph.TaskType {
 	return graph.TaskTypeTest
 }
 
+// Resources returns the resources of the JVM running the tests
+func (j *JunitTest) Resources() graph.Resources {
+	return graph.Resources{CPU: 1, MemoryMB: 512}
+}
+
+// Inputs returns the absolute path of the test file this task runs
+func (j *JunitTest) Inputs() []string {
+	return []string{filepath.Join(j.sourceDir, j.testFile)}
+}
+
 // Hash returns a hash representing the task's configuration and inputs
 func (j *JunitTest) Hash() string {
 	h := sha256.New()
@@ -57,14 +70,17 @@ func (j *JunitTest) Hash() string {
 	// Include task type and test file info
 	h.Write([]byte("JunitTest"))
 	h.Write([]byte(j.testFile))
-	h.Write([]byte(j.sourceDir))
+	h.Write([]byte(graph.HashPath(j.sourceDir)))
 	h.Write([]byte(j.className))
 	
-	// Include test file modification time if file exists
-	if info, err := os.Stat(filepath.Join(j.sourceDir, j.testFile)); err == nil {
-		h.Write([]byte(fmt.Sprintf("%d", info.ModTime().Unix())))
+	// Include test file contents digest if file exists
+	if digest, err := graph.FileDigest(filepath.Join(j.sourceDir, j.testFile)); err == nil {
+		h.Write([]byte(digest))
 	}
 	
+	// Include the JVM version the tests run on
+	h.Write([]byte(j.tools().Java.Fingerprint()))
+	
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
@@ -75,6 +91,11 @@ func (j *JunitTest) Dependencies() []graph.Task {
 
 // Execute runs the JUnit test task
 func (j *JunitTest) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
+	tools := j.tools()
+	if tools.Err != nil {
+		return graph.TaskResult{Error: tools.Err}
+	}
+	
 	// Create test results directory
 	resultsDir := filepath.Join(workDir, "test-results")
 	if err := os.MkdirAll(resultsDir, 0755); err != nil {
@@ -84,10 +105,12 @@ func (j *JunitTest) Execute(ctx context.Context, workDir string, dependencyInput
 	// Build classpath from dependency inputs
 	var classpathParts []string
 	for _, dep := range dependencyInputs {
-		// Add compiled classes directories
-		classesDir := filepath.Join(dep.OutputDir, "classes")
-		if _, err := os.Stat(classesDir); err == nil {
-			classpathParts = append(classpathParts, classesDir)
+		// Add compiled classes and processed resources directories
+		for _, dirName := range []string{"classes", "resources"} {
+			outputDir := filepath.Join(dep.OutputDir, dirName)
+			if _, err := os.Stat(outputDir); err == nil {
+				classpathParts = append(classpathParts, outputDir)
+			}
 		}
 		
 		// Add JAR files from dependencies
@@ -108,6 +131,9 @@ func (j *JunitTest) Execute(ctx context.Context, workDir string, dependencyInput
 		}
 	}
 	
+	if err := graph.CheckStaged(ctx, classpathParts...); err != nil {
+		return graph.TaskResult{Error: err}
+	}
 	classpath := strings.Join(classpathParts, ":")
 	
 	// Build java command to run JUnit tests
@@ -119,13 +145,17 @@ func (j *JunitTest) Execute(ctx context.Context, workDir string, dependencyInput
 	}
 	
 	// Execute java command
-	cmd := exec.CommandContext(ctx, "java", args...)
-	cmd.Dir = workDir
+	cmd := graph.Command(ctx, tools.Java.Path, args...)
+	cmd.Dir = graph.ToolDir(ctx, workDir)
 	
-	output, err := cmd.CombinedOutput()
+	var output bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&output, graph.TaskLog(ctx))
+	cmd.Stderr = cmd.Stdout
+	
+	err := cmd.Run()
 	if err != nil {
 		// Parse the JUnit output to extract clean failure information
-		cleanError := j.parseJUnitFailure(string(output))
+		cleanError := j.parseJUnitFailure(output.String())
 		return graph.TaskResult{
 			Error: fmt.Errorf("junit test execution failed: %w\n%s", err, cleanError),
 		}
@@ -163,6 +193,19 @@ func (j *JunitTest) AddDependency(task graph.Task) {
 	j.dependencies = append(j.dependencies, task)
 }
 
+// SetToolchain sets the JVM used to run the tests
+func (j *JunitTest) SetToolchain(tools *toolchain.Toolchain) {
+	j.toolchain = tools
+}
+
+// tools returns the toolchain of this task, defaulting to the tools on the PATH
+func (j *JunitTest) tools() *toolchain.Toolchain {
+	if j.toolchain == nil {
+		return toolchain.Default()
+	}
+	return j.toolchain
+}
+
 // GetTestFile returns the test file path
 func (j *JunitTest) GetTestFile() string {
 	return j.testFile
@@ -183,6 +226,13 @@ func (j *JunitTest) DisplayName() string {
 	return fmt.Sprintf("junit-test (%s)", j.testFile)
 }
 
+// TargetNames returns the test class and file names this task can be selected by
+func (j *JunitTest) TargetNames() []string {
+	simpleName := j.className[strings.LastIndex(j.className, ".")+1:]
+	fileName := strings.TrimSuffix(filepath.Base(j.testFile), filepath.Ext(j.testFile))
+	return []string{j.className, simpleName, j.testFile, fileName}
+}
+
 // parseJUnitFailure extracts clean failure information from JUnit output
 func (j *JunitTest) parseJUnitFailure(output string) string {
 	lines := strings.Split(output, "\n")
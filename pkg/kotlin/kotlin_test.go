@@ -4,10 +4,14 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"fbs/pkg/config"
 	"fbs/pkg/discoverer"
 	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
 )
 
 func TestKotlinDiscoverer_Discover(t *testing.T) {
@@ -117,6 +121,115 @@ func TestKotlinDiscoverer_Discover(t *testing.T) {
 	}
 }
 
+func TestKotlinDiscoverer_JavaSources(t *testing.T) {
+	tempDir := t.TempDir()
+	kotlinRoot := filepath.Join(tempDir, "src", "main", "kotlin")
+	javaRoot := filepath.Join(tempDir, "src", "main", "java")
+	for _, file := range []string{
+		filepath.Join(kotlinRoot, "com", "example", "App.kt"),
+		filepath.Join(javaRoot, "com", "example", "Legacy.java"),
+		filepath.Join(javaRoot, "com", "example", "Extensions.kt"),
+	} {
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err := os.WriteFile(file, []byte("package com.example\n"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", file, err)
+		}
+	}
+
+	kd := NewKotlinDiscoverer()
+	for _, root := range []string{kotlinRoot, javaRoot} {
+		result, err := kd.Discover(context.Background(), root, []graph.Task{}, discoverer.NewBuildContext())
+		if err != nil {
+			t.Fatalf("Discover failed: %v", err)
+		}
+		if len(result.Tasks) != 1 {
+			t.Fatalf("Expected 1 task for %s, got %d", root, len(result.Tasks))
+		}
+
+		// kotlinc reads the Java sources of the source set
+		task := result.Tasks[0].(*KotlinCompile)
+		expected := filepath.Join(javaRoot, "com", "example", "Legacy.java")
+		if len(task.GetJavaSources()) != 1 || task.GetJavaSources()[0] != expected {
+			t.Errorf("Expected Java sources [%s] for %s, got %v", expected, root, task.GetJavaSources())
+		}
+	}
+
+	// Java sources are inputs of the compilation
+	task := NewKotlinCompile(kotlinRoot, []string{filepath.Join("com", "example", "App.kt")})
+	task.SetJavaSources([]string{filepath.Join(javaRoot, "com", "example", "Legacy.java")})
+	before := task.Hash()
+	os.WriteFile(filepath.Join(javaRoot, "com", "example", "Legacy.java"), []byte("package com.example;\nclass Legacy {}\n"), 0644)
+	if task.Hash() == before {
+		t.Error("Expected the hash to change with the Java sources")
+	}
+}
+
+func TestHash_IndependentOfCheckoutLocation(t *testing.T) {
+	defer graph.SetWorkspaceRoot("")
+	tools := &toolchain.Toolchain{
+		Kotlinc: toolchain.Tool{Name: "kotlinc", Version: "2.0.0"},
+		Java:    toolchain.Tool{Name: "java", Version: "21"},
+	}
+
+	// hashes plans a checkout of the same module in a new directory
+	hashes := func() []string {
+		root := t.TempDir()
+		sourceDir := filepath.Join(root, "service", "src", "test", "kotlin")
+		os.MkdirAll(sourceDir, 0755)
+		if err := os.WriteFile(filepath.Join(sourceDir, "AppTest.kt"), []byte("class AppTest\n"), 0644); err != nil {
+			t.Fatalf("Failed to create source: %v", err)
+		}
+		graph.SetWorkspaceRoot(root)
+
+		compile := NewKotlinCompile(sourceDir, []string{"AppTest.kt"})
+		compile.SetToolchain(tools)
+		test := NewJunitTest("AppTest.kt", sourceDir, "AppTest")
+		test.SetToolchain(tools)
+		return []string{compile.Hash(), test.Hash()}
+	}
+
+	first, second := hashes(), hashes()
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Errorf("Expected identical checkouts to have identical hashes, got %v and %v", first, second)
+	}
+}
+
+func TestHash_IndependentOfInvocationDirectory(t *testing.T) {
+	defer graph.SetWorkspaceRoot("")
+	tools := &toolchain.Toolchain{Kotlinc: toolchain.Tool{Name: "kotlinc", Version: "2.0.0"}}
+
+	// A repository without fbs.conf.json, built from its root and from a subproject
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	sourceDir := filepath.Join(root, "service", "src", "main", "kotlin")
+	os.MkdirAll(sourceDir, 0755)
+	if err := os.WriteFile(filepath.Join(sourceDir, "App.kt"), []byte("class App\n"), 0644); err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+
+	var hashes []string
+	for _, invocationDir := range []string{root, filepath.Join(root, "service")} {
+		graph.SetWorkspaceRoot(config.FindWorkspaceRoot(invocationDir))
+		compile := NewKotlinCompile(sourceDir, []string{"App.kt"})
+		compile.SetToolchain(tools)
+		hashes = append(hashes, compile.Hash())
+	}
+	if hashes[0] != hashes[1] {
+		t.Errorf("Expected the same hash from the repository root and a subproject, got %v", hashes)
+	}
+}
+
+func TestKotlinCompile_HashIndependentOfFileOrder(t *testing.T) {
+	tools := &toolchain.Toolchain{Kotlinc: toolchain.Tool{Name: "kotlinc", Version: "2.0.0"}}
+	first := NewKotlinCompile("/test/src", []string{"Main.kt", "Utils.kt"})
+	first.SetToolchain(tools)
+	second := NewKotlinCompile("/test/src", []string{"Utils.kt", "Main.kt"})
+	second.SetToolchain(tools)
+	if first.Hash() != second.Hash() {
+		t.Error("Expected the hash not to depend on the order the files were discovered in")
+	}
+}
+
 func TestKotlinDiscoverer_Name(t *testing.T) {
 	discoverer := NewKotlinDiscoverer()
 	if discoverer.Name() != "KotlinDiscoverer" {
@@ -159,6 +272,62 @@ func TestKotlinCompile_BasicProperties(t *testing.T) {
 	}
 }
 
+func TestKotlinCompile_HashUsesFileContents(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "kotlin_hash_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sourceFile := filepath.Join(tempDir, "Main.kt")
+	err = os.WriteFile(sourceFile, []byte("fun main() {}"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create Main.kt: %v", err)
+	}
+
+	task := NewKotlinCompile(tempDir, []string{"Main.kt"})
+	originalHash := task.Hash()
+
+	// Touching the file without changing its contents must not change the hash
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(sourceFile, later, later); err != nil {
+		t.Fatalf("Failed to touch Main.kt: %v", err)
+	}
+	if task.Hash() != originalHash {
+		t.Error("Hash should not change when only the modification time changes")
+	}
+
+	// Changing the contents must change the hash
+	err = os.WriteFile(sourceFile, []byte("fun main() { println() }"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to update Main.kt: %v", err)
+	}
+	if task.Hash() == originalHash {
+		t.Error("Hash should change when the file contents change")
+	}
+}
+
+func TestKotlinCompile_HashUsesToolchain(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "Main.kt"), []byte("fun main() {}"), 0644)
+
+	task := NewKotlinCompile(tempDir, []string{"Main.kt"})
+	task.SetToolchain(&toolchain.Toolchain{
+		Kotlinc: toolchain.Tool{Name: "kotlinc", Version: "1.9.22"},
+		Java:    toolchain.Tool{Name: "java", Version: "17.0.9"},
+	})
+	originalHash := task.Hash()
+
+	// Upgrading the JDK must invalidate the compiled classes
+	task.SetToolchain(&toolchain.Toolchain{
+		Kotlinc: toolchain.Tool{Name: "kotlinc", Version: "1.9.22"},
+		Java:    toolchain.Tool{Name: "java", Version: "21.0.2"},
+	})
+	if task.Hash() == originalHash {
+		t.Error("Hash should change when the JDK version changes")
+	}
+}
+
 func TestKotlinCompile_Execute_MockTest(t *testing.T) {
 	// This test verifies the Execute method structure without requiring kotlinc
 	tempDir, err := os.MkdirTemp("", "kotlin_execute_test")
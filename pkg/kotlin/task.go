@@ -5,19 +5,22 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"fbs/pkg/graph"
+	"fbs/pkg/toolchain"
 )
 
 // KotlinCompile represents a task that compiles Kotlin source files
 type KotlinCompile struct {
 	sourceDir    string
 	kotlinFiles  []string
+	javaSources  []string
 	classpath    []string
 	dependencies []graph.Task
+	toolchain    *toolchain.Toolchain
 }
 
 // NewKotlinCompile creates a new Kotlin compilation task
@@ -50,31 +53,67 @@ func (k *KotlinCompile) TaskType() graph.TaskType {
 	return graph.TaskTypeBuild
 }
 
+// Resources returns the resources of a kotlinc JVM
+func (k *KotlinCompile) Resources() graph.Resources {
+	return graph.Resources{CPU: 1, MemoryMB: 1024}
+}
+
+// IncrementalKey identifies the source root, whose previous compilation is
+// the starting point for recompiling only the changed sources
+func (k *KotlinCompile) IncrementalKey() string {
+	return "kotlin-compile:" + k.sourceDir
+}
+
+// Inputs returns the absolute paths of the Kotlin source files this task
+// compiles, of the Java sources they use and of the entries of its configured classpath
+func (k *KotlinCompile) Inputs() []string {
+	inputs := make([]string, 0, len(k.kotlinFiles)+len(k.javaSources)+len(k.classpath))
+	for _, file := range k.kotlinFiles {
+		inputs = append(inputs, filepath.Join(k.sourceDir, file))
+	}
+	inputs = append(inputs, k.javaSources...)
+	inputs = append(inputs, k.classpath...)
+	return inputs
+}
+
 // Hash returns a hash representing the task's configuration and inputs
 func (k *KotlinCompile) Hash() string {
 	h := sha256.New()
 	
-	// Include task type and source directory
+	// Include task type and source directory, relative to the workspace root
 	h.Write([]byte("KotlinCompile"))
-	h.Write([]byte(k.sourceDir))
+	h.Write([]byte(graph.HashPath(k.sourceDir)))
 	
 	// Include sorted list of Kotlin files for consistency
 	sortedFiles := make([]string, len(k.kotlinFiles))
 	copy(sortedFiles, k.kotlinFiles)
+	sort.Strings(sortedFiles)
 	for _, file := range sortedFiles {
 		h.Write([]byte(file))
 		
-		// Include file modification time if file exists
-		if info, err := os.Stat(filepath.Join(k.sourceDir, file)); err == nil {
-			h.Write([]byte(fmt.Sprintf("%d", info.ModTime().Unix())))
+		// Include file contents digest if file exists
+		if digest, err := graph.FileDigest(filepath.Join(k.sourceDir, file)); err == nil {
+			h.Write([]byte(digest))
+		}
+	}
+	
+	// Include the Java sources the Kotlin code is compiled against
+	for _, file := range k.javaSources {
+		h.Write([]byte(graph.HashPath(file)))
+		if digest, err := graph.FileDigest(file); err == nil {
+			h.Write([]byte(digest))
 		}
 	}
 	
 	// Include classpath
 	for _, cp := range k.classpath {
-		h.Write([]byte(cp))
+		h.Write([]byte(graph.HashClasspathEntry(cp)))
 	}
 	
+	// Include the compiler and JDK versions, since upgrading either changes the output
+	h.Write([]byte(k.tools().Kotlinc.Fingerprint()))
+	h.Write([]byte(k.tools().Java.Fingerprint()))
+	
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
@@ -85,18 +124,26 @@ func (k *KotlinCompile) Dependencies() []graph.Task {
 
 // Execute runs the Kotlin compilation task
 func (k *KotlinCompile) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
+	tools := k.tools()
+	if tools.Err != nil {
+		return graph.TaskResult{Error: tools.Err}
+	}
+	
 	// Create classes output directory
 	classesDir := filepath.Join(workDir, "classes")
 	if err := os.MkdirAll(classesDir, 0755); err != nil {
 		return graph.TaskResult{Error: fmt.Errorf("failed to create classes directory: %w", err)}
 	}
 	
-	// Build kotlin compiler command
-	args := []string{"-d", classesDir}
-	
 	// Build classpath from existing classpath and dependencies
 	var classpath []string
-	classpath = append(classpath, k.classpath...)
+	for _, entry := range k.classpath {
+		path, err := graph.Input(ctx, entry)
+		if err != nil {
+			return graph.TaskResult{Error: err}
+		}
+		classpath = append(classpath, path)
+	}
 	
 	// Add dependency classpaths and JAR files
 	for _, dep := range dependencyInputs {
@@ -123,27 +170,44 @@ func (k *KotlinCompile) Execute(ctx context.Context, workDir string, dependencyI
 			}
 		}
 	}
-	
-	// Add classpath to compiler arguments if not empty
-	if len(classpath) > 0 {
-		args = append(args, "-classpath", strings.Join(classpath, ":"))
+	if err := graph.CheckStaged(ctx, classpath...); err != nil {
+		return graph.TaskResult{Error: err}
 	}
 	
-	// Add source files
+	// Source files are staged copies in sandbox mode, and their digests tell
+	// which of them changed since the previous compilation
+	compilation := &compilation{
+		task:       k,
+		tools:      tools,
+		workDir:    workDir,
+		classesDir: classesDir,
+		classpath:  classpath,
+		sources:    make(map[string]string, len(k.kotlinFiles)),
+		digests:    make(map[string]string, len(k.kotlinFiles)),
+	}
 	for _, file := range k.kotlinFiles {
-		sourcePath := filepath.Join(k.sourceDir, file)
-		args = append(args, sourcePath)
+		sourcePath, err := graph.Input(ctx, filepath.Join(k.sourceDir, file))
+		if err != nil {
+			return graph.TaskResult{Error: err}
+		}
+		digest, err := graph.FileDigest(filepath.Join(k.sourceDir, file))
+		if err != nil {
+			return graph.TaskResult{Error: fmt.Errorf("failed to read source file: %w", err)}
+		}
+		compilation.sources[file] = sourcePath
+		compilation.digests[file] = digest
+	}
+	for _, file := range k.javaSources {
+		sourcePath, err := graph.Input(ctx, file)
+		if err != nil {
+			return graph.TaskResult{Error: err}
+		}
+		compilation.javaSources = append(compilation.javaSources, sourcePath)
 	}
 	
-	// Execute kotlinc command
-	cmd := exec.CommandContext(ctx, "kotlinc", args...)
-	cmd.Dir = workDir
-	
-	output, err := cmd.CombinedOutput()
+	abi, err := compilation.run(ctx)
 	if err != nil {
-		return graph.TaskResult{
-			Error: fmt.Errorf("kotlin compilation failed: %w\nOutput: %s", err, string(output)),
-		}
+		return graph.TaskResult{Error: err}
 	}
 	
 	// List generated class files
@@ -170,7 +234,77 @@ func (k *KotlinCompile) Execute(ctx context.Context, workDir string, dependencyI
 	
 	return graph.TaskResult{
 		Files: classFiles,
+		ABI:   abi,
+	}
+}
+
+// ConsumesABI marks the task as only depending on the ABI of the classes it
+// compiles against, so that it isn't recompiled when only their implementation changes
+func (k *KotlinCompile) ConsumesABI() {}
+
+// runCompiler runs the compiler with args in a persistent compiler daemon,
+// falling back to a fresh kotlinc
+func (k *KotlinCompile) runCompiler(ctx context.Context, tools *toolchain.Toolchain, workDir string, args []string) error {
+	// The daemon outlives the task and runs outside of its sandbox
+	if tools.KotlinDaemon && !graph.Sandboxed(ctx) {
+		compiled, err := k.compileWithDaemon(ctx, tools, args)
+		if compiled || err != nil {
+			return err
+		}
+	}
+	
+	// Execute kotlinc command
+	cmd := graph.Command(ctx, tools.Kotlinc.Path, args...)
+	cmd.Dir = graph.ToolDir(ctx, workDir)
+	if tools.JavaHome != "" {
+		cmd.Env = append(cmd.Environ(), "JAVA_HOME="+tools.JavaHome)
+	}
+	
+	cmd.Stdout = graph.TaskLog(ctx)
+	cmd.Stderr = cmd.Stdout
+	
+	// Compiler diagnostics end up in the task log
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("kotlin compilation failed: %w", err)
+	}
+	return nil
+}
+
+// compileWithDaemon compiles in the compiler daemon of the toolchain. It
+// reports false if no daemon could compile, in which case kotlinc is run instead.
+func (k *KotlinCompile) compileWithDaemon(ctx context.Context, tools *toolchain.Toolchain, args []string) (bool, error) {
+	taskLog := graph.TaskLog(ctx)
+	
+	daemon, err := compilerDaemon(tools)
+	if err == nil {
+		var exitCode int
+		exitCode, err = daemon.Compile(ctx, args, taskLog)
+		if err == nil {
+			if exitCode != 0 {
+				return true, fmt.Errorf("kotlin compilation failed: exit status %d", exitCode)
+			}
+			return true, nil
+		}
+		if ctx.Err() != nil {
+			return true, ctx.Err()
+		}
 	}
+	
+	fmt.Fprintf(taskLog, "Kotlin compiler daemon unavailable, running kotlinc: %v\n", err)
+	return false, nil
+}
+
+// SetToolchain sets the compiler and JDK used by this task
+func (k *KotlinCompile) SetToolchain(tools *toolchain.Toolchain) {
+	k.toolchain = tools
+}
+
+// tools returns the toolchain of this task, defaulting to the tools on the PATH
+func (k *KotlinCompile) tools() *toolchain.Toolchain {
+	if k.toolchain == nil {
+		return toolchain.Default()
+	}
+	return k.toolchain
 }
 
 // SetClasspath sets the classpath for compilation
@@ -178,6 +312,17 @@ func (k *KotlinCompile) SetClasspath(classpath []string) {
 	k.classpath = classpath
 }
 
+// SetJavaSources sets the absolute paths of the Java files the Kotlin code may
+// use. kotlinc only reads them, they are compiled by javac.
+func (k *KotlinCompile) SetJavaSources(javaSources []string) {
+	k.javaSources = javaSources
+}
+
+// GetJavaSources returns the Java files the Kotlin code may use
+func (k *KotlinCompile) GetJavaSources() []string {
+	return k.javaSources
+}
+
 // GetSourceDir returns the source directory
 func (k *KotlinCompile) GetSourceDir() string {
 	return k.sourceDir
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"fbs/pkg/graph"
+)
+
+// PlainRenderer prints one line per finished task without escape sequences,
+// which keeps CI logs and redirected output readable
+type PlainRenderer struct {
+	out       io.Writer
+	label     LabelFunc
+	labels    map[string]string
+	total     int
+	counts    counts
+	startTime time.Time
+}
+
+// NewPlainRenderer creates a line-oriented renderer
+func NewPlainRenderer(out io.Writer, label LabelFunc) *PlainRenderer {
+	return &PlainRenderer{
+		out:    out,
+		label:  label,
+		labels: make(map[string]string),
+	}
+}
+
+// Start records the tasks that will be executed
+func (p *PlainRenderer) Start(tasks []graph.Task) {
+	p.startTime = time.Now()
+	p.total = len(tasks)
+	for _, task := range tasks {
+		p.labels[task.ID()] = p.label(task)
+	}
+}
+
+// HandleEvent prints a line when a task finishes
+func (p *PlainRenderer) HandleEvent(event graph.Event) {
+	width := len(fmt.Sprint(p.total))
+	if event.Type == graph.EventTaskRetrying {
+		fmt.Fprintf(p.out, "[%*d/%d] %-7s %s (attempt %d): %s\n", width, p.counts.done(), p.total, "retry", p.labels[event.TaskID], event.Attempt, event.Error)
+		return
+	}
+	if !p.counts.record(event) {
+		return
+	}
+
+	var status string
+	switch event.Type {
+	case graph.EventTaskSucceeded:
+		status = "done"
+		if event.Flaky {
+			status = "flaky"
+		}
+	case graph.EventTaskCacheHit:
+		status = "cached"
+	case graph.EventTaskFailed:
+		status = "FAILED"
+	case graph.EventTaskSkipped:
+		status = "skipped"
+	}
+
+	line := fmt.Sprintf("[%*d/%d] %-7s %s", width, p.counts.done(), p.total, status, p.labels[event.TaskID])
+	if event.Type == graph.EventTaskSucceeded || event.Type == graph.EventTaskFailed {
+		line += fmt.Sprintf(" (%s)", formatDuration(time.Duration(event.DurationMs)*time.Millisecond))
+	}
+	fmt.Fprintln(p.out, line)
+}
+
+// Finish prints the flaky and failed tasks followed by the build summary
+func (p *PlainRenderer) Finish(results []graph.ExecutionResult, err error) {
+	writeFlakySummary(p.out, results, p.labels)
+	writeFailureSummary(p.out, results, p.labels)
+
+	status := "BUILD SUCCESSFUL"
+	if err != nil {
+		status = "BUILD FAILED"
+	}
+	fmt.Fprintf(p.out, "%s: %s\n", status, p.counts.summary(p.total, time.Since(p.startTime)))
+}
@@ -0,0 +1,190 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"fbs/pkg/graph"
+)
+
+// Output modes accepted by New
+const (
+	ModeAuto  = "auto"
+	ModeTTY   = "tty"
+	ModePlain = "plain"
+)
+
+// logTailLines is how many lines of a failed task's log are shown after the build
+const logTailLines = 20
+
+// Renderer displays the progress of a build
+type Renderer interface {
+	// Start is called with all tasks that will be executed, before execution begins
+	Start(tasks []graph.Task)
+
+	// HandleEvent is called for every task event during execution
+	HandleEvent(event graph.Event)
+
+	// Finish is called once execution has ended
+	Finish(results []graph.ExecutionResult, err error)
+}
+
+// LabelFunc returns the text used to identify a task in the output
+type LabelFunc func(task graph.Task) string
+
+// New creates a renderer for the given mode. In auto mode the interactive
+// renderer is used only when out is a terminal.
+func New(mode string, out *os.File, label LabelFunc) (Renderer, error) {
+	switch mode {
+	case ModeAuto, "":
+		if isTerminal(out) {
+			return NewTTYRenderer(out, label), nil
+		}
+		return NewPlainRenderer(out, label), nil
+	case ModeTTY:
+		return NewTTYRenderer(out, label), nil
+	case ModePlain:
+		return NewPlainRenderer(out, label), nil
+	default:
+		return nil, fmt.Errorf("unknown output mode %q", mode)
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal that understands escape sequences
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd())) && os.Getenv("TERM") != "dumb"
+}
+
+// counts tracks how many tasks finished in each state
+type counts struct {
+	executed int
+	cached   int
+	failed   int
+	skipped  int
+	flaky    int
+}
+
+// record updates the counts for a task event and reports whether the task finished
+func (c *counts) record(event graph.Event) bool {
+	switch event.Type {
+	case graph.EventTaskSucceeded:
+		c.executed++
+		if event.Flaky {
+			c.flaky++
+		}
+	case graph.EventTaskCacheHit:
+		c.cached++
+	case graph.EventTaskFailed:
+		c.failed++
+	case graph.EventTaskSkipped:
+		c.skipped++
+	default:
+		return false
+	}
+	return true
+}
+
+// done returns the number of finished tasks
+func (c *counts) done() int {
+	return c.executed + c.cached + c.failed + c.skipped
+}
+
+// summary formats the final one-line summary of a build
+func (c *counts) summary(total int, elapsed time.Duration) string {
+	parts := []string{
+		fmt.Sprintf("%d executed", c.executed),
+		fmt.Sprintf("%d cached", c.cached),
+	}
+	if c.failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", c.failed))
+	}
+	if c.skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d skipped", c.skipped))
+	}
+	if c.flaky > 0 {
+		parts = append(parts, fmt.Sprintf("%d flaky", c.flaky))
+	}
+	return fmt.Sprintf("%d tasks (%s) in %s", total, strings.Join(parts, ", "), formatDuration(elapsed))
+}
+
+// formatDuration formats a duration with a precision suitable for task timings
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// writeFailureSummary prints every failed task with its error and the tail of its log
+func writeFailureSummary(out io.Writer, results []graph.ExecutionResult, labels map[string]string) {
+	for _, result := range results {
+		if result.Result.Error == nil {
+			continue
+		}
+
+		label, exists := labels[result.Task.ID()]
+		if !exists {
+			label = result.Task.DisplayName()
+		}
+		fmt.Fprintf(out, "\nFAILED: %s\n", label)
+		fmt.Fprintf(out, "  %v\n", result.Result.Error)
+
+		if result.LogPath == "" {
+			continue
+		}
+		lines, err := tailLines(result.LogPath, logTailLines)
+		if err != nil || len(lines) == 0 {
+			continue
+		}
+		for _, line := range lines {
+			fmt.Fprintf(out, "  | %s\n", line)
+		}
+		fmt.Fprintf(out, "  Full log: fbs log %s\n", shortHash(result.TaskHash))
+	}
+}
+
+// writeFlakySummary prints every task that only succeeded after being retried
+func writeFlakySummary(out io.Writer, results []graph.ExecutionResult, labels map[string]string) {
+	for _, result := range results {
+		if !result.Flaky {
+			continue
+		}
+
+		label, exists := labels[result.Task.ID()]
+		if !exists {
+			label = result.Task.DisplayName()
+		}
+		fmt.Fprintf(out, "\nFLAKY: %s (passed on attempt %d)\n", label, result.Attempts)
+	}
+}
+
+// tailLines returns the last n lines of a file
+func tailLines(path string, n int) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// shortHash abbreviates a task hash for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,178 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fbs/pkg/graph"
+)
+
+// MockTask implements the graph.Task interface for testing
+type MockTask struct {
+	id   string
+	fail bool
+	deps []graph.Task
+}
+
+func (m *MockTask) ID() string                 { return m.id }
+func (m *MockTask) Name() string               { return m.id }
+func (m *MockTask) Directory() string          { return "/test" }
+func (m *MockTask) TaskType() graph.TaskType   { return graph.TaskTypeBuild }
+func (m *MockTask) Hash() string               { return "hash-" + m.id }
+func (m *MockTask) Dependencies() []graph.Task { return m.deps }
+func (m *MockTask) DisplayName() string        { return m.id }
+
+func (m *MockTask) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
+	if m.fail {
+		fmt.Fprintln(graph.TaskLog(ctx), "Foo.kt:3:5: error: unresolved reference")
+		return graph.TaskResult{Error: errors.New("boom")}
+	}
+	if err := os.WriteFile(filepath.Join(workDir, m.id+".txt"), []byte(m.id), 0644); err != nil {
+		return graph.TaskResult{Error: err}
+	}
+	return graph.TaskResult{Files: []string{m.id + ".txt"}}
+}
+
+// runWithRenderer executes a graph with a failing task, an independent task
+// and a dependent of the failing task, reporting progress to the renderer
+func runWithRenderer(t *testing.T, renderer Renderer) {
+	failing := &MockTask{id: "failing", fail: true}
+	g := graph.NewGraph()
+	g.AddTask(failing)
+	g.AddTask(&MockTask{id: "ok"})
+	g.AddTask(&MockTask{id: "dependent", deps: []graph.Task{failing}})
+
+	tasks, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("Failed to sort tasks: %v", err)
+	}
+
+	runner := graph.NewRunner(t.TempDir())
+	runner.SetKeepGoing(true)
+	runner.SetLogStore(graph.NewLogStore(t.TempDir()))
+	runner.AddEventHandler(renderer.HandleEvent)
+
+	renderer.Start(tasks)
+	results, err := runner.Execute(context.Background(), g)
+	renderer.Finish(results, err)
+}
+
+func label(task graph.Task) string {
+	return task.DisplayName() + " (.)"
+}
+
+func TestPlainRenderer(t *testing.T) {
+	var out bytes.Buffer
+	runWithRenderer(t, NewPlainRenderer(&out, label))
+
+	output := out.String()
+	if strings.Contains(output, "\033[") {
+		t.Errorf("Plain output should not contain escape sequences: %q", output)
+	}
+
+	for _, expected := range []string{
+		"FAILED  failing (.)",
+		"done    ok (.)",
+		"skipped dependent (.)",
+		"FAILED: failing (.)\n  boom\n  | Foo.kt:3:5: error: unresolved reference\n",
+		"BUILD FAILED: 3 tasks (1 executed, 0 cached, 1 failed, 1 skipped)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestTTYRenderer(t *testing.T) {
+	var out bytes.Buffer
+	runWithRenderer(t, NewTTYRenderer(&out, label))
+
+	output := out.String()
+	if !strings.Contains(output, "failing (.)") {
+		t.Errorf("Expected failed task to be listed, got %q", output)
+	}
+
+	// After the final redraw only the summary remains below the last cursor movement
+	lastClear := strings.LastIndex(output, clearDown)
+	if lastClear < 0 {
+		t.Fatalf("Expected the status block to be cleared, got %q", output)
+	}
+	final := output[lastClear+len(clearDown):]
+	if !strings.Contains(final, "3 tasks (1 executed, 0 cached, 1 failed, 1 skipped)") || strings.Contains(final, "running") {
+		t.Errorf("Expected only the summary after the last redraw, got %q", final)
+	}
+}
+
+func TestPlainRenderer_Flaky(t *testing.T) {
+	attempts := 0
+	flaky := &MockTask{id: "flaky"}
+	g := graph.NewGraph()
+	g.AddTask(&flakyTask{MockTask: flaky, attempts: &attempts})
+
+	tasks, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("Failed to sort tasks: %v", err)
+	}
+
+	var out bytes.Buffer
+	renderer := NewPlainRenderer(&out, label)
+	runner := graph.NewRunner(t.TempDir())
+	runner.SetRetries(graph.RetryPolicies{graph.DefaultRetryKey: {Attempts: 2}})
+	runner.AddEventHandler(renderer.HandleEvent)
+
+	renderer.Start(tasks)
+	results, err := runner.Execute(context.Background(), g)
+	renderer.Finish(results, err)
+
+	output := out.String()
+	for _, expected := range []string{
+		"retry   flaky (.) (attempt 2): boom",
+		"flaky   flaky (.)",
+		"FLAKY: flaky (.) (passed on attempt 2)",
+		"BUILD SUCCESSFUL: 1 tasks (1 executed, 0 cached, 1 flaky)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+// flakyTask fails on its first execution only
+type flakyTask struct {
+	*MockTask
+	attempts *int
+}
+
+func (f *flakyTask) Execute(ctx context.Context, workDir string, dependencyInputs []graph.DependencyInput) graph.TaskResult {
+	*f.attempts++
+	if *f.attempts == 1 {
+		return graph.TaskResult{Error: errors.New("boom")}
+	}
+	return f.MockTask.Execute(ctx, workDir, dependencyInputs)
+}
+
+func TestNew_NonTerminalUsesPlain(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer file.Close()
+
+	renderer, err := New(ModeAuto, file, label)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, ok := renderer.(*PlainRenderer); !ok {
+		t.Errorf("Expected a plain renderer for a regular file, got %T", renderer)
+	}
+
+	if _, err := New("fancy", file, label); err == nil {
+		t.Error("Expected an error for an unknown output mode")
+	}
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"fbs/pkg/graph"
+)
+
+// Escape sequences used by the interactive renderer
+const (
+	green     = "\033[32m"
+	orange    = "\033[33m"
+	red       = "\033[31m"
+	reset     = "\033[0m"
+	cursorUp  = "\033[%dA"
+	clearDown = "\033[J"
+)
+
+// maxRunningLines limits how many running tasks are listed at once
+const maxRunningLines = 10
+
+// defaultWidth is used when the terminal width cannot be determined
+const defaultWidth = 80
+
+// TTYRenderer redraws a status block listing the currently running tasks.
+// Failed tasks are printed above the block so they remain in the scrollback,
+// and the block is replaced by a summary when the build finishes.
+type TTYRenderer struct {
+	out       io.Writer
+	label     LabelFunc
+	labels    map[string]string
+	total     int
+	counts    counts
+	running   []string // IDs of running tasks in start order
+	drawn     int      // Number of lines in the currently drawn status block
+	startTime time.Time
+	mu        sync.Mutex
+}
+
+// NewTTYRenderer creates an interactive renderer for a terminal
+func NewTTYRenderer(out io.Writer, label LabelFunc) *TTYRenderer {
+	return &TTYRenderer{
+		out:    out,
+		label:  label,
+		labels: make(map[string]string),
+	}
+}
+
+// Start records the tasks that will be executed and draws the initial status block
+func (t *TTYRenderer) Start(tasks []graph.Task) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.startTime = time.Now()
+	t.total = len(tasks)
+	for _, task := range tasks {
+		t.labels[task.ID()] = t.label(task)
+	}
+	t.draw()
+}
+
+// HandleEvent updates the set of running tasks and redraws the status block
+func (t *TTYRenderer) HandleEvent(event graph.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch event.Type {
+	case graph.EventTaskStarted:
+		t.running = append(t.running, event.TaskID)
+	case graph.EventTaskQueued:
+		return
+	case graph.EventTaskRetrying:
+		t.clear()
+		fmt.Fprintf(t.out, "  %s↻%s %s\n", orange, reset, t.truncate(fmt.Sprintf("%s (attempt %d)", t.labels[event.TaskID], event.Attempt), 4))
+		t.draw()
+		return
+	default:
+		if !t.counts.record(event) {
+			return
+		}
+		t.removeRunning(event.TaskID)
+	}
+
+	t.clear()
+	if event.Type == graph.EventTaskFailed {
+		fmt.Fprintf(t.out, "  %s✗%s %s\n", red, reset, t.truncate(t.labels[event.TaskID], 4))
+	}
+	t.draw()
+}
+
+// Finish replaces the status block with the flaky and failed tasks and the build summary
+func (t *TTYRenderer) Finish(results []graph.ExecutionResult, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.clear()
+	writeFlakySummary(t.out, results, t.labels)
+	writeFailureSummary(t.out, results, t.labels)
+
+	symbol, color := "✓", green
+	if err != nil {
+		symbol, color = "✗", red
+	}
+	fmt.Fprintf(t.out, "%s%s%s %s\n", color, symbol, reset, t.counts.summary(t.total, time.Since(t.startTime)))
+}
+
+// draw prints the status block and remembers its height
+func (t *TTYRenderer) draw() {
+	var lines []string
+	for i, id := range t.running {
+		if i == maxRunningLines {
+			lines = append(lines, fmt.Sprintf("  … and %d more", len(t.running)-maxRunningLines))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %s⏳%s %s", orange, reset, t.truncate(t.labels[id], 5)))
+	}
+
+	status := fmt.Sprintf("[%d/%d] %d running, %d cached", t.counts.done(), t.total, len(t.running), t.counts.cached)
+	if t.counts.failed > 0 {
+		status += fmt.Sprintf(", %s%d failed%s", red, t.counts.failed, reset)
+	}
+	lines = append(lines, status)
+
+	for _, line := range lines {
+		fmt.Fprintln(t.out, line)
+	}
+	t.drawn = len(lines)
+}
+
+// clear erases the previously drawn status block
+func (t *TTYRenderer) clear() {
+	if t.drawn == 0 {
+		return
+	}
+	fmt.Fprintf(t.out, "\r"+cursorUp+clearDown, t.drawn)
+	t.drawn = 0
+}
+
+// removeRunning removes a task from the list of running tasks
+func (t *TTYRenderer) removeRunning(taskID string) {
+	for i, id := range t.running {
+		if id == taskID {
+			t.running = append(t.running[:i], t.running[i+1:]...)
+			return
+		}
+	}
+}
+
+// truncate shortens text so that a line with the given prefix width never wraps,
+// since a wrapped line would throw off the cursor movement in clear
+func (t *TTYRenderer) truncate(text string, prefixWidth int) string {
+	maxWidth := t.width() - prefixWidth - 1
+	runes := []rune(text)
+	if maxWidth <= 0 || len(runes) <= maxWidth {
+		return text
+	}
+	return string(runes[:maxWidth-1]) + "…"
+}
+
+// width returns the current width of the terminal
+func (t *TTYRenderer) width() int {
+	if f, ok := t.out.(*os.File); ok {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	return defaultWidth
+}
@@ -0,0 +1,303 @@
+package toolchain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fbs/pkg/config"
+	"fbs/pkg/discoverer"
+)
+
+// versionTimeout limits how long a tool may take to report its version
+const versionTimeout = 30 * time.Second
+
+// Tool is a resolved executable with the version it reported
+type Tool struct {
+	// Name is the name of the tool, such as kotlinc
+	Name string
+	// Path is the executable to run
+	Path string
+	// Version is the version of the tool, or empty if it couldn't be determined
+	Version string
+}
+
+// Fingerprint identifies the tool in task hashes. It contains the version
+// rather than the path, so that machines with the same tools share results.
+func (t Tool) Fingerprint() string {
+	version := t.Version
+	if version == "" {
+		version = "unknown"
+	}
+	return t.Name + "@" + version
+}
+
+// Toolchain is the set of tools that tasks run
+type Toolchain struct {
+	Kotlinc Tool
+	Java    Tool
+	Javac   Tool
+	Jar     Tool
+	// JavaHome is the JDK that java, javac and jar belong to, if it is known
+	JavaHome string
+	// KotlinHome is the Kotlin compiler distribution kotlinc belongs to, if it is known
+	KotlinHome string
+	// KotlinDaemon is whether Kotlin is compiled in a persistent compiler daemon
+	KotlinDaemon bool
+	// Err explains why the requested JDK couldn't be found. Tasks fail with it
+	// rather than silently running another JDK.
+	Err error
+}
+
+// Request is set in the BuildContext by build systems that declare the JDK
+// version they need, such as Gradle's jvmToolchain(21)
+type Request struct {
+	// JavaVersion is the major version of the JDK
+	JavaVersion int
+}
+
+var (
+	resolved   = make(map[string]*Toolchain)
+	resolvedMu sync.Mutex
+)
+
+// FromBuildContext returns the toolchain for the configuration and JDK request
+// in a BuildContext
+func FromBuildContext(buildContext *discoverer.BuildContext) *Toolchain {
+	var toolchainConfig config.ToolchainConfig
+	javaVersion := 0
+	if buildContext != nil {
+		if configObj := buildContext.GetByExample((*config.Config)(nil)); configObj != nil {
+			toolchainConfig = configObj.(*config.Config).Toolchain
+		}
+		if requestObj := buildContext.GetByExample((*Request)(nil)); requestObj != nil {
+			javaVersion = requestObj.(*Request).JavaVersion
+		}
+	}
+	return Resolve(toolchainConfig, javaVersion)
+}
+
+// Default returns the toolchain found on the PATH
+func Default() *Toolchain {
+	return Resolve(config.ToolchainConfig{}, 0)
+}
+
+// Resolve finds the tools configured in toolchainConfig. If javaVersion is
+// set and no JDK is configured, a JDK of that major version is searched for.
+// Toolchains are resolved once per process.
+func Resolve(toolchainConfig config.ToolchainConfig, javaVersion int) *Toolchain {
+	key := fmt.Sprintf("%s/%s/%s/%s/%s/%s/%t/%d", toolchainConfig.JavaHome, toolchainConfig.KotlinHome,
+		toolchainConfig.Kotlinc, toolchainConfig.Java, toolchainConfig.Javac, toolchainConfig.Jar, kotlinDaemon(toolchainConfig), javaVersion)
+
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+	if toolchain, exists := resolved[key]; exists {
+		return toolchain
+	}
+
+	toolchain := resolve(toolchainConfig, javaVersion)
+	resolved[key] = toolchain
+	return toolchain
+}
+
+// resolve finds the tools of a toolchain
+func resolve(toolchainConfig config.ToolchainConfig, javaVersion int) *Toolchain {
+	toolchain := &Toolchain{JavaHome: toolchainConfig.JavaHome}
+
+	// Pick the JDK that provides java, javac and jar
+	if toolchain.JavaHome == "" && javaVersion > 0 {
+		toolchain.JavaHome = findJDK(javaVersion)
+		if toolchain.JavaHome == "" {
+			toolchain.Err = fmt.Errorf("no JDK %d found for jvmToolchain(%d): install it or set toolchain.javaHome in fbs.conf.json", javaVersion, javaVersion)
+		}
+	}
+
+	toolchain.Java = newTool("java", toolchainConfig.Java, toolchain.JavaHome)
+	toolchain.Javac = newTool("javac", toolchainConfig.Javac, toolchain.JavaHome)
+	toolchain.Jar = newTool("jar", toolchainConfig.Jar, toolchain.JavaHome)
+	toolchain.Kotlinc = newTool("kotlinc", toolchainConfig.Kotlinc, toolchainConfig.KotlinHome)
+	toolchain.KotlinHome = toolchainConfig.KotlinHome
+	if toolchain.KotlinHome == "" {
+		toolchain.KotlinHome = distributionHome(toolchain.Kotlinc.Path)
+	}
+	toolchain.KotlinDaemon = kotlinDaemon(toolchainConfig)
+	return toolchain
+}
+
+// kotlinDaemon returns whether the compiler daemon is enabled, which it is unless configured otherwise
+func kotlinDaemon(toolchainConfig config.ToolchainConfig) bool {
+	return toolchainConfig.KotlinDaemon == nil || *toolchainConfig.KotlinDaemon
+}
+
+// distributionHome returns the directory containing the bin directory of a
+// tool, following symlinks such as those installed by package managers
+func distributionHome(path string) string {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+	return filepath.Dir(filepath.Dir(realPath))
+}
+
+// newTool resolves a tool from its configured path, the bin directory of its
+// distribution, or the PATH, in that order
+func newTool(name, path, home string) Tool {
+	if path == "" && home != "" {
+		path = filepath.Join(home, "bin", name)
+	}
+	if path == "" {
+		path = name
+		if found, err := exec.LookPath(name); err == nil {
+			path = found
+		}
+	}
+	return Tool{Name: name, Path: path, Version: toolVersion(name, path)}
+}
+
+var (
+	versions   = make(map[string]string)
+	versionsMu sync.Mutex
+)
+
+// toolVersion returns the version of the tool at path, determined once per process
+func toolVersion(name, path string) string {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	if version, exists := versions[path]; exists {
+		return version
+	}
+
+	version := detectVersion(name, path)
+	versions[path] = version
+	return version
+}
+
+// detectVersion reads the version from the tool's distribution if possible,
+// since running a JVM tool to ask for its version takes a while
+func detectVersion(name, path string) string {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+	home := distributionHome(realPath)
+
+	if name == "kotlinc" {
+		if data, err := os.ReadFile(filepath.Join(home, "build.txt")); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+		return runVersion(realPath, `kotlinc-jvm ([^ ]+)`, "-version")
+	}
+
+	if version := releaseVersion(home); version != "" {
+		return version
+	}
+	if name == "jar" || name == "javac" {
+		return runVersion(realPath, name+` ([^ \s]+)`, "--version")
+	}
+	return runVersion(realPath, `version "([^"]+)"`, "-version")
+}
+
+// runVersion runs a tool with args and extracts its version from the output with pattern
+func runVersion(path, pattern string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	if matches := regexp.MustCompile(pattern).FindSubmatch(output); matches != nil {
+		return string(matches[1])
+	}
+	return ""
+}
+
+// releaseVersion reads JAVA_VERSION from the release file of a JDK
+func releaseVersion(javaHome string) string {
+	data, err := os.ReadFile(filepath.Join(javaHome, "release"))
+	if err != nil {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if value, found := strings.CutPrefix(scanner.Text(), "JAVA_VERSION="); found {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// majorVersion returns the major version of a Java version such as 21.0.2 or 1.8.0_392
+func majorVersion(version string) int {
+	version = strings.TrimPrefix(version, "1.")
+	end := strings.IndexFunc(version, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		version = version[:end]
+	}
+	major, _ := strconv.Atoi(version)
+	return major
+}
+
+// findJDK returns the home of an installed JDK of the given major version, or
+// an empty string if there is none
+func findJDK(javaVersion int) string {
+	for _, candidate := range jdkCandidates(javaVersion) {
+		if majorVersion(releaseVersion(candidate)) == javaVersion {
+			if _, err := os.Stat(filepath.Join(candidate, "bin", "java")); err == nil {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// jdkCandidates lists the directories that may contain a JDK of the given
+// major version: the JDKs named by CI environment variables and JAVA_HOME,
+// the JDKs installed by Gradle, SDKMAN!, Linux packages and macOS installers,
+// and the JDK on the PATH
+func jdkCandidates(javaVersion int) []string {
+	var candidates []string
+	for _, name := range []string{
+		fmt.Sprintf("JAVA_HOME_%d_X64", javaVersion),
+		fmt.Sprintf("JAVA_HOME_%d_ARM64", javaVersion),
+		"JAVA_HOME",
+	} {
+		if home := os.Getenv(name); home != "" {
+			candidates = append(candidates, home)
+		}
+	}
+
+	var patterns []string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		patterns = append(patterns,
+			filepath.Join(homeDir, ".gradle", "jdks", "*"),
+			filepath.Join(homeDir, ".sdkman", "candidates", "java", "*"),
+			filepath.Join(homeDir, "Library", "Java", "JavaVirtualMachines", "*", "Contents", "Home"),
+		)
+	}
+	patterns = append(patterns,
+		"/usr/lib/jvm/*",
+		"/Library/Java/JavaVirtualMachines/*/Contents/Home",
+	)
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		candidates = append(candidates, matches...)
+	}
+
+	if java, err := exec.LookPath("java"); err == nil {
+		if realPath, err := filepath.EvalSymlinks(java); err == nil {
+			candidates = append(candidates, filepath.Dir(filepath.Dir(realPath)))
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,97 @@
+package toolchain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fbs/pkg/config"
+	"fbs/pkg/discoverer"
+)
+
+// writeJDK creates a fake JDK of the given version with java, javac and jar executables
+func writeJDK(t *testing.T, version string) string {
+	home := t.TempDir()
+	os.MkdirAll(filepath.Join(home, "bin"), 0755)
+	for _, tool := range []string{"java", "javac", "jar"} {
+		os.WriteFile(filepath.Join(home, "bin", tool), []byte("#!/bin/sh\n"), 0755)
+	}
+	os.WriteFile(filepath.Join(home, "release"), []byte("IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_VERSION=\""+version+"\"\n"), 0644)
+	return home
+}
+
+// writeKotlinHome creates a fake Kotlin compiler distribution of the given version
+func writeKotlinHome(t *testing.T, version string) string {
+	home := t.TempDir()
+	os.MkdirAll(filepath.Join(home, "bin"), 0755)
+	os.WriteFile(filepath.Join(home, "bin", "kotlinc"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(home, "build.txt"), []byte(version+"\n"), 0644)
+	return home
+}
+
+func TestResolve_ConfiguredHomes(t *testing.T) {
+	jdk := writeJDK(t, "21.0.2")
+	kotlinHome := writeKotlinHome(t, "2.0.0-release-341")
+
+	toolchain := Resolve(config.ToolchainConfig{JavaHome: jdk, KotlinHome: kotlinHome}, 0)
+	if toolchain.Err != nil {
+		t.Fatalf("Failed to resolve toolchain: %v", toolchain.Err)
+	}
+	if toolchain.Java.Path != filepath.Join(jdk, "bin", "java") || toolchain.Jar.Path != filepath.Join(jdk, "bin", "jar") {
+		t.Errorf("Expected java and jar from the configured JDK, got %s and %s", toolchain.Java.Path, toolchain.Jar.Path)
+	}
+	if toolchain.Java.Fingerprint() != "java@21.0.2" || toolchain.Jar.Fingerprint() != "jar@21.0.2" {
+		t.Errorf("Expected JDK version 21.0.2, got %s and %s", toolchain.Java.Fingerprint(), toolchain.Jar.Fingerprint())
+	}
+	if toolchain.Javac.Path != filepath.Join(jdk, "bin", "javac") || toolchain.Javac.Fingerprint() != "javac@21.0.2" {
+		t.Errorf("Expected javac from the configured JDK, got %+v", toolchain.Javac)
+	}
+	if toolchain.Kotlinc.Fingerprint() != "kotlinc@2.0.0-release-341" {
+		t.Errorf("Expected kotlinc version from build.txt, got %s", toolchain.Kotlinc.Fingerprint())
+	}
+
+	// A single tool can be overridden
+	jar := filepath.Join(writeJDK(t, "17.0.9"), "bin", "jar")
+	toolchain = Resolve(config.ToolchainConfig{JavaHome: jdk, Jar: jar}, 0)
+	if toolchain.Jar.Path != jar || toolchain.Jar.Version != "17.0.9" {
+		t.Errorf("Expected the configured jar, got %+v", toolchain.Jar)
+	}
+}
+
+func TestResolve_JvmToolchain(t *testing.T) {
+	jdk := writeJDK(t, "17.0.9")
+	t.Setenv("JAVA_HOME_17_X64", jdk)
+
+	buildContext := discoverer.NewBuildContext()
+	buildContext.Set(&Request{JavaVersion: 17})
+	toolchain := FromBuildContext(buildContext)
+	if toolchain.Err != nil || toolchain.JavaHome != jdk {
+		t.Errorf("Expected the JDK 17 from JAVA_HOME_17_X64, got %q (%v)", toolchain.JavaHome, toolchain.Err)
+	}
+
+	// A configured JDK takes precedence over the requested version
+	configured := writeJDK(t, "21.0.2")
+	buildContext.Set(&config.Config{Toolchain: config.ToolchainConfig{JavaHome: configured}})
+	if toolchain := FromBuildContext(buildContext); toolchain.JavaHome != configured {
+		t.Errorf("Expected the configured JDK, got %q", toolchain.JavaHome)
+	}
+
+	// A missing JDK is reported rather than replaced by another version
+	if toolchain := Resolve(config.ToolchainConfig{}, 99); toolchain.Err == nil {
+		t.Error("Expected an error for a JDK version that isn't installed")
+	}
+}
+
+func TestMajorVersion(t *testing.T) {
+	for version, expected := range map[string]int{
+		"21.0.2":    21,
+		"17":        17,
+		"1.8.0_392": 8,
+		"22-ea":     22,
+		"":          0,
+	} {
+		if major := majorVersion(version); major != expected {
+			t.Errorf("Expected major version %d for %q, got %d", expected, version, major)
+		}
+	}
+}
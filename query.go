@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"fbs/pkg/config"
+	"fbs/pkg/graph"
+)
+
+type RdepsCmd struct {
+	Path string `arg:"" help:"File or directory whose reverse dependencies to list"`
+	Type string `help:"Only list tasks of this type (build, test or deps)" enum:"build,test,deps,all" default:"all"`
+}
+
+type AffectedCmd struct {
+	Since string `required:"" help:"Git ref to compare the working tree against (e.g. origin/main)"`
+	Type  string `help:"Type of affected tasks to list or run (build, test or deps)" enum:"build,test,deps" default:"test"`
+	Run   bool   `help:"Run the affected tasks instead of listing them"`
+}
+
+func runRdeps(cmd RdepsCmd) error {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	absPath, err := filepath.Abs(cmd.Path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	buildGraph, err := planWorkspace(ctx, workDir)
+	if err != nil {
+		return err
+	}
+
+	tasks := filterTasksByType(reverseDependencies(buildGraph, []string{absPath}), cmd.Type)
+	if len(tasks) == 0 {
+		fmt.Printf("No tasks depend on %s\n", cmd.Path)
+		return nil
+	}
+
+	for _, task := range tasks {
+		fmt.Println(taskLabel(task, workDir))
+	}
+	return nil
+}
+
+func runAffected(cmd AffectedCmd, cli *CLI) error {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	changed, err := changedFiles(workDir, cmd.Since)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		fmt.Printf("No files changed since %s\n", cmd.Since)
+		return nil
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	buildGraph, err := planWorkspace(ctx, workDir)
+	if err != nil {
+		return err
+	}
+
+	tasks := filterTasksByType(reverseDependencies(buildGraph, changed), cmd.Type)
+	if len(tasks) == 0 {
+		fmt.Printf("No %s tasks affected by %d changed file(s)\n", cmd.Type, len(changed))
+		return nil
+	}
+
+	if !cmd.Run {
+		for _, task := range tasks {
+			fmt.Println(taskLabel(task, workDir))
+		}
+		return nil
+	}
+
+	return executeTasks(ctx, tasks, workDir, cli)
+}
+
+// planWorkspace plans the whole workspace containing workDir
+func planWorkspace(ctx context.Context, workDir string) (*graph.Graph, error) {
+	rootDir := config.FindWorkspaceRoot(workDir)
+
+	// Change to the workspace root for planning
+	defer os.Chdir(workDir)
+	if err := os.Chdir(rootDir); err != nil {
+		return nil, fmt.Errorf("failed to change to directory %s: %w", rootDir, err)
+	}
+
+	// Use a persistent digest index so unchanged files aren't re-read on every run
+	digestIndex := openDigestIndex()
+	defer digestIndex.Save()
+
+	result, err := planBuildGraph(ctx, rootDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := result.CycleError(); err != nil {
+		return nil, err
+	}
+	return result.Graph, nil
+}
+
+// reverseDependencies returns the tasks owning any of the paths together with
+// every task that transitively depends on them
+func reverseDependencies(buildGraph *graph.Graph, paths []string) []graph.Task {
+	var ownerIDs []string
+	for _, path := range paths {
+		for _, task := range buildGraph.OwningTasks(path) {
+			ownerIDs = append(ownerIDs, task.ID())
+		}
+	}
+	return buildGraph.TransitiveDependents(ownerIDs)
+}
+
+// filterTasksByType keeps the tasks of the given type, or all tasks for "all"
+func filterTasksByType(tasks []graph.Task, taskType string) []graph.Task {
+	if taskType == "all" {
+		return tasks
+	}
+
+	var filtered []graph.Task
+	for _, task := range tasks {
+		if string(task.TaskType()) == taskType {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered
+}
+
+// changedFiles returns the absolute paths of files that differ between the
+// working tree and the given git ref, including untracked files
+func changedFiles(dir, ref string) ([]string, error) {
+	topLevel, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := gitOutput(dir, "diff", "--name-only", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitOutput(dir, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(diff+"\n"+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, filepath.Join(topLevel, filepath.FromSlash(line)))
+		}
+	}
+	return files, nil
+}
+
+// gitOutput runs a git command in dir and returns its trimmed standard output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to run git: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}